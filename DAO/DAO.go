@@ -1,6 +1,7 @@
 package DAO
 
 type DiffWithName struct {
+	Kind           string
 	Name           string
 	Namespace      string
 	Diff           []string
@@ -8,4 +9,12 @@ type DiffWithName struct {
 	MessageHeading string
 	SourceMessage  string
 	TargetMessage  string
+	Severity       string
+	// Fingerprints of the compared fields, see compare.Fingerprint.
+	SourceFingerprint string
+	TargetFingerprint string
+	// Reason explains an expected difference, see the kompare.io/expected-diff annotation.
+	Reason string
+	// APIVersion is the version both clusters were read at when the kind is served under several, e.g. "autoscaling/v2".
+	APIVersion string
 }
@@ -0,0 +1,158 @@
+// Package analysis holds the questions that span several kinds, like "who can delete secrets",
+// which the per-kind comparators of the compare package cannot answer one object at a time.
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"kompare/DAO"
+	"kompare/cli"
+	"kompare/compare"
+	"kompare/query"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Scope is what an analysis runs against.
+type Scope struct {
+	Source, Target compare.Cluster
+	// Namespace is the -n option: a namespace, a wildcard pattern, or "" for every namespace.
+	Namespace string
+	Args      cli.ArgumentsReceivedValidated
+}
+
+// Includes reports whether findings in a namespace are in scope. Cluster-wide findings, with no namespace, always are.
+func (s Scope) Includes(namespace string) bool {
+	if s.Namespace == "" || namespace == "" {
+		return true
+	}
+	match, err := filepath.Match(s.Namespace, namespace)
+	return err == nil && match
+}
+
+// Namespaces returns the sorted names of the namespaces in scope, in either cluster.
+func (s Scope) Namespaces() ([]string, error) {
+	seen := map[string]bool{}
+	for _, cluster := range []compare.Cluster{s.Source, s.Target} {
+		list, err := query.ListNameSpaces(cluster.Clientset)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range list.Items {
+			if s.Includes(ns.Name) {
+				seen[ns.Name] = true
+			}
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+// Analysis is a named report computed from both clusters. Its findings use the DAO.DiffWithName
+// of the comparators, with the analysis name as Kind, so baselines and reports work the same way.
+type Analysis struct {
+	Name        string
+	Description string
+	Run         func(scope Scope) ([]DAO.DiffWithName, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Analysis{}
+)
+
+// Register makes an analysis available to the --analyze option. It fails when the name is already registered.
+func Register(a Analysis) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if a.Name == "" || a.Run == nil {
+		return fmt.Errorf("analysis %q needs a name and a Run function", a.Name)
+	}
+	if _, found := registry[a.Name]; found {
+		return fmt.Errorf("analysis %q is already registered", a.Name)
+	}
+	registry[a.Name] = a
+	return nil
+}
+
+// MustRegister is Register for init functions; it panics when the analysis cannot be registered.
+func MustRegister(a Analysis) {
+	if err := Register(a); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the analysis registered under a name.
+func Lookup(name string) (Analysis, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, found := registry[name]
+	return a, found
+}
+
+// Names returns the sorted names of the registered analyses.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Print writes the findings of an analysis, one line per difference.
+func Print(a Analysis, findings []DAO.DiffWithName) {
+	fmt.Printf("%s\n", a.Description)
+	printed := false
+	for _, finding := range findings {
+		for _, line := range finding.Diff {
+			if finding.Severity != "" {
+				fmt.Printf("- [%s] %s\n", finding.Severity, line)
+			} else {
+				fmt.Printf("- %s\n", line)
+			}
+			printed = true
+		}
+	}
+	if !printed {
+		fmt.Println("No differences found.")
+	}
+}
+
+// newFinding builds a one-line finding. property identifies the finding within the object, for baselines.
+func newFinding(kind, namespace, name, property, severity, line string) DAO.DiffWithName {
+	return DAO.DiffWithName{
+		Kind:         kind,
+		Namespace:    namespace,
+		Name:         name,
+		PropertyName: property,
+		Severity:     severity,
+		Diff:         []string{line},
+	}
+}
+
+// inCluster names the side of a comparison in findings.
+func inCluster(target bool) string {
+	if target {
+		return "target"
+	}
+	return "source"
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelsSet adapts an object's labels to label selectors.
+func labelsSet(objectLabels map[string]string) labels.Set {
+	return labels.Set(objectLabels)
+}
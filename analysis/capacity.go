@@ -0,0 +1,329 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "capacity",
+		Description: "Resource requests and limits per namespace and cluster, and whether they fit the target's nodes and ResourceQuotas",
+		Run:         runCapacity,
+	})
+}
+
+// capacityResources are the resources summed, in the order they are reported.
+var capacityResources = []Corev1.ResourceName{Corev1.ResourceCPU, Corev1.ResourceMemory, Corev1.ResourceEphemeralStorage}
+
+// Usage is what the pods of a namespace request and are limited to, replicas included.
+type Usage struct {
+	Requests Corev1.ResourceList
+	Limits   Corev1.ResourceList
+}
+
+// CapacityObjects are the workloads, nodes and ResourceQuotas of one cluster.
+type CapacityObjects struct {
+	Workloads []Workload
+	Nodes     *Corev1.NodeList
+	Quotas    *Corev1.ResourceQuotaList
+}
+
+// Prices is a local price table, in any currency and period, to turn requests into costs.
+type Prices struct {
+	CPU       float64 `json:"cpu"`
+	MemoryGiB float64 `json:"memoryGiB"`
+}
+
+// LoadPrices reads a price table file, e.g. {"cpu": 25, "memoryGiB": 3.5}.
+func LoadPrices(path string) (*Prices, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prices %s: %v", path, err)
+	}
+	var prices Prices
+	if err := json.Unmarshal(content, &prices); err != nil {
+		return nil, fmt.Errorf("error parsing prices %s: %v", path, err)
+	}
+	return &prices, nil
+}
+
+func listCapacityObjects(cluster compare.Cluster, namespaces []string) (CapacityObjects, error) {
+	objects := CapacityObjects{Quotas: &Corev1.ResourceQuotaList{}}
+	var err error
+	if objects.Nodes, err = query.ListNodes(cluster.Clientset); err != nil {
+		return objects, err
+	}
+	if objects.Workloads, err = listWorkloads(cluster, namespaces); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		quotas, err := query.ListResourceQuotas(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Quotas.Items = append(objects.Quotas.Items, quotas.Items...)
+	}
+	return objects, nil
+}
+
+func runCapacity(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	var prices *Prices
+	if scope.Args.Prices != "" {
+		if prices, err = LoadPrices(scope.Args.Prices); err != nil {
+			return nil, err
+		}
+	}
+	source, err := listCapacityObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	// The whole target is listed: the namespaces out of scope use its capacity too.
+	_, targetNamespaces, err := allNamespaces(scope.Target)
+	if err != nil {
+		return nil, fmt.Errorf("error listing namespaces in the target cluster: %v", err)
+	}
+	target, err := listCapacityObjects(scope.Target, targetNamespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return DiffCapacity(source, target, namespaces, prices), nil
+}
+
+// DiffCapacity compares the usage of the given namespaces and checks whether the source's usage fits the target:
+//   - a different total request or limit is info, as is a different cost when prices are given
+//   - a namespace whose source requests exceed the target's allocatable capacity, minus what the target's other
+//     namespaces request, would not fit and is an error
+//   - a namespace whose source usage exceeds a ResourceQuota of the target would exceed quota and is an error
+//
+// With more than one namespace, their totals are compared too, and checked to fit the target together.
+func DiffCapacity(source, target CapacityObjects, namespaces []string, prices *Prices) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	sourceUsage, targetUsage := NamespaceUsage(source), NamespaceUsage(target)
+	allocatable := Allocatable(target.Nodes)
+	targetTotal := newUsage()
+	for _, usage := range targetUsage {
+		addUsage(targetTotal, usage)
+	}
+	sourceScope, targetScope := newUsage(), newUsage()
+	for _, namespace := range namespaces {
+		sourceNamespace, targetNamespace := sourceUsage[namespace], targetUsage[namespace]
+		addUsage(sourceScope, sourceNamespace)
+		addUsage(targetScope, targetNamespace)
+		object := "Namespace " + namespace
+		findings = append(findings, usageFindings(namespace, object, sourceNamespace, targetNamespace)...)
+		findings = append(findings, fitFindings(namespace, object, object+" would not fit in target: it requests",
+			sourceNamespace, targetNamespace, targetTotal, allocatable)...)
+		findings = append(findings, quotaFindings(namespace, sourceNamespace, target.Quotas)...)
+		findings = append(findings, costFindings(namespace, object, sourceNamespace, targetNamespace, prices)...)
+	}
+	if len(namespaces) > 1 {
+		// Namespaces that each fit on their own can still exceed the target together.
+		findings = append(findings, usageFindings("", "Cluster", sourceScope, targetScope)...)
+		findings = append(findings, fitFindings("", "Cluster", fmt.Sprintf("The %d namespaces analyzed would not fit in target together: they request", len(namespaces)),
+			sourceScope, targetScope, targetTotal, allocatable)...)
+		findings = append(findings, costFindings("", "Cluster", sourceScope, targetScope, prices)...)
+	}
+	return findings
+}
+
+// usageFindings reports the requests and limits that differ between the source and target usage of object.
+func usageFindings(namespace, object string, source, target Usage) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	for _, kind := range []string{"requests", "limits"} {
+		sourceList, targetList := source.Requests, target.Requests
+		if kind == "limits" {
+			sourceList, targetList = source.Limits, target.Limits
+		}
+		for _, name := range capacityResources {
+			sourceQuantity, targetQuantity := sourceList[name], targetList[name]
+			if sourceQuantity.Cmp(targetQuantity) != 0 {
+				findings = append(findings, newFinding("capacity", namespace, object, kind+" "+string(name), plugins.SeverityInfo,
+					fmt.Sprintf("%s: %s %s is %s in source, %s in target", object, string(name), kind, sourceQuantity.String(), targetQuantity.String())))
+			}
+		}
+	}
+	return findings
+}
+
+// fitFindings reports the resources the source requests of object need more of than the target has free: its
+// allocatable capacity, minus what the target requests outside of object. The message starts with requests.
+func fitFindings(namespace, object, requests string, source, target, targetTotal Usage, allocatable Corev1.ResourceList) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	for _, name := range capacityResources {
+		capacity, known := allocatable[name]
+		if !known {
+			continue
+		}
+		needed, others := source.Requests[name], targetTotal.Requests[name].DeepCopy()
+		others.Sub(target.Requests[name])
+		free := capacity.DeepCopy()
+		free.Sub(others)
+		if needed.Cmp(free) > 0 {
+			findings = append(findings, newFinding("capacity", namespace, object, "fit "+string(name), plugins.SeverityError,
+				fmt.Sprintf("%s %s %s in source, and target has %s allocatable with %s requested by other namespaces",
+					requests, needed.String(), string(name), capacity.String(), others.String())))
+		}
+	}
+	return findings
+}
+
+// costFindings reports a different cost of the source and target requests of object, when prices are given.
+func costFindings(namespace, object string, source, target Usage, prices *Prices) []DAO.DiffWithName {
+	if prices == nil {
+		return nil
+	}
+	sourceCost, targetCost := prices.Cost(source), prices.Cost(target)
+	if fmt.Sprintf("%.2f", sourceCost) == fmt.Sprintf("%.2f", targetCost) {
+		return nil
+	}
+	return []DAO.DiffWithName{newFinding("capacity", namespace, object, "cost", plugins.SeverityInfo,
+		fmt.Sprintf("%s: requests cost %.2f in source, %.2f in target (%+.2f)", object, sourceCost, targetCost, targetCost-sourceCost))}
+}
+
+// quotaFindings checks the source usage of a namespace against the hard limits of the target's ResourceQuotas.
+// "cpu" and "requests.cpu" limit requests, "limits.cpu" limits; the same goes for memory and ephemeral storage.
+func quotaFindings(namespace string, usage Usage, quotas *Corev1.ResourceQuotaList) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	if quotas == nil {
+		return findings
+	}
+	for _, quota := range quotas.Items {
+		if quota.Namespace != namespace {
+			continue
+		}
+		for _, name := range capacityResources {
+			checks := []struct {
+				hard Corev1.ResourceName
+				used resource.Quantity
+			}{
+				{name, usage.Requests[name]},
+				{"requests." + name, usage.Requests[name]},
+				{"limits." + name, usage.Limits[name]},
+			}
+			for _, check := range checks {
+				hard, limited := quota.Spec.Hard[check.hard]
+				if limited && check.used.Cmp(hard) > 0 {
+					findings = append(findings, newFinding("capacity", namespace, "Namespace "+namespace, "quota "+quota.Name+" "+string(check.hard), plugins.SeverityError,
+						fmt.Sprintf("Namespace %s would exceed ResourceQuota %s/%s in target: %s is %s in source, with a hard limit of %s",
+							namespace, namespace, quota.Name, string(check.hard), check.used.String(), hard.String())))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// Cost prices the requests of a namespace.
+func (p Prices) Cost(usage Usage) float64 {
+	cpu, memory := usage.Requests[Corev1.ResourceCPU], usage.Requests[Corev1.ResourceMemory]
+	return cpu.AsApproximateFloat64()*p.CPU + memory.AsApproximateFloat64()/(1<<30)*p.MemoryGiB
+}
+
+// NamespaceUsage sums the usage of the pods of every workload per namespace: replicas times the usage of a pod.
+// A DaemonSet runs a pod on every schedulable node. A CronJob counts as a single pod.
+func NamespaceUsage(objects CapacityObjects) map[string]Usage {
+	nodes := int64(0)
+	if objects.Nodes != nil {
+		for _, node := range objects.Nodes.Items {
+			if !node.Spec.Unschedulable {
+				nodes++
+			}
+		}
+	}
+	usages := map[string]Usage{}
+	for _, workload := range objects.Workloads {
+		replicas := int64(workload.Replicas)
+		if workload.Kind == "DaemonSet" && nodes > 0 {
+			replicas = nodes
+		}
+		usage, found := usages[workload.Namespace]
+		if !found {
+			usage = newUsage()
+			usages[workload.Namespace] = usage
+		}
+		pod := PodUsage(workload.Template.Spec)
+		addResources(usage.Requests, scaleResources(pod.Requests, replicas))
+		addResources(usage.Limits, scaleResources(pod.Limits, replicas))
+	}
+	return usages
+}
+
+// PodUsage is the usage of a pod like the scheduler sees it: the sum of its containers, or the largest
+// init container when larger, plus the pod overhead. Containers without a limit do not add to the limits.
+func PodUsage(spec Corev1.PodSpec) Usage {
+	usage := newUsage()
+	for _, container := range spec.Containers {
+		addResources(usage.Requests, container.Resources.Requests)
+		addResources(usage.Limits, container.Resources.Limits)
+	}
+	for _, container := range spec.InitContainers {
+		maxResources(usage.Requests, container.Resources.Requests)
+		maxResources(usage.Limits, container.Resources.Limits)
+	}
+	addResources(usage.Requests, spec.Overhead)
+	addResources(usage.Limits, spec.Overhead)
+	return usage
+}
+
+// Allocatable sums the allocatable capacity of the schedulable nodes.
+func Allocatable(nodes *Corev1.NodeList) Corev1.ResourceList {
+	allocatable := Corev1.ResourceList{}
+	if nodes == nil {
+		return allocatable
+	}
+	for _, node := range nodes.Items {
+		if !node.Spec.Unschedulable {
+			addResources(allocatable, node.Status.Allocatable)
+		}
+	}
+	return allocatable
+}
+
+func newUsage() Usage {
+	return Usage{Requests: Corev1.ResourceList{}, Limits: Corev1.ResourceList{}}
+}
+
+func addUsage(total, usage Usage) {
+	addResources(total.Requests, usage.Requests)
+	addResources(total.Limits, usage.Limits)
+}
+
+func addResources(total, list Corev1.ResourceList) {
+	for _, name := range capacityResources {
+		if quantity, found := list[name]; found {
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+	}
+}
+
+func maxResources(total, list Corev1.ResourceList) {
+	for _, name := range capacityResources {
+		if quantity, found := list[name]; found && quantity.Cmp(total[name]) > 0 {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func scaleResources(list Corev1.ResourceList, factor int64) Corev1.ResourceList {
+	scaled := Corev1.ResourceList{}
+	for name, quantity := range list {
+		scaled[name] = *resource.NewMilliQuantity(quantity.MilliValue()*factor, quantity.Format)
+	}
+	return scaled
+}
@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func sizedWorkload(namespace, name string, replicas int32, cpu, memory string) Workload {
+	w := workload(namespace, name, nil)
+	w.Replicas = replicas
+	w.Template.Spec.Containers[0].Resources.Requests = Corev1.ResourceList{Corev1.ResourceCPU: resource.MustParse(cpu), Corev1.ResourceMemory: resource.MustParse(memory)}
+	return w
+}
+
+func nodes(count int, cpu, memory string) *Corev1.NodeList {
+	list := &Corev1.NodeList{}
+	for i := 0; i < count; i++ {
+		list.Items = append(list.Items, Corev1.Node{Status: Corev1.NodeStatus{Allocatable: Corev1.ResourceList{
+			Corev1.ResourceCPU: resource.MustParse(cpu), Corev1.ResourceMemory: resource.MustParse(memory),
+		}}})
+	}
+	return list
+}
+
+func TestPodUsage(t *testing.T) {
+	spec := Corev1.PodSpec{
+		Containers: []Corev1.Container{
+			{Resources: Corev1.ResourceRequirements{Requests: Corev1.ResourceList{Corev1.ResourceCPU: resource.MustParse("250m")}}},
+			{Resources: Corev1.ResourceRequirements{Requests: Corev1.ResourceList{Corev1.ResourceCPU: resource.MustParse("250m")}}},
+		},
+		InitContainers: []Corev1.Container{
+			{Resources: Corev1.ResourceRequirements{Requests: Corev1.ResourceList{Corev1.ResourceCPU: resource.MustParse("1")}}},
+		},
+	}
+
+	usage := PodUsage(spec)
+
+	if cpu := usage.Requests[Corev1.ResourceCPU]; cpu.String() != "1" {
+		t.Errorf("Expected the init container to dominate the requests, got %s", cpu.String())
+	}
+}
+
+func TestDiffCapacity(t *testing.T) {
+	source := CapacityObjects{Workloads: []Workload{sizedWorkload("payments", "api", 4, "1", "1Gi")}}
+	target := CapacityObjects{
+		Workloads: []Workload{sizedWorkload("payments", "api", 1, "1", "1Gi"), sizedWorkload("batch", "jobs", 1, "2", "1Gi")},
+		Nodes:     nodes(1, "4", "16Gi"),
+		Quotas: &Corev1.ResourceQuotaList{Items: []Corev1.ResourceQuota{{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "payments"},
+			Spec:       Corev1.ResourceQuotaSpec{Hard: Corev1.ResourceList{Corev1.ResourceRequestsMemory: resource.MustParse("2Gi")}},
+		}}},
+	}
+
+	var lines []string
+	for _, finding := range DiffCapacity(source, target, []string{"payments"}, &Prices{CPU: 10, MemoryGiB: 1}) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	expected := []string{
+		"info Namespace payments: cpu requests is 4 in source, 1 in target",
+		"info Namespace payments: memory requests is 4Gi in source, 1Gi in target",
+		"error Namespace payments would not fit in target: it requests 4 cpu in source, and target has 4 allocatable with 2 requested by other namespaces",
+		"error Namespace payments would exceed ResourceQuota payments/compute in target: requests.memory is 4Gi in source, with a hard limit of 2Gi",
+		"info Namespace payments: requests cost 44.00 in source, 11.00 in target (-33.00)",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffCapacityClusterTotals(t *testing.T) {
+	source := CapacityObjects{Workloads: []Workload{sizedWorkload("payments", "api", 1, "3", "1Gi"), sizedWorkload("web", "frontend", 1, "3", "1Gi")}}
+	target := CapacityObjects{
+		Workloads: []Workload{sizedWorkload("payments", "api", 1, "1", "1Gi"), sizedWorkload("web", "frontend", 1, "1", "1Gi")},
+		Nodes:     nodes(1, "5", "16Gi"),
+	}
+
+	var lines []string
+	for _, finding := range DiffCapacity(source, target, []string{"payments", "web"}, &Prices{CPU: 10, MemoryGiB: 1}) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	// Each namespace fits on its own, with 4 cpu free, but not both.
+	expected := []string{
+		"info Namespace payments: cpu requests is 3 in source, 1 in target",
+		"info Namespace payments: requests cost 31.00 in source, 11.00 in target (-20.00)",
+		"info Namespace web: cpu requests is 3 in source, 1 in target",
+		"info Namespace web: requests cost 31.00 in source, 11.00 in target (-20.00)",
+		"info Cluster: cpu requests is 6 in source, 2 in target",
+		"error The 2 namespaces analyzed would not fit in target together: they request 6 cpu in source, and target has 5 allocatable with 0 requested by other namespaces",
+		"info Cluster: requests cost 62.00 in source, 22.00 in target (-40.00)",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestNamespaceUsageDaemonSet(t *testing.T) {
+	agent := sizedWorkload("monitoring", "agent", 1, "100m", "128Mi")
+	agent.Kind = "DaemonSet"
+
+	usage := NamespaceUsage(CapacityObjects{Workloads: []Workload{agent}, Nodes: nodes(3, "4", "16Gi")})
+
+	if cpu := usage["monitoring"].Requests[Corev1.ResourceCPU]; cpu.String() != "300m" {
+		t.Errorf("Expected a pod per node, got %s", cpu.String())
+	}
+}
+
+func TestLoadPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(`{"cpu": 25, "memoryGiB": 3.5}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prices, err := LoadPrices(path)
+
+	if err != nil || *prices != (Prices{CPU: 25, MemoryGiB: 3.5}) {
+		t.Errorf("Expected the price table, got %+v, %v", prices, err)
+	}
+}
@@ -0,0 +1,501 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/plugins"
+	"kompare/query"
+
+	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "crds",
+		Description: "CustomResourceDefinition schema compatibility, and whether the source's custom resources validate against the target's schemas",
+		Run:         runCRDs,
+	})
+}
+
+// SchemaChange is a change of a CRD from the source to the target. Breaking changes are errors,
+// compatible ones are info, and the ones that may or may not break clients are warnings.
+type SchemaChange struct {
+	CRD         string
+	Version     string
+	Description string
+	Severity    string
+}
+
+func (c SchemaChange) String() string {
+	prefix := "CustomResourceDefinition " + c.CRD
+	if c.Version != "" {
+		prefix += ", version " + c.Version
+	}
+	return prefix + ": " + c.Description
+}
+
+// CRDObjects are the CRDs of one cluster and, by CRD name, the custom resources to validate.
+type CRDObjects struct {
+	CRDs      *apiextensionv1.CustomResourceDefinitionList
+	Resources map[string]*unstructured.UnstructuredList
+}
+
+func runCRDs(scope Scope) ([]DAO.DiffWithName, error) {
+	source := CRDObjects{Resources: map[string]*unstructured.UnstructuredList{}}
+	var err error
+	if source.CRDs, err = query.ListCRDs(scope.Source.Context, scope.Source.Kubeconfig); err != nil {
+		return nil, fmt.Errorf("error listing CRDs in the source cluster: %v", err)
+	}
+	target := CRDObjects{}
+	if target.CRDs, err = query.ListCRDs(scope.Target.Context, scope.Target.Kubeconfig); err != nil {
+		return nil, fmt.Errorf("error listing CRDs in the target cluster: %v", err)
+	}
+	targetCRDs := crdsByName(target.CRDs)
+	for _, crd := range source.CRDs.Items {
+		targetCRD, found := targetCRDs[crd.Name]
+		if !found {
+			continue
+		}
+		version := validationVersion(crd, targetCRD)
+		if version == "" {
+			continue
+		}
+		resource := schema.GroupVersionResource{Group: crd.Spec.Group, Version: version, Resource: crd.Spec.Names.Plural}
+		if source.Resources[crd.Name], err = query.ListCustomResources(scope.Source.Context, scope.Source.Kubeconfig, resource); err != nil {
+			return nil, fmt.Errorf("error listing %s in the source cluster: %v", resource.String(), err)
+		}
+		var inScope []unstructured.Unstructured
+		for _, item := range source.Resources[crd.Name].Items {
+			if scope.Includes(item.GetNamespace()) {
+				inScope = append(inScope, item)
+			}
+		}
+		source.Resources[crd.Name].Items = inScope
+	}
+	return CRDCompatibility(source, target), nil
+}
+
+// CRDCompatibility reports the changes of the CRDs present in both clusters, from the source to the target,
+// and the custom resources of the source that would not validate against the target's schema.
+func CRDCompatibility(source, target CRDObjects) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	targetCRDs := crdsByName(target.CRDs)
+	sourceCRDs := crdsByName(source.CRDs)
+	names := make([]string, 0, len(sourceCRDs))
+	for name := range sourceCRDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		targetCRD, found := targetCRDs[name]
+		if !found {
+			continue
+		}
+		sourceCRD := sourceCRDs[name]
+		for _, change := range DiffCRD(sourceCRD, targetCRD) {
+			findings = append(findings, newFinding("crds", "", "CustomResourceDefinition "+name, change.Version+" "+change.Description, change.Severity, change.String()))
+		}
+		resources := source.Resources[name]
+		if resources == nil {
+			continue
+		}
+		version := validationVersion(sourceCRD, targetCRD)
+		targetSchema := versionSchema(targetCRD, version)
+		for _, item := range resources.Items {
+			object := item.GetKind() + " " + item.GetName()
+			if item.GetNamespace() != "" {
+				object = item.GetKind() + " " + item.GetNamespace() + "/" + item.GetName()
+			}
+			for _, violation := range ValidateResource(targetSchema, item.Object) {
+				findings = append(findings, newFinding("crds", item.GetNamespace(), object, violation, plugins.SeverityError,
+					fmt.Sprintf("%s would not validate against the target schema of %s %s: %s", object, name, version, violation)))
+			}
+		}
+	}
+	return findings
+}
+
+// DiffCRD classifies the changes of a CRD from the source to the target: scope, served and storage versions,
+// conversion, and the openAPIV3Schema of every version served by both.
+func DiffCRD(source, target apiextensionv1.CustomResourceDefinition) []SchemaChange {
+	var changes []SchemaChange
+	add := func(version, severity, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{CRD: source.Name, Version: version, Severity: severity, Description: fmt.Sprintf(format, args...)})
+	}
+	if source.Spec.Scope != target.Spec.Scope {
+		add("", plugins.SeverityError, "scope changes from %s to %s (breaking)", source.Spec.Scope, target.Spec.Scope)
+	}
+	sourceVersions, targetVersions := crdVersions(source), crdVersions(target)
+	for _, version := range source.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+		if targetVersion, found := targetVersions[version.Name]; !found || !targetVersion.Served {
+			add(version.Name, plugins.SeverityError, "version is no longer served in target (breaking)")
+		}
+	}
+	for _, version := range target.Spec.Versions {
+		if sourceVersion, found := sourceVersions[version.Name]; version.Served && (!found || !sourceVersion.Served) {
+			add(version.Name, plugins.SeverityInfo, "version is only served in target (compatible)")
+		}
+	}
+	if sourceStorage, targetStorage := storageVersion(source), storageVersion(target); sourceStorage != targetStorage {
+		add("", plugins.SeverityWarning, "storage version changes from %s to %s", sourceStorage, targetStorage)
+	}
+	if sourceConversion, targetConversion := describeConversion(source.Spec.Conversion), describeConversion(target.Spec.Conversion); sourceConversion != targetConversion {
+		add("", plugins.SeverityWarning, "conversion changes from %s to %s", sourceConversion, targetConversion)
+	}
+	for _, version := range source.Spec.Versions {
+		targetVersion, found := targetVersions[version.Name]
+		if !version.Served || !found || !targetVersion.Served {
+			continue
+		}
+		var sourceSchema, targetSchema *apiextensionv1.JSONSchemaProps
+		if version.Schema != nil {
+			sourceSchema = version.Schema.OpenAPIV3Schema
+		}
+		if targetVersion.Schema != nil {
+			targetSchema = targetVersion.Schema.OpenAPIV3Schema
+		}
+		for _, change := range DiffSchema("", sourceSchema, targetSchema) {
+			add(version.Name, change.Severity, "%s", change.Description)
+		}
+	}
+	return changes
+}
+
+// DiffSchema classifies the changes of a schema from the source to the target. A change that makes the target
+// reject or prune what the source accepts is breaking: a removed field, a type change, a newly required field,
+// removed enum values, a new pattern, or unknown fields no longer preserved.
+func DiffSchema(path string, source, target *apiextensionv1.JSONSchemaProps) []SchemaChange {
+	var changes []SchemaChange
+	add := func(severity, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{Severity: severity, Description: fmt.Sprintf(format, args...)})
+	}
+	if source == nil || target == nil {
+		if source == nil && target != nil && path == "" {
+			add(plugins.SeverityWarning, "a schema is added in target")
+		} else if source != nil && target == nil && path == "" {
+			add(plugins.SeverityInfo, "the schema is removed in target (compatible)")
+		}
+		return changes
+	}
+	field := path
+	if field == "" {
+		field = "."
+	}
+	if source.Type != "" && target.Type != "" && source.Type != target.Type {
+		add(plugins.SeverityError, "%s type changes from %s to %s (breaking)", field, source.Type, target.Type)
+		return changes
+	}
+	if preserves(source) && !preserves(target) {
+		add(plugins.SeverityError, "%s no longer preserves unknown fields, they would be pruned (breaking)", field)
+	}
+	sourceRequired := map[string]bool{}
+	for _, name := range source.Required {
+		sourceRequired[name] = true
+	}
+	targetRequired := map[string]bool{}
+	for _, name := range target.Required {
+		targetRequired[name] = true
+		if !sourceRequired[name] {
+			add(plugins.SeverityError, "%s.%s becomes required (breaking)", path, name)
+		}
+	}
+	for _, name := range source.Required {
+		if !targetRequired[name] {
+			add(plugins.SeverityInfo, "%s.%s is no longer required (compatible)", path, name)
+		}
+	}
+	changes = append(changes, diffEnum(field, source.Enum, target.Enum)...)
+	switch {
+	case source.Pattern == "" && target.Pattern != "":
+		add(plugins.SeverityError, "%s gets the pattern %s (breaking)", field, target.Pattern)
+	case source.Pattern != "" && target.Pattern == "":
+		add(plugins.SeverityInfo, "%s loses the pattern %s (compatible)", field, source.Pattern)
+	case source.Pattern != target.Pattern:
+		add(plugins.SeverityWarning, "%s pattern changes from %s to %s", field, source.Pattern, target.Pattern)
+	}
+	for _, name := range sortedPropertyNames(source.Properties) {
+		sourceProperty := source.Properties[name]
+		targetProperty, found := target.Properties[name]
+		if !found {
+			if preserves(target) {
+				add(plugins.SeverityWarning, "%s.%s is removed, and only kept as an unknown field", path, name)
+			} else {
+				add(plugins.SeverityError, "%s.%s is removed (breaking)", path, name)
+			}
+			continue
+		}
+		changes = append(changes, DiffSchema(path+"."+name, &sourceProperty, &targetProperty)...)
+	}
+	for _, name := range sortedPropertyNames(target.Properties) {
+		if _, found := source.Properties[name]; !found {
+			add(plugins.SeverityInfo, "%s.%s is added (compatible)", path, name)
+		}
+	}
+	if source.Items != nil && target.Items != nil {
+		changes = append(changes, DiffSchema(path+"[]", source.Items.Schema, target.Items.Schema)...)
+	}
+	if source.AdditionalProperties != nil && target.AdditionalProperties != nil {
+		changes = append(changes, DiffSchema(path+"[*]", source.AdditionalProperties.Schema, target.AdditionalProperties.Schema)...)
+	}
+	return changes
+}
+
+func diffEnum(field string, source, target []apiextensionv1.JSON) []SchemaChange {
+	var changes []SchemaChange
+	add := func(severity, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{Severity: severity, Description: fmt.Sprintf(format, args...)})
+	}
+	sourceValues, targetValues := enumValues(source), enumValues(target)
+	switch {
+	case len(source) == 0 && len(target) != 0:
+		add(plugins.SeverityError, "%s is restricted to %s (breaking)", field, strings.Join(sortedKeys(targetValues), ", "))
+	case len(source) != 0 && len(target) == 0:
+		add(plugins.SeverityInfo, "%s is no longer restricted to %s (compatible)", field, strings.Join(sortedKeys(sourceValues), ", "))
+	case len(source) != 0:
+		var removed, added []string
+		for value := range sourceValues {
+			if !targetValues[value] {
+				removed = append(removed, value)
+			}
+		}
+		for value := range targetValues {
+			if !sourceValues[value] {
+				added = append(added, value)
+			}
+		}
+		sort.Strings(removed)
+		sort.Strings(added)
+		if len(removed) != 0 {
+			add(plugins.SeverityError, "%s no longer allows %s (breaking)", field, strings.Join(removed, ", "))
+		}
+		if len(added) != 0 {
+			add(plugins.SeverityInfo, "%s also allows %s (compatible)", field, strings.Join(added, ", "))
+		}
+	}
+	return changes
+}
+
+// ValidateResource checks a custom resource against a schema: types, required fields, enums and patterns,
+// and the fields the schema does not know, which would be pruned. CEL rules and formats are not checked.
+func ValidateResource(schema *apiextensionv1.JSONSchemaProps, object map[string]interface{}) []string {
+	if schema == nil {
+		return nil
+	}
+	content := map[string]interface{}{}
+	for key, value := range object {
+		// apiVersion, kind and metadata are validated by the API server itself.
+		if key != "apiVersion" && key != "kind" && key != "metadata" {
+			content[key] = value
+		}
+	}
+	return validateValue("", schema, content)
+}
+
+func validateValue(path string, schema *apiextensionv1.JSONSchemaProps, value interface{}) []string {
+	if schema == nil {
+		return nil
+	}
+	field := path
+	if field == "" {
+		field = "."
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return []string{field + " must not be null"}
+	}
+	if !hasType(schema, value) {
+		return []string{fmt.Sprintf("%s must be of type %s", field, schema.Type)}
+	}
+	var violations []string
+	if len(schema.Enum) != 0 && !enumValues(schema.Enum)[jsonString(value)] {
+		violations = append(violations, fmt.Sprintf("%s must be one of %s", field, strings.Join(sortedKeys(enumValues(schema.Enum)), ", ")))
+	}
+	if text, isString := value.(string); isString && schema.Pattern != "" {
+		if pattern, err := regexp.Compile(schema.Pattern); err == nil && !pattern.MatchString(text) {
+			violations = append(violations, fmt.Sprintf("%s must match %s", field, schema.Pattern))
+		}
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, found := typed[name]; !found {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, found := schema.Properties[key]; found {
+				violations = append(violations, validateValue(path+"."+key, &property, typed[key])...)
+			} else if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+				violations = append(violations, validateValue(path+"."+key, schema.AdditionalProperties.Schema, typed[key])...)
+			} else if !preserves(schema) && (schema.AdditionalProperties == nil || !schema.AdditionalProperties.Allows) && !schema.XEmbeddedResource {
+				violations = append(violations, fmt.Sprintf("%s.%s is unknown and would be pruned", path, key))
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range typed {
+				violations = append(violations, validateValue(fmt.Sprintf("%s[%d]", path, i), schema.Items.Schema, item)...)
+			}
+		}
+	}
+	return violations
+}
+
+func hasType(schema *apiextensionv1.JSONSchemaProps, value interface{}) bool {
+	if schema.XIntOrString {
+		switch value.(type) {
+		case string, int64, float64:
+			return true
+		}
+		return false
+	}
+	switch schema.Type {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch number := value.(type) {
+		case int64:
+			return true
+		case float64:
+			return number == float64(int64(number))
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int64, float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// preserves reports whether a schema keeps the fields it does not know.
+func preserves(schema *apiextensionv1.JSONSchemaProps) bool {
+	return schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields
+}
+
+func enumValues(enum []apiextensionv1.JSON) map[string]bool {
+	values := map[string]bool{}
+	for _, value := range enum {
+		values[string(value.Raw)] = true
+	}
+	return values
+}
+
+func jsonString(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+func sortedPropertyNames(properties map[string]apiextensionv1.JSONSchemaProps) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func crdsByName(list *apiextensionv1.CustomResourceDefinitionList) map[string]apiextensionv1.CustomResourceDefinition {
+	crds := map[string]apiextensionv1.CustomResourceDefinition{}
+	if list != nil {
+		for _, crd := range list.Items {
+			crds[crd.Name] = crd
+		}
+	}
+	return crds
+}
+
+func crdVersions(crd apiextensionv1.CustomResourceDefinition) map[string]apiextensionv1.CustomResourceDefinitionVersion {
+	versions := map[string]apiextensionv1.CustomResourceDefinitionVersion{}
+	for _, version := range crd.Spec.Versions {
+		versions[version.Name] = version
+	}
+	return versions
+}
+
+func storageVersion(crd apiextensionv1.CustomResourceDefinition) string {
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			return version.Name
+		}
+	}
+	return "none"
+}
+
+func versionSchema(crd apiextensionv1.CustomResourceDefinition, name string) *apiextensionv1.JSONSchemaProps {
+	if version, found := crdVersions(crd)[name]; found && version.Schema != nil {
+		return version.Schema.OpenAPIV3Schema
+	}
+	return nil
+}
+
+// validationVersion is the version the source's custom resources are read in and validated against:
+// the target's storage version when the source serves it, or else the first version both serve.
+func validationVersion(source, target apiextensionv1.CustomResourceDefinition) string {
+	sourceVersions, targetVersions := crdVersions(source), crdVersions(target)
+	if version, found := sourceVersions[storageVersion(target)]; found && version.Served {
+		return version.Name
+	}
+	for _, version := range source.Spec.Versions {
+		if targetVersion, found := targetVersions[version.Name]; version.Served && found && targetVersion.Served {
+			return version.Name
+		}
+	}
+	return ""
+}
+
+// describeConversion summarizes a conversion strategy, e.g. "Webhook via service operators/converter:443/convert (v1)".
+func describeConversion(conversion *apiextensionv1.CustomResourceConversion) string {
+	if conversion == nil || conversion.Strategy == apiextensionv1.NoneConverter {
+		return string(apiextensionv1.NoneConverter)
+	}
+	if conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		return string(conversion.Strategy)
+	}
+	endpoint := "an unknown endpoint"
+	clientConfig := conversion.Webhook.ClientConfig
+	if clientConfig.URL != nil {
+		endpoint = *clientConfig.URL
+	} else if service := clientConfig.Service; service != nil {
+		endpoint = "service " + service.Namespace + "/" + service.Name
+		if service.Port != nil {
+			endpoint += fmt.Sprintf(":%d", *service.Port)
+		}
+		if service.Path != nil {
+			endpoint += *service.Path
+		}
+	}
+	return fmt.Sprintf("%s via %s (%s)", conversion.Strategy, endpoint, strings.Join(conversion.Webhook.ConversionReviewVersions, ", "))
+}
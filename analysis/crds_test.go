@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func widgetSchema(sizes []string, required ...string) *apiextensionv1.JSONSchemaProps {
+	var enum []apiextensionv1.JSON
+	for _, size := range sizes {
+		enum = append(enum, apiextensionv1.JSON{Raw: []byte(`"` + size + `"`)})
+	}
+	return &apiextensionv1.JSONSchemaProps{Type: "object", Properties: map[string]apiextensionv1.JSONSchemaProps{
+		"spec": {Type: "object", Required: required, Properties: map[string]apiextensionv1.JSONSchemaProps{
+			"size":     {Type: "string", Enum: enum},
+			"replicas": {Type: "integer"},
+		}},
+	}}
+}
+
+func widgetCRD(schema *apiextensionv1.JSONSchemaProps, versions ...string) apiextensionv1.CustomResourceDefinition {
+	crd := apiextensionv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		Spec: apiextensionv1.CustomResourceDefinitionSpec{Group: "example.com", Scope: apiextensionv1.NamespaceScoped,
+			Names: apiextensionv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget"}},
+	}
+	for i, version := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionv1.CustomResourceDefinitionVersion{
+			Name: version, Served: true, Storage: i == len(versions)-1, Schema: &apiextensionv1.CustomResourceValidation{OpenAPIV3Schema: schema},
+		})
+	}
+	return crd
+}
+
+func TestDiffCRD(t *testing.T) {
+	source := widgetCRD(widgetSchema([]string{"small", "large"}), "v1alpha1", "v1")
+	target := widgetCRD(widgetSchema([]string{"small"}, "size"), "v1")
+	delete(target.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties, "replicas")
+	target.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["color"] = apiextensionv1.JSONSchemaProps{Type: "string"}
+
+	var lines []string
+	for _, change := range DiffCRD(source, target) {
+		lines = append(lines, change.Severity+" "+change.String())
+	}
+
+	expected := []string{
+		"error CustomResourceDefinition widgets.example.com, version v1alpha1: version is no longer served in target (breaking)",
+		"error CustomResourceDefinition widgets.example.com, version v1: .spec.size becomes required (breaking)",
+		"error CustomResourceDefinition widgets.example.com, version v1: .spec.replicas is removed (breaking)",
+		"error CustomResourceDefinition widgets.example.com, version v1: .spec.size no longer allows \"large\" (breaking)",
+		"info CustomResourceDefinition widgets.example.com, version v1: .spec.color is added (compatible)",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffSchemaTypeChange(t *testing.T) {
+	source := &apiextensionv1.JSONSchemaProps{Type: "object", Properties: map[string]apiextensionv1.JSONSchemaProps{"port": {Type: "string"}}}
+	target := &apiextensionv1.JSONSchemaProps{Type: "object", Properties: map[string]apiextensionv1.JSONSchemaProps{"port": {Type: "integer"}}}
+
+	changes := DiffSchema("", source, target)
+
+	if len(changes) != 1 || changes[0].Description != ".port type changes from string to integer (breaking)" {
+		t.Errorf("Expected a breaking type change, got %+v", changes)
+	}
+}
+
+func TestCRDCompatibilityValidatesResources(t *testing.T) {
+	source := CRDObjects{
+		CRDs: &apiextensionv1.CustomResourceDefinitionList{Items: []apiextensionv1.CustomResourceDefinition{widgetCRD(widgetSchema([]string{"small", "large"}), "v1")}},
+		Resources: map[string]*unstructured.UnstructuredList{"widgets.example.com": {Items: []unstructured.Unstructured{{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1", "kind": "Widget",
+			"metadata": map[string]interface{}{"name": "big", "namespace": "shop"},
+			"spec":     map[string]interface{}{"size": "large", "replicas": "two", "owner": "me"},
+		}}}}},
+	}
+	target := CRDObjects{CRDs: &apiextensionv1.CustomResourceDefinitionList{Items: []apiextensionv1.CustomResourceDefinition{widgetCRD(widgetSchema([]string{"small"}), "v1")}}}
+
+	var lines []string
+	for _, finding := range CRDCompatibility(source, target) {
+		lines = append(lines, finding.Diff[0])
+	}
+
+	expected := []string{
+		"CustomResourceDefinition widgets.example.com, version v1: .spec.size no longer allows \"large\" (breaking)",
+		"Widget shop/big would not validate against the target schema of widgets.example.com v1: .spec.owner is unknown and would be pruned",
+		"Widget shop/big would not validate against the target schema of widgets.example.com v1: .spec.replicas must be of type integer",
+		"Widget shop/big would not validate against the target schema of widgets.example.com v1: .spec.size must be one of \"small\"",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
@@ -0,0 +1,317 @@
+package analysis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "deprecations",
+		Description: "Objects of the source, as last applied or in Helm releases, using API versions the target removed or deprecated",
+		Run:         runDeprecations,
+	})
+}
+
+const (
+	// lastAppliedAnnotation holds the manifest "kubectl apply" last applied to an object.
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+	// helmReleaseType is the type of the secrets Helm 3 stores its releases in.
+	helmReleaseType = "helm.sh/release.v1"
+)
+
+// deprecatedAPI is when an API version of a kind was deprecated and removed, as Kubernetes 1.x minor versions.
+type deprecatedAPI struct {
+	Deprecated, Removed int
+	// Replacement is the API version to migrate to, "" when the kind itself is gone.
+	Replacement string
+}
+
+// deprecatedAPIs are the removals of the Kubernetes deprecation guide, by "apiVersion Kind".
+var deprecatedAPIs = map[string]deprecatedAPI{
+	"extensions/v1beta1 Deployment":                                       {9, 16, "apps/v1"},
+	"extensions/v1beta1 DaemonSet":                                        {9, 16, "apps/v1"},
+	"extensions/v1beta1 ReplicaSet":                                       {9, 16, "apps/v1"},
+	"extensions/v1beta1 NetworkPolicy":                                    {9, 16, "networking.k8s.io/v1"},
+	"extensions/v1beta1 PodSecurityPolicy":                                {10, 16, "policy/v1beta1"},
+	"extensions/v1beta1 Ingress":                                          {14, 22, "networking.k8s.io/v1"},
+	"apps/v1beta1 Deployment":                                             {9, 16, "apps/v1"},
+	"apps/v1beta1 StatefulSet":                                            {9, 16, "apps/v1"},
+	"apps/v1beta2 Deployment":                                             {9, 16, "apps/v1"},
+	"apps/v1beta2 StatefulSet":                                            {9, 16, "apps/v1"},
+	"apps/v1beta2 DaemonSet":                                              {9, 16, "apps/v1"},
+	"apps/v1beta2 ReplicaSet":                                             {9, 16, "apps/v1"},
+	"networking.k8s.io/v1beta1 Ingress":                                   {19, 22, "networking.k8s.io/v1"},
+	"networking.k8s.io/v1beta1 IngressClass":                              {19, 22, "networking.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1 ClusterRole":                       {17, 22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1 ClusterRoleBinding":                {17, 22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1 Role":                              {17, 22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1 RoleBinding":                       {17, 22, "rbac.authorization.k8s.io/v1"},
+	"apiextensions.k8s.io/v1beta1 CustomResourceDefinition":               {16, 22, "apiextensions.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1 MutatingWebhookConfiguration":   {16, 22, "admissionregistration.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1 ValidatingWebhookConfiguration": {16, 22, "admissionregistration.k8s.io/v1"},
+	"scheduling.k8s.io/v1beta1 PriorityClass":                             {14, 22, "scheduling.k8s.io/v1"},
+	"storage.k8s.io/v1beta1 CSIDriver":                                    {19, 22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1 CSINode":                                      {17, 22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1 StorageClass":                                 {19, 22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1 VolumeAttachment":                             {19, 22, "storage.k8s.io/v1"},
+	"certificates.k8s.io/v1beta1 CertificateSigningRequest":               {19, 22, "certificates.k8s.io/v1"},
+	"coordination.k8s.io/v1beta1 Lease":                                   {19, 22, "coordination.k8s.io/v1"},
+	"batch/v1beta1 CronJob":                                               {21, 25, "batch/v1"},
+	"policy/v1beta1 PodDisruptionBudget":                                  {21, 25, "policy/v1"},
+	"policy/v1beta1 PodSecurityPolicy":                                    {21, 25, ""},
+	"discovery.k8s.io/v1beta1 EndpointSlice":                              {21, 25, "discovery.k8s.io/v1"},
+	"events.k8s.io/v1beta1 Event":                                         {19, 25, "events.k8s.io/v1"},
+	"node.k8s.io/v1beta1 RuntimeClass":                                    {20, 25, "node.k8s.io/v1"},
+	"autoscaling/v2beta1 HorizontalPodAutoscaler":                         {22, 25, "autoscaling/v2"},
+	"autoscaling/v2beta2 HorizontalPodAutoscaler":                         {23, 26, "autoscaling/v2"},
+	"flowcontrol.apiserver.k8s.io/v1beta1 FlowSchema":                     {23, 26, "flowcontrol.apiserver.k8s.io/v1beta3"},
+	"flowcontrol.apiserver.k8s.io/v1beta1 PriorityLevelConfiguration":     {23, 26, "flowcontrol.apiserver.k8s.io/v1beta3"},
+	"flowcontrol.apiserver.k8s.io/v1beta2 FlowSchema":                     {26, 29, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta2 PriorityLevelConfiguration":     {26, 29, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta3 FlowSchema":                     {29, 32, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta3 PriorityLevelConfiguration":     {29, 32, "flowcontrol.apiserver.k8s.io/v1"},
+	"storage.k8s.io/v1beta1 CSIStorageCapacity":                           {24, 27, "storage.k8s.io/v1"},
+}
+
+// ManifestObject is an object as written in a manifest, with the API version it was written for.
+type ManifestObject struct {
+	// Origin is where the manifest comes from: the object it was last applied to, e.g. "Deployment payments/api",
+	// or a Helm release, e.g. "Helm release payments/shop".
+	Origin      string
+	LastApplied bool
+	Namespace   string
+	APIVersion  string
+	Kind        string
+	Name        string
+}
+
+// DeprecationObjects are the manifests of one cluster: the last applied configurations of its objects, by
+// "Kind namespace/name", and its Helm release secrets.
+type DeprecationObjects struct {
+	LastApplied  map[string]string
+	HelmReleases []Corev1.Secret
+}
+
+func listDeprecationObjects(cluster compare.Cluster, namespaces []string) (DeprecationObjects, error) {
+	objects := DeprecationObjects{LastApplied: map[string]string{}}
+	collect := func(kind string, list runtime.Object, err error) error {
+		if err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			object, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if lastApplied, found := object.GetAnnotations()[lastAppliedAnnotation]; found {
+				id := kind + " " + object.GetName()
+				if object.GetNamespace() != "" {
+					id = kind + " " + object.GetNamespace() + "/" + object.GetName()
+				}
+				objects.LastApplied[id] = lastApplied
+			}
+		}
+		return nil
+	}
+	clientset := cluster.Clientset
+	clusterRoles, err := query.ListClusterRoles(clientset)
+	if err := collect("ClusterRole", clusterRoles, err); err != nil {
+		return objects, err
+	}
+	clusterRoleBindings, err := query.ListClusterRoleBindings(clientset)
+	if err := collect("ClusterRoleBinding", clusterRoleBindings, err); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		listers := []struct {
+			kind string
+			list func() (runtime.Object, error)
+		}{
+			{"Deployment", func() (runtime.Object, error) { return query.ListDeployments(clientset, namespace) }},
+			{"StatefulSet", func() (runtime.Object, error) { return query.ListStatefulSets(clientset, namespace) }},
+			{"DaemonSet", func() (runtime.Object, error) { return query.ListDaemonSets(clientset, namespace) }},
+			{"CronJob", func() (runtime.Object, error) { return query.ListCronJobs(clientset, namespace) }},
+			{"HorizontalPodAutoscaler", func() (runtime.Object, error) { return query.ListHPAs(clientset, namespace) }},
+			{"Service", func() (runtime.Object, error) { return query.ListServices(clientset, namespace) }},
+			{"Ingress", func() (runtime.Object, error) { return query.ListIngresses(clientset, namespace) }},
+			{"NetworkPolicy", func() (runtime.Object, error) { return query.ListNetworkPolicies(clientset, namespace) }},
+			{"ConfigMap", func() (runtime.Object, error) { return query.ListConfigMaps(clientset, namespace) }},
+			{"ServiceAccount", func() (runtime.Object, error) { return query.ListServiceAccounts(clientset, namespace) }},
+			{"Role", func() (runtime.Object, error) { return query.ListRoles(clientset, namespace) }},
+			{"RoleBinding", func() (runtime.Object, error) { return query.ListRoleBindings(clientset, namespace) }},
+			{"PersistentVolumeClaim", func() (runtime.Object, error) { return query.ListPersistentVolumeClaims(clientset, namespace) }},
+		}
+		for _, lister := range listers {
+			list, err := lister.list()
+			if err := collect(lister.kind, list, err); err != nil {
+				return objects, err
+			}
+		}
+		secrets, err := query.ListSecrets(clientset, namespace)
+		if err := collect("Secret", secrets, err); err != nil {
+			return objects, err
+		}
+		for _, secret := range secrets.Items {
+			if secret.Type == helmReleaseType {
+				objects.HelmReleases = append(objects.HelmReleases, secret)
+			}
+		}
+	}
+	return objects, nil
+}
+
+func runDeprecations(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listDeprecationObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listDiscovery(scope.Target)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering the target cluster: %v", err)
+	}
+	return DeprecatedAPIUsage(ManifestObjects(source), target), nil
+}
+
+// ManifestObjects lists the objects of the last applied configurations and of the deployed Helm releases.
+func ManifestObjects(objects DeprecationObjects) []ManifestObject {
+	var manifests []ManifestObject
+	for _, id := range sortedStringKeys(objects.LastApplied) {
+		var applied manifestHeader
+		if err := json.Unmarshal([]byte(objects.LastApplied[id]), &applied); err != nil {
+			// A hand-edited annotation is not worth failing the analysis for.
+			continue
+		}
+		manifest := applied.object(id)
+		manifest.LastApplied = true
+		manifests = append(manifests, manifest)
+	}
+	for _, secret := range objects.HelmReleases {
+		if status, found := secret.Labels["status"]; found && status != "deployed" {
+			continue
+		}
+		release, err := decodeHelmRelease(secret.Data["release"])
+		if err != nil {
+			// Nor is a release secret written by another tool, or by another Helm version.
+			continue
+		}
+		origin := "Helm release " + secret.Namespace + "/" + release.Name
+		for _, document := range yamlDocumentSeparator.Split(release.Manifest, -1) {
+			var header manifestHeader
+			if err := yaml.Unmarshal([]byte(document), &header); err != nil || header.Kind == "" {
+				continue
+			}
+			if header.Metadata.Namespace == "" {
+				header.Metadata.Namespace = release.Namespace
+			}
+			manifests = append(manifests, header.object(origin))
+		}
+	}
+	return manifests
+}
+
+// DeprecatedAPIUsage reports the manifests using an API version the target does not serve, as errors,
+// and the ones using an API version deprecated in the target's Kubernetes version, as warnings.
+func DeprecatedAPIUsage(manifests []ManifestObject, target Discovery) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	for _, manifest := range manifests {
+		api := manifest.APIVersion + " " + manifest.Kind
+		deprecation, known := deprecatedAPIs[api]
+		advice := ""
+		if known && deprecation.Replacement != "" {
+			advice = ": use " + deprecation.Replacement
+		} else if known {
+			advice = ": the kind is removed without replacement"
+		}
+		object := manifest.Kind + " " + manifest.Name
+		if manifest.Namespace != "" {
+			object = manifest.Kind + " " + manifest.Namespace + "/" + manifest.Name
+		}
+		usage := fmt.Sprintf("%s manifest uses %s for %s", manifest.Origin, api, object)
+		if manifest.LastApplied {
+			usage = fmt.Sprintf("%s was last applied as %s", manifest.Origin, api)
+		}
+		_, undiscovered := target.Failed[manifest.APIVersion]
+		switch {
+		case undiscovered:
+			findings = append(findings, newFinding("deprecations", manifest.Namespace, manifest.Origin, object+" "+api, plugins.SeverityWarning,
+				fmt.Sprintf("%s, which target could not discover", usage)))
+		case !target.Serves(manifest.APIVersion, manifest.Kind):
+			findings = append(findings, newFinding("deprecations", manifest.Namespace, manifest.Origin, object+" "+api, plugins.SeverityError,
+				fmt.Sprintf("%s, which target does not serve%s", usage, advice)))
+		case known && target.Minor >= deprecation.Deprecated:
+			findings = append(findings, newFinding("deprecations", manifest.Namespace, manifest.Origin, object+" "+api, plugins.SeverityWarning,
+				fmt.Sprintf("%s, deprecated since 1.%d and removed in 1.%d%s", usage, deprecation.Deprecated, deprecation.Removed, advice)))
+		}
+	}
+	return findings
+}
+
+// yamlDocumentSeparator splits a multi-document YAML manifest.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---.*$`)
+
+// manifestHeader is the part of a manifest the scan needs.
+type manifestHeader struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func (h manifestHeader) object(origin string) ManifestObject {
+	return ManifestObject{Origin: origin, Namespace: h.Metadata.Namespace, APIVersion: h.APIVersion, Kind: h.Kind, Name: h.Metadata.Name}
+}
+
+// helmRelease is the part of a Helm 3 release the scan needs.
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Manifest  string `json:"manifest"`
+}
+
+// decodeHelmRelease decodes the "release" key of a Helm release secret: base64 encoded, usually gzipped, JSON.
+func decodeHelmRelease(data []byte) (helmRelease, error) {
+	var release helmRelease
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return release, err
+	}
+	if bytes.HasPrefix(decoded, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return release, err
+		}
+		defer reader.Close()
+		if decoded, err = io.ReadAll(reader); err != nil {
+			return release, err
+		}
+	}
+	err = json.Unmarshal(decoded, &release)
+	return release, err
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// helmReleaseSecret encodes a release like Helm 3 does: JSON, gzipped, then base64 encoded.
+func helmReleaseSecret(status, release string) Corev1.Secret {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write([]byte(release))
+	writer.Close()
+	return Corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.shop.v1", Namespace: "payments", Labels: map[string]string{"status": status}},
+		Type:       helmReleaseType,
+		Data:       map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(gzipped.Bytes()))},
+	}
+}
+
+func TestManifestObjects(t *testing.T) {
+	release := `{"name": "shop", "namespace": "payments", "manifest": "---\n# Source: shop/templates/cronjob.yaml\napiVersion: batch/v1beta1\nkind: CronJob\nmetadata:\n  name: report\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: shop\n  namespace: payments\n"}`
+	objects := DeprecationObjects{
+		LastApplied: map[string]string{"Deployment payments/api": `{"apiVersion": "extensions/v1beta1", "kind": "Deployment", "metadata": {"name": "api", "namespace": "payments"}}`},
+		HelmReleases: []Corev1.Secret{
+			helmReleaseSecret("deployed", release),
+			helmReleaseSecret("superseded", release),
+			{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.broken.v1", Namespace: "payments"}, Type: helmReleaseType, Data: map[string][]byte{"release": []byte("not base64")}},
+		},
+	}
+
+	manifests := ManifestObjects(objects)
+
+	expected := []ManifestObject{
+		{Origin: "Deployment payments/api", LastApplied: true, Namespace: "payments", APIVersion: "extensions/v1beta1", Kind: "Deployment", Name: "api"},
+		{Origin: "Helm release payments/shop", Namespace: "payments", APIVersion: "batch/v1beta1", Kind: "CronJob", Name: "report"},
+		{Origin: "Helm release payments/shop", Namespace: "payments", APIVersion: "v1", Kind: "Service", Name: "shop"},
+	}
+	if !reflect.DeepEqual(manifests, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, manifests)
+	}
+}
+
+func TestDeprecatedAPIUsage(t *testing.T) {
+	target := Discovery{Minor: 24, Resources: map[string]map[string]DiscoveredResource{
+		"apps/v1":       {"deployments": {Kind: "Deployment"}},
+		"batch/v1":      {"cronjobs": {Kind: "CronJob"}},
+		"batch/v1beta1": {"cronjobs": {Kind: "CronJob"}},
+		"v1":            {"services": {Kind: "Service"}},
+	}}
+	manifests := []ManifestObject{
+		{Origin: "Deployment payments/api", LastApplied: true, Namespace: "payments", APIVersion: "extensions/v1beta1", Kind: "Deployment", Name: "api"},
+		{Origin: "Helm release payments/shop", Namespace: "payments", APIVersion: "batch/v1beta1", Kind: "CronJob", Name: "report"},
+		{Origin: "Helm release payments/shop", Namespace: "payments", APIVersion: "v1", Kind: "Service", Name: "shop"},
+	}
+
+	var lines []string
+	for _, finding := range DeprecatedAPIUsage(manifests, target) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	expected := []string{
+		"error Deployment payments/api was last applied as extensions/v1beta1 Deployment, which target does not serve: use apps/v1",
+		"warning Helm release payments/shop manifest uses batch/v1beta1 CronJob for CronJob payments/report, deprecated since 1.21 and removed in 1.25: use batch/v1",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
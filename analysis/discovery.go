@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+	"kompare/tools"
+
+	clientdiscovery "k8s.io/client-go/discovery"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "discovery",
+		Description: "API discovery: server versions, served group-versions and resources, preferred versions and verbs",
+		Run:         runDiscovery,
+	})
+}
+
+// DiscoveredResource is a resource an API server serves.
+type DiscoveredResource struct {
+	Kind  string
+	Verbs []string
+}
+
+// Discovery is what an API server says it serves.
+type Discovery struct {
+	// Version is the server's git version, e.g. "v1.29.1", and Minor its minor version, e.g. 29.
+	Version string
+	Minor   int
+	// Preferred is the preferred group-version of each group, e.g. "autoscaling" -> "autoscaling/v2".
+	Preferred map[string]string
+	// Resources are the resources of each group-version, by name, e.g. "apps/v1" -> "deployments".
+	Resources map[string]map[string]DiscoveredResource
+	// Failed are the group-versions that could not be discovered, with the error, e.g. "metrics.k8s.io/v1beta1".
+	// Whether they are served is unknown.
+	Failed map[string]string
+}
+
+// Serves reports whether a group-version, like "batch/v1", serves a kind.
+func (d Discovery) Serves(groupVersion, kind string) bool {
+	for _, resource := range d.Resources[groupVersion] {
+		if resource.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func listDiscovery(cluster compare.Cluster) (Discovery, error) {
+	discovery := Discovery{Preferred: map[string]string{}, Resources: map[string]map[string]DiscoveredResource{}, Failed: map[string]string{}}
+	serverVersion, err := query.GetServerVersion(cluster.Clientset)
+	if err != nil {
+		return discovery, err
+	}
+	discovery.Version = serverVersion.GitVersion
+	discovery.Minor, _ = strconv.Atoi(strings.TrimRight(serverVersion.Minor, "+"))
+	groups, resourceLists, err := query.ListServerGroupsAndResources(cluster.Clientset)
+	var failed *clientdiscovery.ErrGroupDiscoveryFailed
+	if errors.As(err, &failed) {
+		for groupVersion, groupErr := range failed.Groups {
+			discovery.Failed[groupVersion.String()] = groupErr.Error()
+		}
+	} else if err != nil {
+		return discovery, err
+	}
+	for _, group := range groups {
+		discovery.Preferred[group.Name] = group.PreferredVersion.GroupVersion
+	}
+	for _, list := range resourceLists {
+		resources := map[string]DiscoveredResource{}
+		for _, resource := range list.APIResources {
+			resources[resource.Name] = DiscoveredResource{Kind: resource.Kind, Verbs: resource.Verbs}
+		}
+		discovery.Resources[list.GroupVersion] = resources
+	}
+	return discovery, nil
+}
+
+func runDiscovery(scope Scope) ([]DAO.DiffWithName, error) {
+	source, err := listDiscovery(scope.Source)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering the source cluster: %v", err)
+	}
+	target, err := listDiscovery(scope.Target)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering the target cluster: %v", err)
+	}
+	return DiffDiscovery(source, target), nil
+}
+
+// DiffDiscovery compares what the clusters serve. What only the source serves is an error, as objects using it
+// cannot be moved to the target; what only the target serves is info. A changed preferred version and verbs
+// the target no longer supports are warnings. A group-version that could not be discovered in a cluster is a
+// warning, and is not compared.
+func DiffDiscovery(source, target Discovery) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	add := func(name, property, severity, format string, args ...interface{}) {
+		findings = append(findings, newFinding("discovery", "", name, property, severity, fmt.Sprintf(format, args...)))
+	}
+	if source.Version != target.Version {
+		add("server", "version", plugins.SeverityInfo, "server version is %s in source, %s in target", source.Version, target.Version)
+	}
+	for _, side := range []struct {
+		name   string
+		failed map[string]string
+	}{{"source", source.Failed}, {"target", target.Failed}} {
+		for _, groupVersion := range sortedStringKeys(side.failed) {
+			add(groupVersion, "discovery in "+side.name, plugins.SeverityWarning, "group-version %s could not be discovered in %s: %s", groupVersion, side.name, side.failed[groupVersion])
+		}
+	}
+	for _, group := range sortedStringKeys(source.Preferred) {
+		if preferred, found := target.Preferred[group]; found && preferred != source.Preferred[group] {
+			add(group, "preferred version", plugins.SeverityWarning, "preferred version of %s changes from %s to %s", groupName(group), source.Preferred[group], preferred)
+		}
+	}
+	groupVersions := map[string]bool{}
+	for groupVersion := range source.Resources {
+		groupVersions[groupVersion] = true
+	}
+	for groupVersion := range target.Resources {
+		groupVersions[groupVersion] = true
+	}
+	for _, groupVersion := range sortedKeys(groupVersions) {
+		if _, failed := source.Failed[groupVersion]; failed {
+			continue
+		}
+		if _, failed := target.Failed[groupVersion]; failed {
+			continue
+		}
+		sourceResources, inSource := source.Resources[groupVersion]
+		targetResources, inTarget := target.Resources[groupVersion]
+		switch {
+		case !inTarget:
+			add(groupVersion, "served", plugins.SeverityError, "group-version %s is served only in source", groupVersion)
+			continue
+		case !inSource:
+			add(groupVersion, "served", plugins.SeverityInfo, "group-version %s is served only in target", groupVersion)
+			continue
+		}
+		names := map[string]bool{}
+		for name := range sourceResources {
+			names[name] = true
+		}
+		for name := range targetResources {
+			names[name] = true
+		}
+		for _, name := range sortedKeys(names) {
+			sourceResource, inSource := sourceResources[name]
+			targetResource, inTarget := targetResources[name]
+			switch {
+			case !inTarget:
+				add(groupVersion, name, plugins.SeverityError, "resource %s of %s is served only in source", name, groupVersion)
+			case !inSource:
+				add(groupVersion, name, plugins.SeverityInfo, "resource %s of %s is served only in target", name, groupVersion)
+			default:
+				var removed []string
+				for _, verb := range sourceResource.Verbs {
+					if !tools.IsInList(verb, targetResource.Verbs) {
+						removed = append(removed, verb)
+					}
+				}
+				if len(removed) != 0 {
+					sort.Strings(removed)
+					add(groupVersion, name+" verbs", plugins.SeverityWarning, "resource %s of %s no longer supports %s in target", name, groupVersion, strings.Join(removed, ", "))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// groupName names the core group, which is "", in findings.
+func groupName(group string) string {
+	if group == "" {
+		return "the core group"
+	}
+	return group
+}
+
+func sortedStringKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func discovery(version string, minor int, autoscaling string, cronJobVerbs ...string) Discovery {
+	return Discovery{
+		Version:   version,
+		Minor:     minor,
+		Preferred: map[string]string{"batch": "batch/v1", "autoscaling": autoscaling},
+		Resources: map[string]map[string]DiscoveredResource{
+			"batch/v1":  {"cronjobs": {Kind: "CronJob", Verbs: cronJobVerbs}},
+			autoscaling: {"horizontalpodautoscalers": {Kind: "HorizontalPodAutoscaler", Verbs: []string{"get", "list"}}},
+		},
+	}
+}
+
+func TestDiffDiscovery(t *testing.T) {
+	source := discovery("v1.25.4", 25, "autoscaling/v2beta2", "create", "delete", "list")
+	target := discovery("v1.29.1", 29, "autoscaling/v2", "list")
+
+	var lines []string
+	for _, finding := range DiffDiscovery(source, target) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	expected := []string{
+		"info server version is v1.25.4 in source, v1.29.1 in target",
+		"warning preferred version of autoscaling changes from autoscaling/v2beta2 to autoscaling/v2",
+		"info group-version autoscaling/v2 is served only in target",
+		"error group-version autoscaling/v2beta2 is served only in source",
+		"warning resource cronjobs of batch/v1 no longer supports create, delete in target",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiscoveryServes(t *testing.T) {
+	served := discovery("v1.29.1", 29, "autoscaling/v2", "list")
+	if !served.Serves("batch/v1", "CronJob") {
+		t.Errorf("Expected batch/v1 CronJob to be served")
+	}
+	if served.Serves("batch/v1beta1", "CronJob") || served.Serves("batch/v1", "Job") {
+		t.Errorf("Expected only the discovered kinds to be served")
+	}
+}
+
+func TestDiffDiscoveryFailedGroups(t *testing.T) {
+	source := discovery("v1.29.1", 29, "autoscaling/v2", "list")
+	target := discovery("v1.29.1", 29, "autoscaling/v2", "list")
+	delete(target.Resources, "autoscaling/v2")
+	target.Failed = map[string]string{"autoscaling/v2": "the server is currently unable to handle the request"}
+
+	var lines []string
+	for _, finding := range DiffDiscovery(source, target) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	expected := []string{"warning group-version autoscaling/v2 could not be discovered in target: the server is currently unable to handle the request"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
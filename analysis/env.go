@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/plugins"
+
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "env",
+		Description: "Effective container environments, resolved from ConfigMaps, Secrets and the downward API",
+		Run:         runEnv,
+	})
+}
+
+// EnvValue is the value a container sees for one variable and where it comes from.
+type EnvValue struct {
+	// Value is the resolved value. Secret values are replaced by a short keyed hash and never printed.
+	Value string
+	// Via is where the value comes from, e.g. "configmap app-config", or "literal value".
+	Via string
+}
+
+// ContainerEnv is the effective environment of one container of a workload, by variable name.
+type ContainerEnv struct {
+	// Container is "container <name>" or "init container <name>".
+	Container string
+	Env       map[string]EnvValue
+}
+
+func runEnv(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listConfigObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listConfigObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return DiffEnv(source, target), nil
+}
+
+// DiffEnv compares the effective environment of the containers of the workloads present in both clusters.
+// Workloads and containers present in a single cluster are left to the per-kind comparison.
+func DiffEnv(source, target ConfigObjects) []DAO.DiffWithName {
+	targetWorkloads := map[string]Workload{}
+	for _, workload := range target.Workloads {
+		targetWorkloads[workload.ID()] = workload
+	}
+	var findings []DAO.DiffWithName
+	for _, sourceWorkload := range source.Workloads {
+		targetWorkload, found := targetWorkloads[sourceWorkload.ID()]
+		if !found {
+			continue
+		}
+		targetEnvs := map[string]map[string]EnvValue{}
+		for _, containerEnv := range EffectiveEnv(target, targetWorkload) {
+			targetEnvs[containerEnv.Container] = containerEnv.Env
+		}
+		for _, sourceEnv := range EffectiveEnv(source, sourceWorkload) {
+			targetEnv, found := targetEnvs[sourceEnv.Container]
+			if !found {
+				continue
+			}
+			names := map[string]bool{}
+			for name := range sourceEnv.Env {
+				names[name] = true
+			}
+			for name := range targetEnv {
+				names[name] = true
+			}
+			for _, name := range sortedKeys(names) {
+				line := diffEnvValue(name, sourceEnv.Env, targetEnv)
+				if line == "" {
+					continue
+				}
+				findings = append(findings, newFinding("env", sourceWorkload.Namespace, sourceWorkload.ID(), sourceEnv.Container+" env "+name, plugins.SeverityWarning,
+					fmt.Sprintf("%s, %s: %s", sourceWorkload.ID(), sourceEnv.Container, line)))
+			}
+		}
+	}
+	return findings
+}
+
+// diffEnvValue describes how a variable differs between the clusters, or returns "" when the containers see the same value.
+func diffEnvValue(name string, source, target map[string]EnvValue) string {
+	sourceValue, inSource := source[name]
+	targetValue, inTarget := target[name]
+	switch {
+	case !inTarget:
+		return fmt.Sprintf("%s is only set in source: %s (via %s)", name, sourceValue.Value, sourceValue.Via)
+	case !inSource:
+		return fmt.Sprintf("%s is only set in target: %s (via %s)", name, targetValue.Value, targetValue.Via)
+	case sourceValue.Value == targetValue.Value:
+		return ""
+	case sourceValue.Via == targetValue.Via:
+		return fmt.Sprintf("%s differs: %s vs %s (via %s)", name, sourceValue.Value, targetValue.Value, sourceValue.Via)
+	}
+	return fmt.Sprintf("%s differs: %s vs %s (via %s in source, %s in target)", name, sourceValue.Value, targetValue.Value, sourceValue.Via, targetValue.Via)
+}
+
+// EffectiveEnv resolves the environment of every container of a workload the way the kubelet does:
+// envFrom sources in order, then env entries, later ones overriding earlier ones.
+// Variables of a missing envFrom source are left out; the references analysis reports the source itself.
+// Fields only known once the pod runs, such as status.podIP, resolve to a placeholder that is the same in both clusters.
+func EffectiveEnv(objects ConfigObjects, workload Workload) []ContainerEnv {
+	var envs []ContainerEnv
+	for _, container := range workload.Template.Spec.InitContainers {
+		envs = append(envs, ContainerEnv{Container: "init container " + container.Name, Env: containerEnv(objects, workload, container)})
+	}
+	for _, container := range workload.Template.Spec.Containers {
+		envs = append(envs, ContainerEnv{Container: "container " + container.Name, Env: containerEnv(objects, workload, container)})
+	}
+	return envs
+}
+
+func containerEnv(objects ConfigObjects, workload Workload, container Corev1.Container) map[string]EnvValue {
+	env := map[string]EnvValue{}
+	for _, from := range container.EnvFrom {
+		if from.ConfigMapRef != nil {
+			if configMap := findConfigMap(objects, workload.Namespace, from.ConfigMapRef.Name); configMap != nil {
+				for key, value := range configMap.Data {
+					env[from.Prefix+key] = EnvValue{Value: value, Via: "configmap " + configMap.Name}
+				}
+			}
+		}
+		if from.SecretRef != nil {
+			if secret := findSecret(objects, workload.Namespace, from.SecretRef.Name); secret != nil {
+				for key, value := range secret.Data {
+					env[from.Prefix+key] = EnvValue{Value: hashSecretValue(value), Via: "secret " + secret.Name}
+				}
+			}
+		}
+	}
+	for _, variable := range container.Env {
+		if variable.ValueFrom == nil {
+			env[variable.Name] = EnvValue{Value: variable.Value, Via: "literal value"}
+			continue
+		}
+		if value, found := resolveEnvSource(objects, workload, container, variable.ValueFrom); found {
+			env[variable.Name] = value
+		}
+	}
+	return env
+}
+
+// resolveEnvSource resolves a valueFrom. It reports false for a missing optional key, which leaves the variable unset.
+func resolveEnvSource(objects ConfigObjects, workload Workload, container Corev1.Container, source *Corev1.EnvVarSource) (EnvValue, bool) {
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		ref := source.ConfigMapKeyRef
+		via := "configmap " + ref.Name
+		if configMap := findConfigMap(objects, workload.Namespace, ref.Name); configMap != nil {
+			if value, found := configMap.Data[ref.Key]; found {
+				return EnvValue{Value: value, Via: via}, true
+			}
+		}
+		return EnvValue{Value: "<missing>", Via: via}, ref.Optional == nil || !*ref.Optional
+	case source.SecretKeyRef != nil:
+		ref := source.SecretKeyRef
+		via := "secret " + ref.Name
+		if secret := findSecret(objects, workload.Namespace, ref.Name); secret != nil {
+			if value, found := secret.Data[ref.Key]; found {
+				return EnvValue{Value: hashSecretValue(value), Via: via}, true
+			}
+		}
+		return EnvValue{Value: "<missing>", Via: via}, ref.Optional == nil || !*ref.Optional
+	case source.FieldRef != nil:
+		return EnvValue{Value: resolveFieldRef(workload, source.FieldRef.FieldPath), Via: "field " + source.FieldRef.FieldPath}, true
+	case source.ResourceFieldRef != nil:
+		return EnvValue{Value: resolveResourceFieldRef(workload, container, source.ResourceFieldRef), Via: "resource " + source.ResourceFieldRef.Resource}, true
+	}
+	return EnvValue{}, false
+}
+
+// resolveFieldRef resolves the downward API fields known from the pod template.
+func resolveFieldRef(workload Workload, fieldPath string) string {
+	template := workload.Template
+	switch {
+	case fieldPath == "metadata.namespace":
+		return workload.Namespace
+	case fieldPath == "spec.serviceAccountName":
+		if template.Spec.ServiceAccountName == "" {
+			return "default"
+		}
+		return template.Spec.ServiceAccountName
+	case strings.HasPrefix(fieldPath, "metadata.labels['") && strings.HasSuffix(fieldPath, "']"):
+		return template.Labels[strings.TrimSuffix(strings.TrimPrefix(fieldPath, "metadata.labels['"), "']")]
+	case strings.HasPrefix(fieldPath, "metadata.annotations['") && strings.HasSuffix(fieldPath, "']"):
+		return template.Annotations[strings.TrimSuffix(strings.TrimPrefix(fieldPath, "metadata.annotations['"), "']")]
+	}
+	return "<" + fieldPath + " at runtime>"
+}
+
+// resolveResourceFieldRef resolves requests and limits like the kubelet: divided by the divisor and rounded up.
+// A limit that is not set defaults to the node's allocatable, which is only known at runtime.
+func resolveResourceFieldRef(workload Workload, container Corev1.Container, ref *Corev1.ResourceFieldSelector) string {
+	if ref.ContainerName != "" {
+		for _, candidate := range append(workload.Template.Spec.InitContainers, workload.Template.Spec.Containers...) {
+			if candidate.Name == ref.ContainerName {
+				container = candidate
+			}
+		}
+	}
+	resources := container.Resources.Limits
+	name := strings.TrimPrefix(ref.Resource, "limits.")
+	if strings.HasPrefix(ref.Resource, "requests.") {
+		resources, name = container.Resources.Requests, strings.TrimPrefix(ref.Resource, "requests.")
+	}
+	quantity, found := resources[Corev1.ResourceName(name)]
+	if !found {
+		return "<node allocatable " + name + " at runtime>"
+	}
+	divisor := resource.MustParse("1")
+	if !ref.Divisor.IsZero() {
+		divisor = ref.Divisor
+	}
+	return fmt.Sprintf("%d", int64(math.Ceil(quantity.AsApproximateFloat64()/divisor.AsApproximateFloat64())))
+}
+
+func findConfigMap(objects ConfigObjects, namespace, name string) *Corev1.ConfigMap {
+	if objects.ConfigMaps == nil {
+		return nil
+	}
+	for i, configMap := range objects.ConfigMaps.Items {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return &objects.ConfigMaps.Items[i]
+		}
+	}
+	return nil
+}
+
+func findSecret(objects ConfigObjects, namespace, name string) *Corev1.Secret {
+	if objects.Secrets == nil {
+		return nil
+	}
+	for i, secret := range objects.Secrets.Items {
+		if secret.Namespace == namespace && secret.Name == name {
+			return &objects.Secrets.Items[i]
+		}
+	}
+	return nil
+}
+
+// secretHashKey keys the hashes of secret values. It is random for every run, so that a hash
+// printed in a report cannot be checked against guessed values, and only compares within the run.
+var secretHashKey = newSecretHashKey()
+
+func newSecretHashKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Errorf("error generating the key of the secret hashes: %v", err))
+	}
+	return key
+}
+
+// hashSecretValue replaces a secret value with a short keyed hash, enough to tell two values apart.
+func hashSecretValue(value []byte) string {
+	mac := hmac.New(sha256.New, secretHashKey)
+	mac.Write(value)
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func envObjects(url, password string) ConfigObjects {
+	api := workload("payments", "api", map[string]string{"app": "api"})
+	api.Template.Spec.Containers[0].EnvFrom = []Corev1.EnvFromSource{{Prefix: "DB_", SecretRef: &Corev1.SecretEnvSource{LocalObjectReference: Corev1.LocalObjectReference{Name: "db"}}}}
+	api.Template.Spec.Containers[0].Env = []Corev1.EnvVar{
+		{Name: "API_URL", ValueFrom: &Corev1.EnvVarSource{ConfigMapKeyRef: &Corev1.ConfigMapKeySelector{LocalObjectReference: Corev1.LocalObjectReference{Name: "app-config"}, Key: "url"}}},
+		{Name: "APP", ValueFrom: &Corev1.EnvVarSource{FieldRef: &Corev1.ObjectFieldSelector{FieldPath: "metadata.labels['app']"}}},
+		{Name: "POD_IP", ValueFrom: &Corev1.EnvVarSource{FieldRef: &Corev1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
+	}
+	return ConfigObjects{
+		Workloads:  []Workload{api},
+		ConfigMaps: &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "payments"}, Data: map[string]string{"url": url}}}},
+		Secrets:    &Corev1.SecretList{Items: []Corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "payments"}, Data: map[string][]byte{"PASSWORD": []byte(password)}}}},
+	}
+}
+
+func TestEffectiveEnv(t *testing.T) {
+	objects := envObjects("https://a", "hunter2")
+
+	env := EffectiveEnv(objects, objects.Workloads[0])[0].Env
+
+	if env["API_URL"] != (EnvValue{Value: "https://a", Via: "configmap app-config"}) {
+		t.Errorf("Expected API_URL from the configmap, got %+v", env["API_URL"])
+	}
+	if env["APP"].Value != "api" || !strings.Contains(env["POD_IP"].Value, "runtime") {
+		t.Errorf("Expected the downward API to be resolved from the template, got %+v", env)
+	}
+	if password := env["DB_PASSWORD"]; password.Via != "secret db" || strings.Contains(password.Value, "hunter2") || !strings.HasPrefix(password.Value, "hmac:") {
+		t.Errorf("Expected the secret value to be hashed, got %+v", password)
+	}
+}
+
+func TestDiffEnv(t *testing.T) {
+	findings := DiffEnv(envObjects("https://a", "hunter2"), envObjects("https://b", "hunter2"))
+
+	if len(findings) != 1 {
+		t.Fatalf("Expected only API_URL to differ, got %+v", findings)
+	}
+	expected := "Deployment payments/api, container api: API_URL differs: https://a vs https://b (via configmap app-config)"
+	if findings[0].Diff[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, findings[0].Diff[0])
+	}
+
+	findings = DiffEnv(envObjects("https://a", "hunter2"), envObjects("https://a", "hunter3"))
+	if len(findings) != 1 || !strings.Contains(findings[0].Diff[0], "DB_PASSWORD differs: hmac:") || strings.Contains(findings[0].Diff[0], "hunter") {
+		t.Errorf("Expected the secret values to differ by hash only, got %+v", findings)
+	}
+}
+
+func TestResolveResourceFieldRef(t *testing.T) {
+	api := workload("payments", "api", nil)
+	api.Template.Spec.Containers[0].Resources.Limits = Corev1.ResourceList{Corev1.ResourceMemory: resource.MustParse("256Mi")}
+	ref := &Corev1.ResourceFieldSelector{Resource: "limits.memory", Divisor: resource.MustParse("1Mi")}
+
+	if value := resolveResourceFieldRef(api, api.Template.Spec.Containers[0], ref); value != "256" {
+		t.Errorf("Expected 256, got %s", value)
+	}
+	ref = &Corev1.ResourceFieldSelector{Resource: "limits.cpu"}
+	if value := resolveResourceFieldRef(api, api.Template.Spec.Containers[0], ref); !strings.Contains(value, "node allocatable") {
+		t.Errorf("Expected an unset limit to default to the node allocatable, got %s", value)
+	}
+}
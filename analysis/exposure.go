@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+	"kompare/tools"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "exposure",
+		Description: "Endpoints reachable from outside the clusters: LoadBalancer and NodePort Services, Ingresses and external-dns names",
+		Run:         runExposure,
+	})
+}
+
+const (
+	// externalDNSAnnotation lists the DNS names external-dns publishes for a Service or Ingress.
+	externalDNSAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	// sourceRangesAnnotation is the older way to restrict the clients of a LoadBalancer.
+	sourceRangesAnnotation = "service.beta.kubernetes.io/load-balancer-source-ranges"
+	// ingressClassAnnotation is the older way to pick the IngressClass of an Ingress.
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+	// defaultIngressClassAnnotation marks the IngressClass of the Ingresses that do not name one.
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// ExposureObjects are the Services, Ingresses and IngressClasses of one cluster.
+type ExposureObjects struct {
+	Services       *Corev1.ServiceList
+	Ingresses      *networkingv1.IngressList
+	IngressClasses *networkingv1.IngressClassList
+}
+
+// Endpoint is one way an object can be reached from outside the cluster.
+type Endpoint struct {
+	Namespace string
+	// Object is like "Service payments/api".
+	Object string
+	// Description is like "LoadBalancer TCP/443 from 0.0.0.0/0".
+	Description string
+	// NodePort is the node port of a NodePort Service endpoint. It is not part of what the endpoint is: the cluster
+	// usually allocates it, so a different node port is a warning on an endpoint exposed in both clusters.
+	NodePort int32
+}
+
+func (e Endpoint) String() string {
+	return e.Object + ": " + e.Description
+}
+
+func listExposureObjects(cluster compare.Cluster, namespaces []string) (ExposureObjects, error) {
+	objects := ExposureObjects{Services: &Corev1.ServiceList{}, Ingresses: &networkingv1.IngressList{}}
+	var err error
+	if objects.IngressClasses, err = query.ListIngressClasses(cluster.Clientset); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		services, err := query.ListServices(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Services.Items = append(objects.Services.Items, services.Items...)
+		ingresses, err := query.ListIngresses(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Ingresses.Items = append(objects.Ingresses.Items, ingresses.Items...)
+	}
+	return objects, nil
+}
+
+func runExposure(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listExposureObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listExposureObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return DiffExposure(source, target), nil
+}
+
+// DiffExposure reports the endpoints exposed by a single cluster. An endpoint exposed only by the target
+// is an error, as the target must expose nothing more than the source; one exposed only by the source is a warning.
+// A NodePort endpoint exposed in both clusters on different node ports is a warning.
+func DiffExposure(source, target ExposureObjects) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	sourceEndpoints, targetEndpoints := Exposure(source), Exposure(target)
+	report := func(endpoints []Endpoint, other []Endpoint, targetOnly bool, severity string) {
+		known := map[string]Endpoint{}
+		for _, endpoint := range other {
+			known[endpoint.String()] = endpoint
+		}
+		for _, endpoint := range endpoints {
+			otherEndpoint, found := known[endpoint.String()]
+			if !found {
+				findings = append(findings, newFinding("exposure", endpoint.Namespace, endpoint.Object, endpoint.Description, severity,
+					fmt.Sprintf("%s is exposed only in %s", endpoint, inCluster(targetOnly))))
+			} else if targetOnly && endpoint.NodePort != otherEndpoint.NodePort {
+				findings = append(findings, newFinding("exposure", endpoint.Namespace, endpoint.Object, endpoint.Description+" node port", plugins.SeverityWarning,
+					fmt.Sprintf("%s uses node port %d in source, %d in target", endpoint, otherEndpoint.NodePort, endpoint.NodePort)))
+			}
+		}
+	}
+	report(sourceEndpoints, targetEndpoints, false, plugins.SeverityWarning)
+	report(targetEndpoints, sourceEndpoints, true, plugins.SeverityError)
+	return findings
+}
+
+// Exposure lists the endpoints of a cluster, sorted: the ports of LoadBalancer and NodePort Services and of
+// external IPs with their source ranges, the hosts and paths of Ingresses with their IngressClass and TLS secrets,
+// and the external-dns names of both.
+func Exposure(objects ExposureObjects) []Endpoint {
+	var endpoints []Endpoint
+	if objects.Services != nil {
+		for _, service := range objects.Services.Items {
+			endpoints = append(endpoints, serviceEndpoints(service)...)
+		}
+	}
+	if objects.Ingresses != nil {
+		for _, ingress := range objects.Ingresses.Items {
+			endpoints = append(endpoints, ingressEndpoints(ingress, ingressClass(ingress, objects.IngressClasses))...)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].String() < endpoints[j].String()
+	})
+	return endpoints
+}
+
+func serviceEndpoints(service Corev1.Service) []Endpoint {
+	var endpoints []Endpoint
+	object := "Service " + service.Namespace + "/" + service.Name
+	add := func(description string) {
+		endpoints = append(endpoints, Endpoint{Namespace: service.Namespace, Object: object, Description: description})
+	}
+	sourceRanges := service.Spec.LoadBalancerSourceRanges
+	if len(sourceRanges) == 0 {
+		sourceRanges = annotationList(service.Annotations[sourceRangesAnnotation])
+	}
+	from := "0.0.0.0/0"
+	if len(sourceRanges) != 0 {
+		from = strings.Join(sourceRanges, ", ")
+	}
+	for _, port := range service.Spec.Ports {
+		portName := fmt.Sprintf("%s/%d", port.Protocol, port.Port)
+		switch service.Spec.Type {
+		case Corev1.ServiceTypeLoadBalancer:
+			// Node ports of a LoadBalancer are usually allocated by the cluster and are not compared.
+			add(fmt.Sprintf("LoadBalancer %s from %s", portName, from))
+		case Corev1.ServiceTypeNodePort:
+			endpoints = append(endpoints, Endpoint{Namespace: service.Namespace, Object: object, Description: fmt.Sprintf("NodePort %s on every node", portName), NodePort: port.NodePort})
+		}
+		for _, ip := range service.Spec.ExternalIPs {
+			add(fmt.Sprintf("external IP %s %s", ip, portName))
+		}
+	}
+	for _, hostname := range annotationList(service.Annotations[externalDNSAnnotation]) {
+		add("DNS name " + hostname + " (external-dns)")
+	}
+	return endpoints
+}
+
+func ingressEndpoints(ingress networkingv1.Ingress, class string) []Endpoint {
+	var endpoints []Endpoint
+	object := "Ingress " + ingress.Namespace + "/" + ingress.Name
+	add := func(description string) {
+		endpoints = append(endpoints, Endpoint{Namespace: ingress.Namespace, Object: object, Description: description})
+	}
+	if ingress.Spec.DefaultBackend != nil {
+		add("any host, any path, via " + class)
+	}
+	for _, rule := range ingress.Spec.Rules {
+		host := rule.Host
+		if host == "" {
+			host = "any host, path "
+		}
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			pathType := "ImplementationSpecific"
+			if path.PathType != nil {
+				pathType = string(*path.PathType)
+			}
+			add(fmt.Sprintf("%s%s (%s) via %s", host, path.Path, pathType, class))
+		}
+	}
+	for _, tls := range ingress.Spec.TLS {
+		hosts := strings.Join(tls.Hosts, ", ")
+		if hosts == "" {
+			hosts = "any host"
+		}
+		add(fmt.Sprintf("TLS for %s with secret %s", hosts, tls.SecretName))
+	}
+	for _, hostname := range annotationList(ingress.Annotations[externalDNSAnnotation]) {
+		add("DNS name " + hostname + " (external-dns)")
+	}
+	return endpoints
+}
+
+// ingressClass names the IngressClass serving an Ingress and its controller: the one it names, or else the default one.
+func ingressClass(ingress networkingv1.Ingress, classes *networkingv1.IngressClassList) string {
+	name := ingress.Annotations[ingressClassAnnotation]
+	if ingress.Spec.IngressClassName != nil {
+		name = *ingress.Spec.IngressClassName
+	}
+	if classes != nil {
+		for _, class := range classes.Items {
+			if class.Name == name || (name == "" && class.Annotations[defaultIngressClassAnnotation] == "true") {
+				return fmt.Sprintf("IngressClass %s (%s)", class.Name, class.Spec.Controller)
+			}
+		}
+	}
+	if name == "" {
+		return "no IngressClass"
+	}
+	return "IngressClass " + name + " (missing)"
+}
+
+// annotationList splits a comma separated annotation value, allowing spaces after the commas.
+func annotationList(value string) []string {
+	values := tools.ParseCommaSeparateList(value)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
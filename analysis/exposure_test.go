@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func exposureObjects(sourceRanges []string, hosts ...string) ExposureObjects {
+	prefix := networkingv1.PathTypePrefix
+	objects := ExposureObjects{
+		Services: &Corev1.ServiceList{Items: []Corev1.Service{{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments", Annotations: map[string]string{externalDNSAnnotation: "api.example.com"}},
+			Spec: Corev1.ServiceSpec{Type: Corev1.ServiceTypeLoadBalancer, LoadBalancerSourceRanges: sourceRanges,
+				Ports: []Corev1.ServicePort{{Protocol: Corev1.ProtocolTCP, Port: 443, NodePort: 31443}}},
+		}}},
+		Ingresses: &networkingv1.IngressList{},
+		IngressClasses: &networkingv1.IngressClassList{Items: []networkingv1.IngressClass{{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Annotations: map[string]string{defaultIngressClassAnnotation: "true"}},
+			Spec:       networkingv1.IngressClassSpec{Controller: "k8s.io/ingress-nginx"},
+		}}},
+	}
+	for _, host := range hosts {
+		objects.Ingresses.Items = append(objects.Ingresses.Items, networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "payments"},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: host, IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: "/", PathType: &prefix}},
+				}}}},
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: "web-tls"}},
+			},
+		})
+	}
+	return objects
+}
+
+func TestExposure(t *testing.T) {
+	var lines []string
+	for _, endpoint := range Exposure(exposureObjects(nil, "www.example.com")) {
+		lines = append(lines, endpoint.String())
+	}
+
+	expected := []string{
+		"Ingress payments/web: TLS for www.example.com with secret web-tls",
+		"Ingress payments/web: www.example.com/ (Prefix) via IngressClass nginx (k8s.io/ingress-nginx)",
+		"Service payments/api: DNS name api.example.com (external-dns)",
+		"Service payments/api: LoadBalancer TCP/443 from 0.0.0.0/0",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffExposure(t *testing.T) {
+	source := exposureObjects([]string{"10.0.0.0/8"}, "www.example.com")
+	target := exposureObjects(nil, "www.example.com", "admin.example.com")
+
+	var lines []string
+	for _, finding := range DiffExposure(source, target) {
+		lines = append(lines, finding.Severity+" "+finding.Diff[0])
+	}
+
+	expected := []string{
+		"warning Service payments/api: LoadBalancer TCP/443 from 10.0.0.0/8 is exposed only in source",
+		"error Ingress payments/web: TLS for admin.example.com with secret web-tls is exposed only in target",
+		"error Ingress payments/web: admin.example.com/ (Prefix) via IngressClass nginx (k8s.io/ingress-nginx) is exposed only in target",
+		"error Service payments/api: LoadBalancer TCP/443 from 0.0.0.0/0 is exposed only in target",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffExposureNodePorts(t *testing.T) {
+	nodePort := func(port int32) ExposureObjects {
+		return ExposureObjects{Services: &Corev1.ServiceList{Items: []Corev1.Service{{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments"},
+			Spec: Corev1.ServiceSpec{Type: Corev1.ServiceTypeNodePort,
+				Ports: []Corev1.ServicePort{{Protocol: Corev1.ProtocolTCP, Port: 443, NodePort: port}}},
+		}}}}
+	}
+
+	if findings := DiffExposure(nodePort(31443), nodePort(31443)); len(findings) != 0 {
+		t.Errorf("Expected no findings for the same node port, got %+v", findings)
+	}
+	findings := DiffExposure(nodePort(31443), nodePort(30443))
+	expected := "Service payments/api: NodePort TCP/443 on every node uses node port 31443 in source, 30443 in target"
+	if len(findings) != 1 || findings[0].Severity != "warning" || findings[0].Diff[0] != expected {
+		t.Errorf("Expected a node port warning on the same endpoint, got %+v", findings)
+	}
+}
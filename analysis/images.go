@@ -0,0 +1,311 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	Corev1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "images",
+		Description: "Container images of every workload: differing versions, images used on one side only, and tags resolved to different digests",
+		Run:         runImages,
+	})
+}
+
+// dockerHub is the registry of image references without one, like "nginx:1.25".
+const dockerHub = "docker.io"
+
+// Image is a parsed image reference.
+type Image struct {
+	// Repository includes the registry, e.g. "docker.io/library/nginx".
+	Repository string
+	Tag        string
+	// Digest is the "sha256:..." digest the reference pins, or the digests running pods resolved it to.
+	Digest string
+}
+
+func (i Image) String() string {
+	s := i.Repository
+	if i.Tag != "" {
+		s += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		s += "@" + i.Digest
+	}
+	return s
+}
+
+// ImageUse is the image of one container of a workload.
+type ImageUse struct {
+	Namespace string
+	Workload  string
+	// Container is "container <name>" or "init container <name>".
+	Container string
+	Image     Image
+}
+
+// ImageObjects are the workloads of one cluster and, to read the digests they run, their pods.
+type ImageObjects struct {
+	Workloads []Workload
+	Pods      *Corev1.PodList
+}
+
+func listImageObjects(cluster compare.Cluster, namespaces []string, digests bool) (ImageObjects, error) {
+	objects := ImageObjects{Pods: &Corev1.PodList{}}
+	var err error
+	if objects.Workloads, err = listWorkloads(cluster, namespaces); err != nil {
+		return objects, err
+	}
+	if !digests {
+		return objects, nil
+	}
+	for _, namespace := range namespaces {
+		pods, err := query.ListPods(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Pods.Items = append(objects.Pods.Items, pods.Items...)
+	}
+	return objects, nil
+}
+
+func runImages(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listImageObjects(scope.Source, namespaces, scope.Args.ImageDigests)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listImageObjects(scope.Target, namespaces, scope.Args.ImageDigests)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	mirrors := RegistryMirrors(scope.Args.RegistryMirrors)
+	sourceUses, targetUses := ImageInventory(source, mirrors), ImageInventory(target, mirrors)
+	if scope.Args.ImageInventory {
+		fmt.Printf("Image inventory:\n%s", compare.FormatMatrix(InventoryRows(sourceUses, targetUses)))
+	}
+	return DiffImages(sourceUses, targetUses), nil
+}
+
+// RegistryMirrors parses "mirror=registry" entries, e.g. "mirror.example.com/dockerhub=docker.io".
+// Entries without "=" are ignored.
+func RegistryMirrors(entries []string) map[string]string {
+	mirrors := map[string]string{}
+	for _, entry := range entries {
+		mirror, registry, found := strings.Cut(entry, "=")
+		if found {
+			mirrors[strings.TrimSuffix(strings.TrimSpace(mirror), "/")] = strings.TrimSuffix(strings.TrimSpace(registry), "/")
+		}
+	}
+	return mirrors
+}
+
+// ParseImage parses an image reference into its repository, tag and digest. References without a registry
+// are on Docker Hub, and the mirror prefixes are replaced by the registry they mirror, the longest prefix first,
+// so that "nginx", "docker.io/library/nginx:latest" and "mirror.example.com/dockerhub/library/nginx" are the same image.
+func ParseImage(reference string, mirrors map[string]string) Image {
+	var image Image
+	reference, image.Digest, _ = strings.Cut(reference, "@")
+	if slash, colon := strings.LastIndex(reference, "/"), strings.LastIndex(reference, ":"); colon > slash {
+		reference, image.Tag = reference[:colon], reference[colon+1:]
+	}
+	if image.Tag == "" && image.Digest == "" {
+		image.Tag = "latest"
+	}
+	prefixes := make([]string, 0, len(mirrors))
+	for prefix := range mirrors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(reference, prefix+"/") {
+			reference = mirrors[prefix] + strings.TrimPrefix(reference, prefix)
+			break
+		}
+	}
+	registry, path, found := strings.Cut(reference, "/")
+	switch {
+	case !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost"):
+		reference = dockerHub + "/" + reference
+		registry, path, _ = strings.Cut(reference, "/")
+	case registry == "index.docker.io":
+		registry = dockerHub
+	}
+	if registry == dockerHub && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	image.Repository = registry + "/" + path
+	return image
+}
+
+// ImageInventory lists the image of every container of every workload. When the objects have pods,
+// an image reference with no digest gets the digests the pods of the namespace running it resolved it to.
+func ImageInventory(objects ImageObjects, mirrors map[string]string) []ImageUse {
+	running := runningDigests(objects.Pods, mirrors)
+	var uses []ImageUse
+	for _, workload := range objects.Workloads {
+		add := func(container Corev1.Container, name string) {
+			image := ParseImage(container.Image, mirrors)
+			if image.Digest == "" {
+				image.Digest = strings.Join(sortedKeys(running[workload.Namespace+" "+image.String()]), ", ")
+			}
+			uses = append(uses, ImageUse{Namespace: workload.Namespace, Workload: workload.ID(), Container: name, Image: image})
+		}
+		for _, container := range workload.Template.Spec.InitContainers {
+			add(container, "init container "+container.Name)
+		}
+		for _, container := range workload.Template.Spec.Containers {
+			add(container, "container "+container.Name)
+		}
+	}
+	return uses
+}
+
+// notUsed is the image of a container a cluster does not have in the inventory.
+const notUsed = "not used"
+
+// InventoryRows lays out the image of every container of every workload, in the source and target
+// clusters, as rows of a matrix: one row per container, with one value when both clusters run the same image.
+func InventoryRows(source, target []ImageUse) []compare.MatrixRow {
+	var rows []compare.MatrixRow
+	index := map[string]int{}
+	for _, side := range []struct {
+		uses   []ImageUse
+		target bool
+	}{{source, false}, {target, true}} {
+		for _, use := range side.uses {
+			id := use.Workload + ", " + use.Container
+			i, found := index[id]
+			if !found {
+				kind, namespacedName, _ := strings.Cut(use.Workload, " ")
+				_, name, _ := strings.Cut(namespacedName, "/")
+				i = len(rows)
+				index[id] = i
+				rows = append(rows, compare.MatrixRow{Kind: kind, Namespace: use.Namespace, Name: name, Field: use.Container,
+					Groups: []compare.ValueGroup{{Value: notUsed, Clusters: []string{inCluster(false)}}}})
+			}
+			if !side.target {
+				rows[i].Groups[0].Value = use.Image.String()
+				continue
+			}
+			if rows[i].Groups[0].Value == use.Image.String() {
+				rows[i].Groups[0].Clusters = append(rows[i].Groups[0].Clusters, inCluster(true))
+			} else {
+				rows[i].Groups = append(rows[i].Groups, compare.ValueGroup{Value: use.Image.String(), Clusters: []string{inCluster(true)}})
+			}
+		}
+	}
+	for i := range rows {
+		if len(rows[i].Groups) == 1 && len(rows[i].Groups[0].Clusters) == 1 {
+			rows[i].Groups = append(rows[i].Groups, compare.ValueGroup{Value: notUsed, Clusters: []string{inCluster(true)}})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		return rows[i].Kind+" "+rows[i].Name < rows[j].Kind+" "+rows[j].Name
+	})
+	return rows
+}
+
+// runningDigests maps "namespace image" to the digests of the imageID of the pods running that image.
+func runningDigests(pods *Corev1.PodList, mirrors map[string]string) map[string]map[string]bool {
+	digests := map[string]map[string]bool{}
+	if pods == nil {
+		return digests
+	}
+	for _, pod := range pods.Items {
+		statuses := append(append([]Corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			at := strings.Index(status.ImageID, "sha256:")
+			if at < 0 {
+				continue
+			}
+			image := ParseImage(status.Image, mirrors)
+			image.Digest = ""
+			key := pod.Namespace + " " + image.String()
+			if digests[key] == nil {
+				digests[key] = map[string]bool{}
+			}
+			digests[key][status.ImageID[at:]] = true
+		}
+	}
+	return digests
+}
+
+// DiffImages compares the images of the containers present in both clusters: a different repository, tag, or digest
+// of references pinned by digest only is a warning, and the same tag resolved to different digests is an error. Images used in a single cluster are warnings too.
+func DiffImages(source, target []ImageUse) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	targetUses := map[string]ImageUse{}
+	for _, use := range target {
+		targetUses[use.Workload+", "+use.Container] = use
+	}
+	for _, sourceUse := range source {
+		id := sourceUse.Workload + ", " + sourceUse.Container
+		targetUse, found := targetUses[id]
+		if !found {
+			continue
+		}
+		sourceImage, targetImage := sourceUse.Image, targetUse.Image
+		switch {
+		case sourceImage.Repository != targetImage.Repository || sourceImage.Tag != targetImage.Tag,
+			// Without a tag, the digest the reference pins is its version.
+			sourceImage.Tag == "" && sourceImage.Digest != targetImage.Digest:
+			findings = append(findings, newFinding("images", sourceUse.Namespace, sourceUse.Workload, sourceUse.Container+" image", plugins.SeverityWarning,
+				fmt.Sprintf("%s: image differs: %s in source, %s in target", id, sourceImage, targetImage)))
+		case sourceImage.Digest != "" && targetImage.Digest != "" && sourceImage.Digest != targetImage.Digest:
+			findings = append(findings, newFinding("images", sourceUse.Namespace, sourceUse.Workload, sourceUse.Container+" digest", plugins.SeverityError,
+				fmt.Sprintf("%s: the same tag %s:%s resolves to different digests: %s in source, %s in target", id, sourceImage.Repository, sourceImage.Tag, sourceImage.Digest, targetImage.Digest)))
+		}
+	}
+	sourceRepositories, targetRepositories := repositoryUsers(source), repositoryUsers(target)
+	for _, side := range []struct {
+		repositories, other map[string][]ImageUse
+		target              bool
+	}{{sourceRepositories, targetRepositories, false}, {targetRepositories, sourceRepositories, true}} {
+		for _, repository := range sortedRepositories(side.repositories) {
+			if _, found := side.other[repository]; found {
+				continue
+			}
+			users := map[string]bool{}
+			for _, use := range side.repositories[repository] {
+				users[use.Workload] = true
+			}
+			first := side.repositories[repository][0]
+			findings = append(findings, newFinding("images", first.Namespace, repository, "only in "+inCluster(side.target), plugins.SeverityWarning,
+				fmt.Sprintf("image %s is only used in %s, by %s", repository, inCluster(side.target), strings.Join(sortedKeys(users), ", "))))
+		}
+	}
+	return findings
+}
+
+func repositoryUsers(uses []ImageUse) map[string][]ImageUse {
+	users := map[string][]ImageUse{}
+	for _, use := range uses {
+		users[use.Image.Repository] = append(users[use.Image.Repository], use)
+	}
+	return users
+}
+
+func sortedRepositories(repositories map[string][]ImageUse) []string {
+	names := make([]string, 0, len(repositories))
+	for name := range repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
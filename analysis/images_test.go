@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"kompare/compare"
+
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseImage(t *testing.T) {
+	mirrors := RegistryMirrors([]string{"mirror.example.com/dockerhub=docker.io", "invalid"})
+	tests := map[string]string{
+		"nginx":             "docker.io/library/nginx:latest",
+		"bitnami/redis:7.2": "docker.io/bitnami/redis:7.2",
+		"mirror.example.com/dockerhub/library/nginx":        "docker.io/library/nginx:latest",
+		"localhost:5000/api:1.2":                            "localhost:5000/api:1.2",
+		"ghcr.io/acme/api@sha256:abc":                       "ghcr.io/acme/api@sha256:abc",
+		"registry.example.com:5000/acme/api:1.2@sha256:abc": "registry.example.com:5000/acme/api:1.2@sha256:abc",
+	}
+	for reference, expected := range tests {
+		if image := ParseImage(reference, mirrors); image.String() != expected {
+			t.Errorf("Expected %s to parse as %s, got %s", reference, expected, image)
+		}
+	}
+}
+
+func imageObjects(image, imageID string, names ...string) ImageObjects {
+	objects := ImageObjects{Pods: &Corev1.PodList{}}
+	for _, name := range names {
+		w := workload("payments", name, nil)
+		w.Template.Spec.Containers[0].Image = image
+		objects.Workloads = append(objects.Workloads, w)
+	}
+	if imageID != "" {
+		objects.Pods.Items = append(objects.Pods.Items, Corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "payments"},
+			Status:     Corev1.PodStatus{ContainerStatuses: []Corev1.ContainerStatus{{Image: image, ImageID: "docker-pullable://acme/api@" + imageID}}},
+		})
+	}
+	return objects
+}
+
+func TestDiffImages(t *testing.T) {
+	source := ImageInventory(imageObjects("acme/api:1.2", "sha256:aaa", "api"), nil)
+	target := ImageInventory(imageObjects("acme/api:1.2", "sha256:bbb", "api"), nil)
+
+	findings := DiffImages(source, target)
+
+	if len(findings) != 1 || findings[0].Severity != "error" {
+		t.Fatalf("Expected the same tag resolved to different digests to be an error, got %+v", findings)
+	}
+	expected := "Deployment payments/api, container api: the same tag docker.io/acme/api:1.2 resolves to different digests: sha256:aaa in source, sha256:bbb in target"
+	if findings[0].Diff[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, findings[0].Diff[0])
+	}
+}
+
+func TestDiffImagesVersionsAndOneSide(t *testing.T) {
+	source := ImageInventory(imageObjects("acme/api:1.2", "", "api"), nil)
+	target := ImageInventory(imageObjects("acme/web:1.3", "", "api"), nil)
+
+	var lines []string
+	for _, finding := range DiffImages(source, target) {
+		lines = append(lines, finding.Diff[0])
+	}
+
+	expected := []string{
+		"Deployment payments/api, container api: image differs: docker.io/acme/api:1.2 in source, docker.io/acme/web:1.3 in target",
+		"image docker.io/acme/api is only used in source, by Deployment payments/api",
+		"image docker.io/acme/web is only used in target, by Deployment payments/api",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffImagesPinnedByDigest(t *testing.T) {
+	source := ImageInventory(imageObjects("nginx@sha256:aaa", "", "api"), nil)
+	target := ImageInventory(imageObjects("nginx@sha256:bbb", "", "api"), nil)
+
+	findings := DiffImages(source, target)
+
+	if len(findings) != 1 || findings[0].Severity != "warning" {
+		t.Fatalf("Expected references pinned to different digests to be a version difference, got %+v", findings)
+	}
+	expected := "Deployment payments/api, container api: image differs: docker.io/library/nginx@sha256:aaa in source, docker.io/library/nginx@sha256:bbb in target"
+	if findings[0].Diff[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, findings[0].Diff[0])
+	}
+}
+
+func TestInventoryRows(t *testing.T) {
+	source := ImageInventory(imageObjects("acme/api:1.2", "", "api", "web"), nil)
+	target := ImageInventory(imageObjects("acme/api:1.2", "", "api", "worker"), nil)
+	target[0].Image.Tag = "1.3"
+
+	expected := `Deployment payments/api
+  container api:
+    docker.io/acme/api:1.2 in source
+    docker.io/acme/api:1.3 in target
+Deployment payments/web
+  container web:
+    docker.io/acme/api:1.2 in source
+    not used in target
+Deployment payments/worker
+  container worker:
+    not used in source
+    docker.io/acme/api:1.2 in target
+`
+	if got := compare.FormatMatrix(InventoryRows(source, target)); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"fmt"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	Corev1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "impact",
+		Description: "Impact of differing ConfigMaps and Secrets on the workloads consuming them",
+		Run:         runImpact,
+	})
+}
+
+// configCriteria are the fields that change what a consuming pod sees. Secret values are only
+// compared through their fingerprint and never printed.
+var configCriteria = map[string][]string{
+	"ConfigMap": {"Data", "BinaryData"},
+	"Secret":    {"Data", "Type"},
+}
+
+// ConfigObjects are the workloads of one cluster and the configuration they consume.
+type ConfigObjects struct {
+	Workloads  []Workload
+	ConfigMaps *Corev1.ConfigMapList
+	Secrets    *Corev1.SecretList
+}
+
+func listConfigObjects(cluster compare.Cluster, namespaces []string) (ConfigObjects, error) {
+	objects := ConfigObjects{ConfigMaps: &Corev1.ConfigMapList{}, Secrets: &Corev1.SecretList{}}
+	var err error
+	if objects.Workloads, err = listWorkloads(cluster, namespaces); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		configMaps, err := query.ListConfigMaps(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.ConfigMaps.Items = append(objects.ConfigMaps.Items, configMaps.Items...)
+		secrets, err := query.ListSecrets(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Secrets.Items = append(objects.Secrets.Items, secrets.Items...)
+	}
+	return objects, nil
+}
+
+func runImpact(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listConfigObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listConfigObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return ConfigImpact(source, target), nil
+}
+
+// ChangedConfig returns how each ConfigMap and Secret that is not the same in both clusters differs,
+// keyed by "Kind namespace/name": "differs between clusters", "is missing in target" or "is missing in source".
+func ChangedConfig(source, target ConfigObjects) map[string]string {
+	sourceFingerprints, targetFingerprints := configFingerprints(source), configFingerprints(target)
+	changed := map[string]string{}
+	for key, fingerprint := range sourceFingerprints {
+		targetFingerprint, found := targetFingerprints[key]
+		switch {
+		case !found:
+			changed[key] = "is missing in target"
+		case targetFingerprint != fingerprint:
+			changed[key] = "differs between clusters"
+		}
+	}
+	for key := range targetFingerprints {
+		if _, found := sourceFingerprints[key]; !found {
+			changed[key] = "is missing in source"
+		}
+	}
+	return changed
+}
+
+// ConfigImpact marks the workloads of either cluster that consume a changed ConfigMap or Secret,
+// through volumes, projected volumes, env or envFrom, as effectively different.
+func ConfigImpact(source, target ConfigObjects) []DAO.DiffWithName {
+	changed := ChangedConfig(source, target)
+	var findings []DAO.DiffWithName
+	reported := map[string]bool{}
+	for _, workloads := range [][]Workload{source.Workloads, target.Workloads} {
+		for _, workload := range workloads {
+			for _, ref := range podTemplateReferences(workload) {
+				how, isChanged := changed[ref.target()]
+				key := workload.ID() + " " + ref.Via + " " + ref.target()
+				if !isChanged || reported[key] {
+					continue
+				}
+				reported[key] = true
+				findings = append(findings, newFinding("impact", workload.Namespace, workload.ID(), ref.Via+" "+ref.target(), plugins.SeverityWarning,
+					fmt.Sprintf("%s is effectively different: it consumes %s (%s), which %s", workload.ID(), ref.target(), ref.Via, how)))
+			}
+		}
+	}
+	return findings
+}
+
+func configFingerprints(objects ConfigObjects) map[string]string {
+	fingerprints := map[string]string{}
+	if objects.ConfigMaps != nil {
+		for _, configMap := range objects.ConfigMaps.Items {
+			fingerprints["ConfigMap "+configMap.Namespace+"/"+configMap.Name] = compare.Fingerprint(configMap, configCriteria["ConfigMap"])
+		}
+	}
+	if objects.Secrets != nil {
+		for _, secret := range objects.Secrets.Items {
+			fingerprints["Secret "+secret.Namespace+"/"+secret.Name] = compare.Fingerprint(secret, configCriteria["Secret"])
+		}
+	}
+	return fingerprints
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigImpact(t *testing.T) {
+	consumer := workload("payments", "api", nil)
+	consumer.Template.Spec.Containers[0].Env = []Corev1.EnvVar{{Name: "API_URL", ValueFrom: &Corev1.EnvVarSource{
+		ConfigMapKeyRef: &Corev1.ConfigMapKeySelector{LocalObjectReference: Corev1.LocalObjectReference{Name: "app-config"}, Key: "url"},
+	}}}
+	bystander := workload("payments", "worker", nil)
+	bystander.Kind = "StatefulSet"
+	configMap := func(url string) Corev1.ConfigMap {
+		return Corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "payments"}, Data: map[string]string{"url": url}}
+	}
+	source := ConfigObjects{Workloads: []Workload{consumer, bystander}, ConfigMaps: &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{configMap("https://a")}}}
+	target := ConfigObjects{Workloads: []Workload{consumer, bystander}, ConfigMaps: &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{configMap("https://b")}}}
+
+	findings := ConfigImpact(source, target)
+
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %+v", findings)
+	}
+	expected := "Deployment payments/api is effectively different: it consumes ConfigMap payments/app-config (env API_URL), which differs between clusters"
+	if findings[0].Diff[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, findings[0].Diff[0])
+	}
+}
+
+func TestChangedConfigMissingSecret(t *testing.T) {
+	source := ConfigObjects{Secrets: &Corev1.SecretList{Items: []Corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "api-env", Namespace: "payments"}}}}}
+
+	changed := ChangedConfig(source, ConfigObjects{})
+
+	if changed["Secret payments/api-env"] != "is missing in target" {
+		t.Errorf("Expected the secret to be missing in target, got %v", changed)
+	}
+}
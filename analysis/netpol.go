@@ -0,0 +1,365 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "netpol",
+		Description: "NetworkPolicy flows: traffic allowed between workloads",
+		Run:         runNetworkPolicies,
+	})
+}
+
+// externalEndpoint stands for everything outside the cluster, matched by ipBlock peers.
+const externalEndpoint = "external"
+
+// NetworkState is what the allowed flows of a cluster are computed from.
+type NetworkState struct {
+	Namespaces *Corev1.NamespaceList
+	Workloads  []Workload
+	Policies   *networkingv1.NetworkPolicyList
+}
+
+// Flow is traffic from one workload, or from outside the cluster, to another on one port.
+type Flow struct {
+	From, To                   string
+	FromNamespace, ToNamespace string
+	// Port is like "TCP/8080", or "any port" for destinations that declare no container port. Those
+	// destinations, like the outside of the cluster, are also checked on every port the policies name.
+	Port string
+}
+
+func (f Flow) String() string {
+	return fmt.Sprintf("%s -> %s on %s", f.From, f.To, f.Port)
+}
+
+type endpoint struct {
+	id, namespace string
+	labels        map[string]string
+	external      bool
+	ports         []containerPort
+}
+
+type containerPort struct {
+	number   int32
+	name     string
+	protocol Corev1.Protocol
+	any      bool
+}
+
+func (p containerPort) String() string {
+	if p.any {
+		return "any port"
+	}
+	return string(p.protocol) + "/" + strconv.Itoa(int(p.number))
+}
+
+// listNetworkState lists the workloads and NetworkPolicies of the namespaces in scope, so that only the flows
+// from and to them are computed. Every namespace is listed for its labels, which namespaceSelectors match.
+func listNetworkState(cluster compare.Cluster, names []string) (NetworkState, error) {
+	var state NetworkState
+	namespaces, _, err := allNamespaces(cluster)
+	if err != nil {
+		return state, err
+	}
+	state.Namespaces = namespaces
+	if state.Workloads, err = listWorkloads(cluster, names); err != nil {
+		return state, err
+	}
+	state.Policies = &networkingv1.NetworkPolicyList{}
+	for _, namespace := range names {
+		policies, err := query.ListNetworkPolicies(cluster.Clientset, namespace)
+		if err != nil {
+			return state, err
+		}
+		state.Policies.Items = append(state.Policies.Items, policies.Items...)
+	}
+	return state, nil
+}
+
+func runNetworkPolicies(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listNetworkState(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing network policies in the source cluster: %v", err)
+	}
+	target, err := listNetworkState(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing network policies in the target cluster: %v", err)
+	}
+	var findings []DAO.DiffWithName
+	for _, finding := range DiffFlows(source, target) {
+		if scope.Includes(finding.Namespace) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// DiffFlows reports the flows allowed in one cluster and not in the other. Only flows between
+// workloads present in both clusters are compared: missing workloads are reported by the comparison.
+// A flow allowed only in the target means the target's segmentation is less strict, and is an error.
+func DiffFlows(source, target NetworkState) []DAO.DiffWithName {
+	inBoth := map[string]bool{externalEndpoint: true}
+	targetWorkloads := map[string]bool{}
+	for _, workload := range target.Workloads {
+		targetWorkloads[workload.ID()] = true
+	}
+	for _, workload := range source.Workloads {
+		if targetWorkloads[workload.ID()] {
+			inBoth[workload.ID()] = true
+		}
+	}
+	// Both clusters are checked on the ports of either, so that a port only one of them names is compared.
+	ports := rulePorts(source.Policies, target.Policies)
+	sourceFlows, targetFlows := allowedFlows(source, ports), allowedFlows(target, ports)
+	var findings []DAO.DiffWithName
+	report := func(flows, other map[Flow]bool, only, severity string) {
+		for _, flow := range sortedFlows(flows) {
+			if !other[flow] && inBoth[flow.From] && inBoth[flow.To] {
+				findings = append(findings, newFinding("netpol", flow.ToNamespace, flow.To, "from "+flow.From+" on "+flow.Port, severity,
+					fmt.Sprintf("%s is allowed only in %s", flow, only)))
+			}
+		}
+	}
+	report(targetFlows, sourceFlows, "target", plugins.SeverityError)
+	report(sourceFlows, targetFlows, "source", plugins.SeverityInfo)
+	return findings
+}
+
+// AllowedFlows evaluates the NetworkPolicies of a cluster against its namespaces and pod template labels.
+// A flow is allowed when the egress policies of its origin and the ingress policies of its destination allow it.
+func AllowedFlows(state NetworkState) map[Flow]bool {
+	return allowedFlows(state, rulePorts(state.Policies))
+}
+
+// allowedFlows checks the destinations that declare no port, like the outside of the cluster, on "any port"
+// and on each of ports, since rules with ports never match "any port".
+func allowedFlows(state NetworkState, ports []containerPort) map[Flow]bool {
+	namespaceLabels := map[string]map[string]string{}
+	if state.Namespaces != nil {
+		for _, ns := range state.Namespaces.Items {
+			namespaceLabels[ns.Name] = ns.Labels
+		}
+	}
+	endpoints := []endpoint{{id: externalEndpoint, external: true, ports: []containerPort{{any: true}}}}
+	for _, workload := range state.Workloads {
+		endpoints = append(endpoints, workloadEndpoint(workload))
+	}
+	var policies []networkingv1.NetworkPolicy
+	if state.Policies != nil {
+		policies = state.Policies.Items
+	}
+
+	flows := map[Flow]bool{}
+	for _, from := range endpoints {
+		for _, to := range endpoints {
+			if from.external && to.external {
+				continue
+			}
+			toPorts := to.ports
+			if to.ports[0].any {
+				toPorts = append([]containerPort{{any: true}}, ports...)
+			}
+			for _, port := range toPorts {
+				if allowed(policies, namespaceLabels, networkingv1.PolicyTypeEgress, from, to, port) &&
+					allowed(policies, namespaceLabels, networkingv1.PolicyTypeIngress, to, from, port) {
+					flows[Flow{From: from.id, To: to.id, FromNamespace: from.namespace, ToNamespace: to.namespace, Port: port.String()}] = true
+				}
+			}
+		}
+	}
+	return flows
+}
+
+func workloadEndpoint(workload Workload) endpoint {
+	e := endpoint{id: workload.ID(), namespace: workload.Namespace, labels: workload.Template.Labels}
+	for _, container := range workload.Template.Spec.Containers {
+		for _, port := range container.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = Corev1.ProtocolTCP
+			}
+			e.ports = append(e.ports, containerPort{number: port.ContainerPort, name: port.Name, protocol: protocol})
+		}
+	}
+	if len(e.ports) == 0 {
+		e.ports = []containerPort{{any: true}}
+	}
+	return e
+}
+
+// rulePorts returns the numbered ports the rules of policies name, once each. A range is named by its first port.
+func rulePorts(lists ...*networkingv1.NetworkPolicyList) []containerPort {
+	var ports []containerPort
+	seen := map[string]bool{}
+	add := func(rulePorts []networkingv1.NetworkPolicyPort) {
+		for _, p := range rulePorts {
+			if p.Port == nil || p.Port.Type == intstr.String {
+				continue
+			}
+			port := containerPort{number: p.Port.IntVal, protocol: Corev1.ProtocolTCP}
+			if p.Protocol != nil {
+				port.protocol = *p.Protocol
+			}
+			if !seen[port.String()] {
+				seen[port.String()] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, policy := range list.Items {
+			for _, rule := range policy.Spec.Ingress {
+				add(rule.Ports)
+			}
+			for _, rule := range policy.Spec.Egress {
+				add(rule.Ports)
+			}
+		}
+	}
+	return ports
+}
+
+// allowed reports whether the policies of subject let traffic in (ingress) from peer, or out (egress) to peer.
+// Traffic is allowed when no policy of that type selects subject, or when a rule of one of them matches.
+func allowed(policies []networkingv1.NetworkPolicy, namespaceLabels map[string]map[string]string, policyType networkingv1.PolicyType, subject, peer endpoint, port containerPort) bool {
+	if subject.external {
+		return true
+	}
+	isolated := false
+	for _, policy := range policies {
+		if policy.Namespace != subject.namespace || !hasPolicyType(policy, policyType) || !selectorMatches(&policy.Spec.PodSelector, subject.labels) {
+			continue
+		}
+		isolated = true
+		if policyType == networkingv1.PolicyTypeIngress {
+			for _, rule := range policy.Spec.Ingress {
+				if portsMatch(rule.Ports, port) && peersMatch(rule.From, policy.Namespace, namespaceLabels, peer) {
+					return true
+				}
+			}
+		} else {
+			for _, rule := range policy.Spec.Egress {
+				if portsMatch(rule.Ports, port) && peersMatch(rule.To, policy.Namespace, namespaceLabels, peer) {
+					return true
+				}
+			}
+		}
+	}
+	return !isolated
+}
+
+// hasPolicyType applies the defaults of policyTypes: Ingress always, Egress when the policy has egress rules.
+func hasPolicyType(policy networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, namespaceLabels map[string]map[string]string, e endpoint) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		switch {
+		case peer.IPBlock != nil:
+			if e.external {
+				return true
+			}
+		case e.external:
+		case peer.NamespaceSelector == nil:
+			if e.namespace == policyNamespace && selectorMatches(peer.PodSelector, e.labels) {
+				return true
+			}
+		default:
+			if selectorMatches(peer.NamespaceSelector, namespaceLabels[e.namespace]) &&
+				(peer.PodSelector == nil || selectorMatches(peer.PodSelector, e.labels)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// portsMatch reports whether a rule's ports include a destination port. Rules with ports never match
+// "any port", since the port is unknown: destinations that declare none are also checked on each rule port.
+func portsMatch(ports []networkingv1.NetworkPolicyPort, port containerPort) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	if port.any {
+		return false
+	}
+	for _, p := range ports {
+		protocol := Corev1.ProtocolTCP
+		if p.Protocol != nil {
+			protocol = *p.Protocol
+		}
+		if protocol != port.protocol {
+			continue
+		}
+		switch {
+		case p.Port == nil:
+			return true
+		case p.Port.Type == intstr.String:
+			if p.Port.StrVal == port.name {
+				return true
+			}
+		case p.EndPort != nil:
+			if port.number >= p.Port.IntVal && port.number <= *p.EndPort {
+				return true
+			}
+		case p.Port.IntVal == port.number:
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(selector *metav1.LabelSelector, objectLabels map[string]string) bool {
+	if selector == nil {
+		return true
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	return err == nil && s.Matches(labelsSet(objectLabels))
+}
+
+func sortedFlows(flows map[Flow]bool) []Flow {
+	keys := map[string]bool{}
+	byKey := map[string]Flow{}
+	for flow := range flows {
+		keys[flow.String()] = true
+		byKey[flow.String()] = flow
+	}
+	sorted := make([]Flow, 0, len(flows))
+	for _, key := range sortedKeys(keys) {
+		sorted = append(sorted, byKey[key])
+	}
+	return sorted
+}
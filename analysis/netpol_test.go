@@ -0,0 +1,133 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func workload(namespace, name string, labels map[string]string, ports ...int32) Workload {
+	container := Corev1.Container{Name: name}
+	for _, port := range ports {
+		container.Ports = append(container.Ports, Corev1.ContainerPort{ContainerPort: port})
+	}
+	return Workload{Kind: "Deployment", Namespace: namespace, Name: name, Replicas: 1, Template: Corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec:       Corev1.PodSpec{Containers: []Corev1.Container{container}},
+	}}
+}
+
+func namespaces(names ...string) *Corev1.NamespaceList {
+	list := &Corev1.NamespaceList{}
+	for _, name := range names {
+		list.Items = append(list.Items, Corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/metadata.name": name}}})
+	}
+	return list
+}
+
+// dbPolicy lets only pods of the namespaces selected by namespaceLabels reach the database on 5432.
+func dbPolicy(namespaceLabels map[string]string) networkingv1.NetworkPolicy {
+	port := intstr.FromInt(5432)
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "data"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "postgres"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From:  []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: namespaceLabels}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+			}},
+		},
+	}
+}
+
+func TestAllowedFlows(t *testing.T) {
+	state := NetworkState{
+		Namespaces: namespaces("payments", "data", "web"),
+		Workloads: []Workload{
+			workload("payments", "api", map[string]string{"app": "api"}, 8080),
+			workload("web", "frontend", map[string]string{"app": "frontend"}),
+			workload("data", "postgres", map[string]string{"app": "postgres"}, 5432),
+		},
+		Policies: &networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{dbPolicy(map[string]string{"kubernetes.io/metadata.name": "payments"})}},
+	}
+
+	flows := AllowedFlows(state)
+
+	toDatabase := func(namespace, name string) bool {
+		return flows[Flow{From: "Deployment " + namespace + "/" + name, To: "Deployment data/postgres", FromNamespace: namespace, ToNamespace: "data", Port: "TCP/5432"}]
+	}
+	if !toDatabase("payments", "api") {
+		t.Errorf("Expected payments/api to reach the database")
+	}
+	if toDatabase("web", "frontend") {
+		t.Errorf("Expected web/frontend not to reach the database")
+	}
+	if !flows[Flow{From: "Deployment data/postgres", To: "Deployment payments/api", FromNamespace: "data", ToNamespace: "payments", Port: "TCP/8080"}] {
+		t.Errorf("Expected traffic to a workload without policies to be allowed")
+	}
+}
+
+func TestDiffFlowsReportsLessStrictTarget(t *testing.T) {
+	source := NetworkState{
+		Namespaces: namespaces("payments", "data", "web"),
+		Workloads: []Workload{
+			workload("payments", "api", map[string]string{"app": "api"}),
+			workload("web", "frontend", map[string]string{"app": "frontend"}),
+			workload("data", "postgres", map[string]string{"app": "postgres"}, 5432),
+		},
+		Policies: &networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{dbPolicy(map[string]string{"kubernetes.io/metadata.name": "payments"})}},
+	}
+	target := source
+	// The target selects every namespace: the frontend can reach the database.
+	target.Policies = &networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{dbPolicy(map[string]string{})}}
+
+	findings := DiffFlows(source, target)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	for _, finding := range findings {
+		if finding.Severity != "error" || !strings.HasSuffix(finding.Diff[0], "is allowed only in target") {
+			t.Errorf("Unexpected finding %+v", finding)
+		}
+	}
+	if findings[0].Diff[0] != "Deployment data/postgres -> Deployment data/postgres on TCP/5432 is allowed only in target" {
+		t.Errorf("Unexpected first finding %q", findings[0].Diff[0])
+	}
+}
+
+func TestDiffFlowsChecksEgressToTheOutsideOnRulePorts(t *testing.T) {
+	source := NetworkState{
+		Namespaces: namespaces("payments"),
+		Workloads:  []Workload{workload("payments", "api", map[string]string{"app": "api"}, 8080)},
+		Policies: &networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "deny-egress", Namespace: "payments"},
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}},
+		}}},
+	}
+	target := source
+	https := intstr.FromInt(443)
+	target.Policies = &networkingv1.NetworkPolicyList{Items: []networkingv1.NetworkPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-https", Namespace: "payments"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To:    []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Port: &https}},
+			}},
+		},
+	}}}
+
+	findings := DiffFlows(source, target)
+
+	if len(findings) != 1 || findings[0].Diff[0] != "Deployment payments/api -> external on TCP/443 is allowed only in target" {
+		t.Fatalf("Expected egress to the outside on 443 to be allowed only in target, got %+v", findings)
+	}
+	if findings[0].Severity != "error" {
+		t.Errorf("Expected an error, got %s", findings[0].Severity)
+	}
+}
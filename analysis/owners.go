@@ -0,0 +1,273 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "owners",
+		Description: "Owner trees: Deployment -> ReplicaSet -> Pod, StatefulSet and DaemonSet pods, and orphans",
+		Run:         runOwners,
+	})
+}
+
+// revisionAnnotation is set by the Deployment controller on each ReplicaSet it creates.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// OwnerObjects are the controllers and pods of one cluster.
+type OwnerObjects struct {
+	Deployments  *v1.DeploymentList
+	ReplicaSets  *v1.ReplicaSetList
+	StatefulSets *v1.StatefulSetList
+	DaemonSets   *v1.DaemonSetList
+	Pods         *Corev1.PodList
+}
+
+// OwnerTree summarizes the ownership tree of one controller.
+type OwnerTree struct {
+	Namespace string
+	// Properties are like "active ReplicaSet" or "pods".
+	Properties map[string]string
+}
+
+type owned struct {
+	kind, namespace, name string
+	owners                []metav1.OwnerReference
+}
+
+func (o owned) id() string {
+	return o.kind + " " + o.namespace + "/" + o.name
+}
+
+func listOwnerObjects(cluster compare.Cluster, namespaces []string) (OwnerObjects, error) {
+	objects := OwnerObjects{
+		Deployments: &v1.DeploymentList{}, ReplicaSets: &v1.ReplicaSetList{}, StatefulSets: &v1.StatefulSetList{},
+		DaemonSets: &v1.DaemonSetList{}, Pods: &Corev1.PodList{},
+	}
+	for _, namespace := range namespaces {
+		deployments, err := query.ListDeployments(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Deployments.Items = append(objects.Deployments.Items, deployments.Items...)
+		replicaSets, err := query.ListReplicaSets(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.ReplicaSets.Items = append(objects.ReplicaSets.Items, replicaSets.Items...)
+		statefulSets, err := query.ListStatefulSets(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.StatefulSets.Items = append(objects.StatefulSets.Items, statefulSets.Items...)
+		daemonSets, err := query.ListDaemonSets(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.DaemonSets.Items = append(objects.DaemonSets.Items, daemonSets.Items...)
+		pods, err := query.ListPods(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Pods.Items = append(objects.Pods.Items, pods.Items...)
+	}
+	return objects, nil
+}
+
+func runOwners(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listOwnerObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing controllers and pods in the source cluster: %v", err)
+	}
+	target, err := listOwnerObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing controllers and pods in the target cluster: %v", err)
+	}
+	return DiffOwnerTrees(source, target), nil
+}
+
+// DiffOwnerTrees reports the controllers present in both clusters whose trees differ, then the orphans of each cluster.
+func DiffOwnerTrees(source, target OwnerObjects) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	sourceTrees, targetTrees := OwnerTrees(source), OwnerTrees(target)
+	ids := make([]string, 0, len(sourceTrees))
+	for id := range sourceTrees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		targetTree, found := targetTrees[id]
+		if !found {
+			continue
+		}
+		sourceTree := sourceTrees[id]
+		properties := map[string]bool{}
+		for property := range sourceTree.Properties {
+			properties[property] = true
+		}
+		for property := range targetTree.Properties {
+			properties[property] = true
+		}
+		for _, property := range sortedKeys(properties) {
+			sourceValue, targetValue := sourceTree.Properties[property], targetTree.Properties[property]
+			if sourceValue != targetValue {
+				findings = append(findings, newFinding("owners", sourceTree.Namespace, id, property, plugins.SeverityWarning,
+					fmt.Sprintf("%s: %s is %s in source and %s in target", id, property, valueOrNone(sourceValue), valueOrNone(targetValue))))
+			}
+		}
+	}
+	for _, side := range []struct {
+		objects OwnerObjects
+		target  bool
+	}{{source, false}, {target, true}} {
+		for _, orphan := range Orphans(side.objects) {
+			findings = append(findings, newFinding("owners", orphan.Namespace, orphan.Object, "orphan in "+inCluster(side.target), plugins.SeverityWarning,
+				fmt.Sprintf("%s is orphaned in %s: %s", orphan.Object, inCluster(side.target), orphan.Reason)))
+		}
+	}
+	return findings
+}
+
+// OwnerTrees summarizes the tree of every Deployment, StatefulSet and DaemonSet, keyed by "Kind namespace/name".
+// A Deployment's tree has its active ReplicaSet, the number of its ReplicaSets that have pods and its number of pods.
+func OwnerTrees(objects OwnerObjects) map[string]OwnerTree {
+	trees := map[string]OwnerTree{}
+	podsByOwner := map[types.UID]int{}
+	for _, pod := range podItems(objects) {
+		if owner := metav1.GetControllerOf(&pod); owner != nil {
+			podsByOwner[owner.UID]++
+		}
+	}
+	if objects.Deployments != nil {
+		for _, deployment := range objects.Deployments.Items {
+			tree := OwnerTree{Namespace: deployment.Namespace, Properties: map[string]string{"active ReplicaSet": ""}}
+			activeRevision, withPods, pods := -1, 0, 0
+			for _, replicaSet := range replicaSetItems(objects) {
+				owner := metav1.GetControllerOf(&replicaSet)
+				if owner == nil || owner.UID != deployment.UID {
+					continue
+				}
+				if podsByOwner[replicaSet.UID] > 0 {
+					withPods++
+					pods += podsByOwner[replicaSet.UID]
+				}
+				revision, _ := strconv.Atoi(replicaSet.Annotations[revisionAnnotation])
+				if revision > activeRevision {
+					activeRevision = revision
+					tree.Properties["active ReplicaSet"] = replicaSet.Name
+				}
+			}
+			tree.Properties["ReplicaSets with pods"] = strconv.Itoa(withPods)
+			tree.Properties["pods"] = strconv.Itoa(pods)
+			trees["Deployment "+deployment.Namespace+"/"+deployment.Name] = tree
+		}
+	}
+	if objects.StatefulSets != nil {
+		for _, statefulSet := range objects.StatefulSets.Items {
+			trees["StatefulSet "+statefulSet.Namespace+"/"+statefulSet.Name] = OwnerTree{Namespace: statefulSet.Namespace, Properties: map[string]string{"pods": strconv.Itoa(podsByOwner[statefulSet.UID])}}
+		}
+	}
+	if objects.DaemonSets != nil {
+		for _, daemonSet := range objects.DaemonSets.Items {
+			trees["DaemonSet "+daemonSet.Namespace+"/"+daemonSet.Name] = OwnerTree{Namespace: daemonSet.Namespace, Properties: map[string]string{"pods": strconv.Itoa(podsByOwner[daemonSet.UID])}}
+		}
+	}
+	return trees
+}
+
+// Orphan is a ReplicaSet or Pod without its controller.
+type Orphan struct {
+	Namespace, Object, Reason string
+}
+
+// Orphans returns the ReplicaSets and Pods whose controller does not exist, and the pods no controller owns.
+// Owners of kinds that are not listed, like Jobs or Nodes for static pods, are assumed to exist.
+func Orphans(objects OwnerObjects) []Orphan {
+	existing := map[types.UID]bool{}
+	if objects.Deployments != nil {
+		for _, deployment := range objects.Deployments.Items {
+			existing[deployment.UID] = true
+		}
+	}
+	for _, replicaSet := range replicaSetItems(objects) {
+		existing[replicaSet.UID] = true
+	}
+	if objects.StatefulSets != nil {
+		for _, statefulSet := range objects.StatefulSets.Items {
+			existing[statefulSet.UID] = true
+		}
+	}
+	if objects.DaemonSets != nil {
+		for _, daemonSet := range objects.DaemonSets.Items {
+			existing[daemonSet.UID] = true
+		}
+	}
+	var items []owned
+	for _, replicaSet := range replicaSetItems(objects) {
+		items = append(items, owned{"ReplicaSet", replicaSet.Namespace, replicaSet.Name, replicaSet.OwnerReferences})
+	}
+	for _, pod := range podItems(objects) {
+		items = append(items, owned{"Pod", pod.Namespace, pod.Name, pod.OwnerReferences})
+	}
+
+	var orphans []Orphan
+	for _, item := range items {
+		var controller *metav1.OwnerReference
+		for i := range item.owners {
+			if item.owners[i].Controller != nil && *item.owners[i].Controller {
+				controller = &item.owners[i]
+			}
+		}
+		switch {
+		case controller == nil && item.kind == "Pod":
+			orphans = append(orphans, Orphan{item.namespace, item.id(), "it is not owned by any controller"})
+		case controller == nil:
+			orphans = append(orphans, Orphan{item.namespace, item.id(), "it has no owner"})
+		case isOwnerKind(controller.Kind) && !existing[controller.UID]:
+			orphans = append(orphans, Orphan{item.namespace, item.id(), fmt.Sprintf("its owner %s %s/%s does not exist", controller.Kind, item.namespace, controller.Name)})
+		}
+	}
+	return orphans
+}
+
+func isOwnerKind(kind string) bool {
+	return kind == "Deployment" || kind == "ReplicaSet" || kind == "StatefulSet" || kind == "DaemonSet"
+}
+
+func replicaSetItems(objects OwnerObjects) []v1.ReplicaSet {
+	if objects.ReplicaSets == nil {
+		return nil
+	}
+	return objects.ReplicaSets.Items
+}
+
+func podItems(objects OwnerObjects) []Corev1.Pod {
+	if objects.Pods == nil {
+		return nil
+	}
+	return objects.Pods.Items
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
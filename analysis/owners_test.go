@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func controlledBy(kind, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+func replicaSet(name, revision string, uid types.UID) v1.ReplicaSet {
+	return v1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: name, Namespace: "payments", UID: uid,
+		Annotations:     map[string]string{revisionAnnotation: revision},
+		OwnerReferences: controlledBy("Deployment", "web", "deploy-uid"),
+	}}
+}
+
+func pod(name, owner string, uid types.UID) Corev1.Pod {
+	p := Corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "payments"}}
+	if owner != "" {
+		p.OwnerReferences = controlledBy("ReplicaSet", owner, uid)
+	}
+	return p
+}
+
+func TestDiffOwnerTreesStuckRollout(t *testing.T) {
+	deployments := &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "payments", UID: "deploy-uid"}}}}
+	source := OwnerObjects{
+		Deployments: deployments,
+		ReplicaSets: &v1.ReplicaSetList{Items: []v1.ReplicaSet{replicaSet("web-old", "1", "rs-old"), replicaSet("web-new", "2", "rs-new")}},
+		Pods:        &Corev1.PodList{Items: []Corev1.Pod{pod("web-new-a", "web-new", "rs-new"), pod("web-new-b", "web-new", "rs-new")}},
+	}
+	// The rollout is stuck in the target: the old ReplicaSet still has a pod.
+	target := OwnerObjects{
+		Deployments: deployments,
+		ReplicaSets: source.ReplicaSets,
+		Pods:        &Corev1.PodList{Items: []Corev1.Pod{pod("web-old-a", "web-old", "rs-old"), pod("web-new-a", "web-new", "rs-new"), pod("debug", "", "")}},
+	}
+
+	findings := DiffOwnerTrees(source, target)
+
+	lines := []string{}
+	for _, finding := range findings {
+		lines = append(lines, finding.Diff[0])
+	}
+	expected := []string{
+		"Deployment payments/web: ReplicaSets with pods is 1 in source and 2 in target",
+		"Pod payments/debug is orphaned in target: it is not owned by any controller",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestOrphanedReplicaSet(t *testing.T) {
+	objects := OwnerObjects{ReplicaSets: &v1.ReplicaSetList{Items: []v1.ReplicaSet{replicaSet("web-old", "1", "rs-old")}}}
+
+	orphans := Orphans(objects)
+
+	if len(orphans) != 1 || orphans[0].Reason != "its owner Deployment payments/web does not exist" {
+		t.Errorf("Expected the ReplicaSet to be orphaned, got %+v", orphans)
+	}
+}
@@ -0,0 +1,280 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+	"kompare/tools"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "posture",
+		Description: "Security posture of namespaces and workloads, and where the target is weaker than the source",
+		Run:         runPosture,
+	})
+}
+
+// podSecurityModes are the Pod Security Admission modes, set as pod-security.kubernetes.io/<mode> namespace labels.
+var podSecurityModes = []string{"enforce", "audit", "warn"}
+
+// podSecurityLevels rank the Pod Security Standards from the least to the most restrictive.
+var podSecurityLevels = map[string]int{"privileged": 0, "baseline": 1, "restricted": 2}
+
+// PostureSetting is the value of one security setting and how strict it is: a higher Strength is stricter.
+type PostureSetting struct {
+	Value    string
+	Strength int
+	// entries are the values of a list setting, which compare as sets rather than by Strength.
+	entries map[string]bool
+}
+
+// strengthAgainst is negative when the setting is weaker than source, and positive when it is stronger.
+// A list setting is weaker when it has an entry source does not have, and stronger when it has a strict subset of source's entries.
+func (s PostureSetting) strengthAgainst(source PostureSetting) int {
+	if s.entries == nil || source.entries == nil {
+		return s.Strength - source.Strength
+	}
+	for entry := range s.entries {
+		if !source.entries[entry] {
+			return -1
+		}
+	}
+	return len(source.entries) - len(s.entries)
+}
+
+// ObjectPosture is the security settings of a namespace or workload, by setting name.
+type ObjectPosture struct {
+	Namespace string
+	Settings  map[string]PostureSetting
+}
+
+// PostureObjects are the namespaces, workloads and network policies of one cluster.
+type PostureObjects struct {
+	Namespaces *Corev1.NamespaceList
+	Workloads  []Workload
+	Policies   *networkingv1.NetworkPolicyList
+}
+
+func listPostureObjects(cluster compare.Cluster, namespaces []string) (PostureObjects, error) {
+	objects := PostureObjects{Namespaces: &Corev1.NamespaceList{}, Policies: &networkingv1.NetworkPolicyList{}}
+	list, _, err := allNamespaces(cluster)
+	if err != nil {
+		return objects, err
+	}
+	for _, ns := range list.Items {
+		if tools.IsInList(ns.Name, namespaces) {
+			objects.Namespaces.Items = append(objects.Namespaces.Items, ns)
+		}
+	}
+	if objects.Workloads, err = listWorkloads(cluster, namespaces); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		policies, err := query.ListNetworkPolicies(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Policies.Items = append(objects.Policies.Items, policies.Items...)
+	}
+	return objects, nil
+}
+
+func runPosture(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listPostureObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listPostureObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return DiffPosture(source, target), nil
+}
+
+// DiffPosture compares the settings of the namespaces and workloads present in both clusters.
+// A setting weaker in the target is an error, a stronger one is info, and a different one just as strict is a warning.
+func DiffPosture(source, target PostureObjects) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	sourcePostures, targetPostures := Posture(source), Posture(target)
+	ids := make([]string, 0, len(sourcePostures))
+	for id := range sourcePostures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		targetPosture, found := targetPostures[id]
+		if !found {
+			continue
+		}
+		sourcePosture := sourcePostures[id]
+		settings := map[string]bool{}
+		for setting := range sourcePosture.Settings {
+			settings[setting] = true
+		}
+		for setting := range targetPosture.Settings {
+			settings[setting] = true
+		}
+		for _, setting := range sortedKeys(settings) {
+			sourceSetting, inSource := sourcePosture.Settings[setting]
+			targetSetting, inTarget := targetPosture.Settings[setting]
+			if !inSource || !inTarget || sourceSetting.Value == targetSetting.Value {
+				// A container present in a single cluster is reported by the comparison.
+				continue
+			}
+			severity, how := plugins.SeverityWarning, "differs"
+			if strength := targetSetting.strengthAgainst(sourceSetting); strength < 0 {
+				severity, how = plugins.SeverityError, "is weaker in target"
+			} else if strength > 0 {
+				severity, how = plugins.SeverityInfo, "is stronger in target"
+			}
+			findings = append(findings, newFinding("posture", sourcePosture.Namespace, id, setting, severity,
+				fmt.Sprintf("%s: %s %s: %s in source, %s in target", id, setting, how, sourceSetting.Value, targetSetting.Value)))
+		}
+	}
+	return findings
+}
+
+// Posture extracts the security settings of every namespace, keyed by "Namespace name", and of every workload, keyed by "Kind namespace/name".
+func Posture(objects PostureObjects) map[string]ObjectPosture {
+	postures := map[string]ObjectPosture{}
+	if objects.Namespaces != nil {
+		for _, ns := range objects.Namespaces.Items {
+			settings := map[string]PostureSetting{}
+			for _, mode := range podSecurityModes {
+				level, found := ns.Labels["pod-security.kubernetes.io/"+mode]
+				if !found {
+					level = "privileged"
+				}
+				settings["pod security "+mode] = PostureSetting{Value: level, Strength: podSecurityLevels[level]}
+			}
+			for _, policyType := range []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress} {
+				settings["default-deny "+strings.ToLower(string(policyType))+" NetworkPolicy"] = boolSetting(hasDefaultDeny(objects.Policies, ns.Name, policyType), true)
+			}
+			postures["Namespace "+ns.Name] = ObjectPosture{Namespace: ns.Name, Settings: settings}
+		}
+	}
+	for _, workload := range objects.Workloads {
+		postures[workload.ID()] = ObjectPosture{Namespace: workload.Namespace, Settings: podPosture(workload.Template.Spec)}
+	}
+	return postures
+}
+
+// podPosture extracts the settings of a pod spec, then of each container, with the pod's security context as default.
+func podPosture(spec Corev1.PodSpec) map[string]PostureSetting {
+	settings := map[string]PostureSetting{
+		"hostNetwork": boolSetting(spec.HostNetwork, false),
+		"hostPID":     boolSetting(spec.HostPID, false),
+		"hostIPC":     boolSetting(spec.HostIPC, false),
+	}
+	var hostPaths []string
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			hostPaths = append(hostPaths, volume.HostPath.Path)
+		}
+	}
+	settings["hostPath volumes"] = listSetting(hostPaths)
+	automount := spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken
+	settings["automountServiceAccountToken"] = boolSetting(automount, false)
+
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &Corev1.PodSecurityContext{}
+	}
+	containers := map[string]Corev1.Container{}
+	for _, container := range spec.InitContainers {
+		containers["init container "+container.Name] = container
+	}
+	for _, container := range spec.Containers {
+		containers["container "+container.Name] = container
+	}
+	for name, container := range containers {
+		context := container.SecurityContext
+		if context == nil {
+			context = &Corev1.SecurityContext{}
+		}
+		settings[name+" privileged"] = boolSetting(context.Privileged != nil && *context.Privileged, false)
+		var capabilities []string
+		if context.Capabilities != nil {
+			for _, capability := range context.Capabilities.Add {
+				capabilities = append(capabilities, string(capability))
+			}
+		}
+		settings[name+" added capabilities"] = listSetting(capabilities)
+		runAsNonRoot := podContext.RunAsNonRoot
+		if context.RunAsNonRoot != nil {
+			runAsNonRoot = context.RunAsNonRoot
+		}
+		settings[name+" runAsNonRoot"] = boolSetting(runAsNonRoot != nil && *runAsNonRoot, true)
+		settings[name+" readOnlyRootFilesystem"] = boolSetting(context.ReadOnlyRootFilesystem != nil && *context.ReadOnlyRootFilesystem, true)
+		seccomp := podContext.SeccompProfile
+		if context.SeccompProfile != nil {
+			seccomp = context.SeccompProfile
+		}
+		settings[name+" seccomp profile"] = seccompSetting(seccomp)
+	}
+	return settings
+}
+
+// hasDefaultDeny reports whether a policy of the namespace selects every pod without allowing any traffic of the given type.
+func hasDefaultDeny(policies *networkingv1.NetworkPolicyList, namespace string, policyType networkingv1.PolicyType) bool {
+	if policies == nil {
+		return false
+	}
+	for _, policy := range policies.Items {
+		if policy.Namespace != namespace || len(policy.Spec.PodSelector.MatchLabels) != 0 || len(policy.Spec.PodSelector.MatchExpressions) != 0 || !hasPolicyType(policy, policyType) {
+			continue
+		}
+		if (policyType == networkingv1.PolicyTypeIngress && len(policy.Spec.Ingress) == 0) ||
+			(policyType == networkingv1.PolicyTypeEgress && len(policy.Spec.Egress) == 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// boolSetting is stricter when the value is the safe one.
+func boolSetting(value, safe bool) PostureSetting {
+	setting := PostureSetting{Value: strconv.FormatBool(value)}
+	if value == safe {
+		setting.Strength = 1
+	}
+	return setting
+}
+
+// listSetting is stricter with a subset of the entries, e.g. a subset of the added capabilities.
+func listSetting(values []string) PostureSetting {
+	entries := map[string]bool{}
+	for _, value := range values {
+		entries[value] = true
+	}
+	if len(values) == 0 {
+		return PostureSetting{Value: "none", entries: entries}
+	}
+	sort.Strings(values)
+	return PostureSetting{Value: strings.Join(values, ", "), entries: entries}
+}
+
+// seccompSetting is stricter with a profile than unconfined; an unset profile is unconfined by default.
+func seccompSetting(profile *Corev1.SeccompProfile) PostureSetting {
+	if profile == nil {
+		return PostureSetting{Value: "unset"}
+	}
+	if profile.Type == Corev1.SeccompProfileTypeUnconfined {
+		return PostureSetting{Value: string(profile.Type)}
+	}
+	return PostureSetting{Value: string(profile.Type), Strength: 1}
+}
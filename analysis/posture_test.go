@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func postureObjects(enforce string, privileged bool, defaultDeny bool) PostureObjects {
+	ns := namespaces("payments")
+	ns.Items[0].Labels["pod-security.kubernetes.io/enforce"] = enforce
+	api := workload("payments", "api", nil)
+	api.Template.Spec.Containers[0].SecurityContext = &Corev1.SecurityContext{Privileged: &privileged}
+	objects := PostureObjects{Namespaces: ns, Workloads: []Workload{api}, Policies: &networkingv1.NetworkPolicyList{}}
+	if defaultDeny {
+		objects.Policies.Items = append(objects.Policies.Items, networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-deny", Namespace: "payments"},
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+		})
+	}
+	return objects
+}
+
+func TestDiffPostureWeakerTarget(t *testing.T) {
+	findings := DiffPosture(postureObjects("restricted", false, true), postureObjects("baseline", true, false))
+
+	var lines []string
+	for _, finding := range findings {
+		if finding.Severity != "error" {
+			t.Errorf("Expected every finding to be an error, got %+v", finding)
+		}
+		lines = append(lines, finding.Diff[0])
+	}
+	expected := []string{
+		"Deployment payments/api: container api privileged is weaker in target: false in source, true in target",
+		"Namespace payments: default-deny ingress NetworkPolicy is weaker in target: true in source, false in target",
+		"Namespace payments: pod security enforce is weaker in target: restricted in source, baseline in target",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestDiffPostureStrongerTarget(t *testing.T) {
+	findings := DiffPosture(postureObjects("baseline", false, false), postureObjects("restricted", false, false))
+
+	if len(findings) != 1 || findings[0].Severity != "info" || !strings.Contains(findings[0].Diff[0], "is stronger in target") {
+		t.Errorf("Expected a stricter target to be info, got %+v", findings)
+	}
+}
+
+func TestPodPostureDefaults(t *testing.T) {
+	runAsNonRoot := true
+	settings := podPosture(Corev1.PodSpec{
+		SecurityContext: &Corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot, SeccompProfile: &Corev1.SeccompProfile{Type: Corev1.SeccompProfileTypeRuntimeDefault}},
+		Containers: []Corev1.Container{{Name: "api", SecurityContext: &Corev1.SecurityContext{
+			Capabilities: &Corev1.Capabilities{Add: []Corev1.Capability{"NET_ADMIN", "NET_RAW"}},
+		}}},
+		Volumes: []Corev1.Volume{{Name: "docker", VolumeSource: Corev1.VolumeSource{HostPath: &Corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}},
+	})
+
+	if settings["container api runAsNonRoot"].Value != "true" || settings["container api seccomp profile"].Value != "RuntimeDefault" {
+		t.Errorf("Expected the container to inherit the pod security context, got %+v", settings)
+	}
+	if settings["container api added capabilities"].Value != "NET_ADMIN, NET_RAW" || settings["hostPath volumes"].Value != "/var/run/docker.sock" {
+		t.Errorf("Expected capabilities and hostPath volumes to be listed, got %+v", settings)
+	}
+	if settings["automountServiceAccountToken"].Value != "true" {
+		t.Errorf("Expected the token to be mounted by default, got %+v", settings["automountServiceAccountToken"])
+	}
+}
+
+func TestListSettingsCompareAsSets(t *testing.T) {
+	for _, test := range []struct {
+		source, target []string
+		how            string
+	}{
+		{[]string{"NET_ADMIN", "NET_RAW"}, []string{"SYS_ADMIN"}, "is weaker in target"},
+		{[]string{"NET_ADMIN"}, []string{"NET_ADMIN", "NET_RAW"}, "is weaker in target"},
+		{[]string{"NET_ADMIN", "NET_RAW"}, []string{"NET_RAW"}, "is stronger in target"},
+		{[]string{"NET_ADMIN"}, nil, "is stronger in target"},
+	} {
+		source, target := postureObjects("restricted", false, false), postureObjects("restricted", false, false)
+		for objects, capabilities := range map[*PostureObjects][]string{&source: test.source, &target: test.target} {
+			context := objects.Workloads[0].Template.Spec.Containers[0].SecurityContext
+			context.Capabilities = &Corev1.Capabilities{}
+			for _, capability := range capabilities {
+				context.Capabilities.Add = append(context.Capabilities.Add, Corev1.Capability(capability))
+			}
+		}
+
+		findings := DiffPosture(source, target)
+
+		if len(findings) != 1 || !strings.Contains(findings[0].Diff[0], "added capabilities "+test.how) {
+			t.Errorf("Expected %v then %v to be reported as %q, got %+v", test.source, test.target, test.how, findings)
+		}
+	}
+}
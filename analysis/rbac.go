@@ -0,0 +1,382 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+	"kompare/tools"
+
+	RbacV1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "rbac",
+		Description: "Effective RBAC permissions: what each user, group and ServiceAccount can do",
+		Run:         runRBAC,
+	})
+}
+
+// expandedVerbs is what a "*" verb grants on every resource, on top of its specialVerbs and of the verbs
+// named for the resource in any rule of either cluster.
+var expandedVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+
+// specialVerbs are the verbs that only some resources know of, granted by a "*" verb on them.
+var specialVerbs = map[groupResource][]string{
+	{"rbac.authorization.k8s.io", "clusterroles"}:           {"bind", "escalate"},
+	{"rbac.authorization.k8s.io", "roles"}:                  {"bind", "escalate"},
+	{"", "groups"}:                                          {"impersonate"},
+	{"", "serviceaccounts"}:                                 {"impersonate"},
+	{"", "users"}:                                           {"impersonate"},
+	{"authentication.k8s.io", "uids"}:                       {"impersonate"},
+	{"authentication.k8s.io", "userextras"}:                 {"impersonate"},
+	{"certificates.k8s.io", "signers"}:                      {"approve", "sign"},
+	{"policy", "podsecuritypolicies"}:                       {"use"},
+	{"security.openshift.io", "securitycontextconstraints"}: {"use"},
+}
+
+// clusterScopedResources are the resources a ClusterRoleBinding grants cluster-wide.
+// Every other resource is granted in each namespace, like a RoleBinding in every namespace would.
+var clusterScopedResources = map[groupResource]bool{
+	{"", "namespaces"}: true, {"", "nodes"}: true, {"", "persistentvolumes"}: true,
+	{"", "groups"}: true, {"", "users"}: true,
+	{"admissionregistration.k8s.io", "mutatingwebhookconfigurations"}:   true,
+	{"admissionregistration.k8s.io", "validatingwebhookconfigurations"}: true,
+	{"apiextensions.k8s.io", "customresourcedefinitions"}:               true,
+	{"authentication.k8s.io", "uids"}:                                   true, {"authentication.k8s.io", "userextras"}: true,
+	{"certificates.k8s.io", "certificatesigningrequests"}: true, {"certificates.k8s.io", "signers"}: true,
+	{"policy", "podsecuritypolicies"}: true, {"scheduling.k8s.io", "priorityclasses"}: true,
+	{"rbac.authorization.k8s.io", "clusterrolebindings"}: true, {"rbac.authorization.k8s.io", "clusterroles"}: true,
+	{"storage.k8s.io", "storageclasses"}: true, {"security.openshift.io", "securitycontextconstraints"}: true,
+}
+
+// commonResources are the resources a "*" resource always expands to, on top of the ones named in any rule of either cluster.
+var commonResources = []groupResource{
+	{"", "configmaps"}, {"", "endpoints"}, {"", "events"}, {"", "namespaces"}, {"", "nodes"},
+	{"", "persistentvolumeclaims"}, {"", "persistentvolumes"}, {"", "pods"}, {"", "pods/exec"}, {"", "pods/log"},
+	{"", "secrets"}, {"", "serviceaccounts"}, {"", "services"},
+	{"apps", "daemonsets"}, {"apps", "deployments"}, {"apps", "replicasets"}, {"apps", "statefulsets"},
+	{"batch", "cronjobs"}, {"batch", "jobs"}, {"networking.k8s.io", "ingresses"}, {"networking.k8s.io", "networkpolicies"},
+	{"rbac.authorization.k8s.io", "clusterrolebindings"}, {"rbac.authorization.k8s.io", "clusterroles"},
+	{"rbac.authorization.k8s.io", "rolebindings"}, {"rbac.authorization.k8s.io", "roles"},
+}
+
+type groupResource struct {
+	group, resource string
+}
+
+// clusterScoped tells whether a resource, or the resource of a subresource like "nodes/proxy", is cluster-scoped.
+func (gr groupResource) clusterScoped() bool {
+	resource, _, _ := strings.Cut(gr.resource, "/")
+	return clusterScopedResources[groupResource{gr.group, resource}]
+}
+
+// ruleUniverse is what the wildcards of a rule can stand for.
+type ruleUniverse struct {
+	resources []groupResource
+	// verbs are the verbs named in a rule of either cluster, by resource. Non-resource URLs are
+	// keyed by their path, in the resource field.
+	verbs map[groupResource][]string
+}
+
+// RBACObjects are the RBAC objects of one cluster.
+type RBACObjects struct {
+	Roles               *RbacV1.RoleList
+	RoleBindings        *RbacV1.RoleBindingList
+	ClusterRoles        *RbacV1.ClusterRoleList
+	ClusterRoleBindings *RbacV1.ClusterRoleBindingList
+}
+
+// Permissions maps a subject ("user alice", "group dev-team", "serviceaccount payments/api")
+// to the namespaces it has permissions in ("" for cluster-scoped resources and non-resource URLs) and to the set of permissions there,
+// written like "delete secrets", "get deployments.apps", "get secrets app-token" or "get /healthz".
+type Permissions map[string]map[string]map[string]bool
+
+// listRBAC lists the RBAC objects of a cluster, with Roles and RoleBindings from the namespaces in scope only.
+func listRBAC(cluster compare.Cluster, namespaces []string) (RBACObjects, error) {
+	objects := RBACObjects{Roles: &RbacV1.RoleList{}, RoleBindings: &RbacV1.RoleBindingList{}}
+	for _, namespace := range namespaces {
+		roles, err := query.ListRoles(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Roles.Items = append(objects.Roles.Items, roles.Items...)
+		roleBindings, err := query.ListRoleBindings(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.RoleBindings.Items = append(objects.RoleBindings.Items, roleBindings.Items...)
+	}
+	var err error
+	if objects.ClusterRoles, err = query.ListClusterRoles(cluster.Clientset); err != nil {
+		return objects, err
+	}
+	objects.ClusterRoleBindings, err = query.ListClusterRoleBindings(cluster.Clientset)
+	return objects, err
+}
+
+func runRBAC(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listRBAC(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing RBAC objects in the source cluster: %v", err)
+	}
+	target, err := listRBAC(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing RBAC objects in the target cluster: %v", err)
+	}
+	var findings []DAO.DiffWithName
+	for _, finding := range DiffPermissions(EffectivePermissions(source, target, namespaces), EffectivePermissions(target, source, namespaces)) {
+		if scope.Includes(finding.Namespace) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// EffectivePermissions resolves the bindings of a cluster to subjects, with aggregated ClusterRoles
+// and wildcards expanded. other is the other cluster: the resources and verbs named in its rules are
+// part of what a "*" expands to, so that "*" and an explicit list compare equal.
+// ClusterRoleBindings grant their namespaced resources in each of the namespaces, so that the same
+// permission compares equal whether a RoleBinding or a ClusterRoleBinding grants it.
+func EffectivePermissions(objects, other RBACObjects, namespaces []string) Permissions {
+	universe := newRuleUniverse(objects, other)
+	clusterRoleRules := aggregatedClusterRoleRules(objects.ClusterRoles)
+	roleRules := map[string][]RbacV1.PolicyRule{}
+	if objects.Roles != nil {
+		for _, role := range objects.Roles.Items {
+			roleRules[role.Namespace+"/"+role.Name] = role.Rules
+		}
+	}
+
+	permissions := Permissions{}
+	grant := func(subjects []RbacV1.Subject, bindingNamespace string, namespaces []string, rules []RbacV1.PolicyRule) {
+		for _, subject := range subjects {
+			name := subjectName(subject, bindingNamespace)
+			if permissions[name] == nil {
+				permissions[name] = map[string]map[string]bool{}
+			}
+			for _, namespace := range namespaces {
+				if permissions[name][namespace] == nil {
+					permissions[name][namespace] = map[string]bool{}
+				}
+				for _, rule := range rules {
+					for _, permission := range expandRule(rule, universe, namespace == "") {
+						permissions[name][namespace][permission] = true
+					}
+				}
+			}
+		}
+	}
+	if objects.ClusterRoleBindings != nil {
+		for _, binding := range objects.ClusterRoleBindings.Items {
+			grant(binding.Subjects, "", append([]string{""}, namespaces...), clusterRoleRules[binding.RoleRef.Name])
+		}
+	}
+	if objects.RoleBindings != nil {
+		for _, binding := range objects.RoleBindings.Items {
+			rules := roleRules[binding.Namespace+"/"+binding.RoleRef.Name]
+			if binding.RoleRef.Kind == "ClusterRole" {
+				rules = clusterRoleRules[binding.RoleRef.Name]
+			}
+			grant(binding.Subjects, binding.Namespace, []string{binding.Namespace}, rules)
+		}
+	}
+	return permissions
+}
+
+// DiffPermissions reports every permission a subject gains or loses in the target, one finding per permission.
+func DiffPermissions(source, target Permissions) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	subjects := map[string]bool{}
+	for subject := range source {
+		subjects[subject] = true
+	}
+	for subject := range target {
+		subjects[subject] = true
+	}
+	for _, subject := range sortedKeys(subjects) {
+		namespaces := map[string]bool{}
+		for namespace := range source[subject] {
+			namespaces[namespace] = true
+		}
+		for namespace := range target[subject] {
+			namespaces[namespace] = true
+		}
+		for _, namespace := range sortedKeys(namespaces) {
+			where := "cluster-wide"
+			if namespace != "" {
+				where = "in namespace " + namespace
+			}
+			sourcePermissions, targetPermissions := source[subject][namespace], target[subject][namespace]
+			for _, permission := range sortedKeys(targetPermissions) {
+				if !sourcePermissions[permission] {
+					findings = append(findings, newFinding("rbac", namespace, subject, permission, plugins.SeverityWarning,
+						fmt.Sprintf("%s gains `%s` %s in target", subject, permission, where)))
+				}
+			}
+			for _, permission := range sortedKeys(sourcePermissions) {
+				if !targetPermissions[permission] {
+					findings = append(findings, newFinding("rbac", namespace, subject, permission, plugins.SeverityInfo,
+						fmt.Sprintf("%s loses `%s` %s in target", subject, permission, where)))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// aggregatedClusterRoleRules returns the rules of each ClusterRole, including the rules of the
+// ClusterRoles its aggregation rule selects.
+func aggregatedClusterRoleRules(clusterRoles *RbacV1.ClusterRoleList) map[string][]RbacV1.PolicyRule {
+	rules := map[string][]RbacV1.PolicyRule{}
+	if clusterRoles == nil {
+		return rules
+	}
+	for _, role := range clusterRoles.Items {
+		rules[role.Name] = append(rules[role.Name], role.Rules...)
+		if role.AggregationRule == nil {
+			continue
+		}
+		for _, selector := range role.AggregationRule.ClusterRoleSelectors {
+			selector := selector
+			labelSelector, err := metav1.LabelSelectorAsSelector(&selector)
+			if err != nil {
+				continue
+			}
+			for _, aggregated := range clusterRoles.Items {
+				if aggregated.Name != role.Name && labelSelector.Matches(labelsSet(aggregated.Labels)) {
+					rules[role.Name] = append(rules[role.Name], aggregated.Rules...)
+				}
+			}
+		}
+	}
+	return rules
+}
+
+// expandRule writes a rule as single permissions. Cluster-wide, it writes the permissions on cluster-scoped
+// resources and non-resource URLs; in a namespace, the permissions on namespaced resources.
+func expandRule(rule RbacV1.PolicyRule, universe ruleUniverse, clusterWide bool) []string {
+	var permissions []string
+	if clusterWide {
+		for _, url := range rule.NonResourceURLs {
+			for _, verb := range universe.expandVerbs(rule.Verbs, groupResource{"", url}) {
+				permissions = append(permissions, verb+" "+url)
+			}
+		}
+	}
+	for _, gr := range universe.resources {
+		if !matchesRule(rule, gr) || gr.clusterScoped() != clusterWide {
+			continue
+		}
+		resource := gr.resource
+		if gr.group != "" {
+			resource += "." + gr.group
+		}
+		for _, verb := range universe.expandVerbs(rule.Verbs, gr) {
+			if len(rule.ResourceNames) == 0 {
+				permissions = append(permissions, verb+" "+resource)
+			}
+			for _, name := range rule.ResourceNames {
+				permissions = append(permissions, verb+" "+resource+" "+name)
+			}
+		}
+	}
+	return permissions
+}
+
+// expandVerbs returns the verbs of a rule on a resource, with "*" standing for every verb the resource knows of.
+func (u ruleUniverse) expandVerbs(verbs []string, gr groupResource) []string {
+	if !tools.IsInList("*", verbs) {
+		return verbs
+	}
+	expanded := append([]string(nil), expandedVerbs...)
+	for _, verb := range append(append([]string(nil), specialVerbs[gr]...), u.verbs[gr]...) {
+		if verb != "*" && !tools.IsInList(verb, expanded) {
+			expanded = append(expanded, verb)
+		}
+	}
+	return expanded
+}
+
+func matchesRule(rule RbacV1.PolicyRule, gr groupResource) bool {
+	return (tools.IsInList("*", rule.APIGroups) || tools.IsInList(gr.group, rule.APIGroups)) &&
+		(tools.IsInList("*", rule.Resources) || tools.IsInList(gr.resource, rule.Resources))
+}
+
+// newRuleUniverse lists the resources a "*" can stand for: the common ones, the ones with special verbs and every
+// resource named in a rule of either cluster. It also collects the verbs named for each resource and non-resource URL.
+func newRuleUniverse(clusters ...RBACObjects) ruleUniverse {
+	seen := map[groupResource]bool{}
+	for _, gr := range commonResources {
+		seen[gr] = true
+	}
+	for gr := range specialVerbs {
+		seen[gr] = true
+	}
+	verbs := map[groupResource][]string{}
+	for _, objects := range clusters {
+		var rules []RbacV1.PolicyRule
+		if objects.Roles != nil {
+			for _, role := range objects.Roles.Items {
+				rules = append(rules, role.Rules...)
+			}
+		}
+		if objects.ClusterRoles != nil {
+			for _, role := range objects.ClusterRoles.Items {
+				rules = append(rules, role.Rules...)
+			}
+		}
+		for _, rule := range rules {
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					if group != "*" && resource != "*" {
+						gr := groupResource{group, resource}
+						seen[gr] = true
+						verbs[gr] = append(verbs[gr], rule.Verbs...)
+					}
+				}
+			}
+			for _, url := range rule.NonResourceURLs {
+				gr := groupResource{"", url}
+				verbs[gr] = append(verbs[gr], rule.Verbs...)
+			}
+		}
+	}
+	resources := make([]groupResource, 0, len(seen))
+	for gr := range seen {
+		resources = append(resources, gr)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].group != resources[j].group {
+			return resources[i].group < resources[j].group
+		}
+		return resources[i].resource < resources[j].resource
+	})
+	return ruleUniverse{resources: resources, verbs: verbs}
+}
+
+// subjectName writes a subject the way findings show it. ServiceAccounts default to the binding's namespace.
+func subjectName(subject RbacV1.Subject, bindingNamespace string) string {
+	switch subject.Kind {
+	case RbacV1.ServiceAccountKind:
+		namespace := subject.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		return "serviceaccount " + namespace + "/" + subject.Name
+	case RbacV1.GroupKind:
+		return "group " + subject.Name
+	default:
+		return strings.ToLower(subject.Kind) + " " + subject.Name
+	}
+}
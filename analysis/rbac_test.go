@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	RbacV1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func groupBinding(namespace, group, roleKind, roleName string) RbacV1.RoleBinding {
+	return RbacV1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: group + "-" + roleName, Namespace: namespace},
+		Subjects:   []RbacV1.Subject{{Kind: RbacV1.GroupKind, Name: group}},
+		RoleRef:    RbacV1.RoleRef{Kind: roleKind, Name: roleName},
+	}
+}
+
+func TestEffectivePermissionsDiff(t *testing.T) {
+	source := RBACObjects{
+		Roles: &RbacV1.RoleList{Items: []RbacV1.Role{{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-reader", Namespace: "payments"},
+			Rules:      []RbacV1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}}},
+		}}},
+		RoleBindings: &RbacV1.RoleBindingList{Items: []RbacV1.RoleBinding{groupBinding("payments", "dev-team", "Role", "secret-reader")}},
+	}
+	target := RBACObjects{
+		ClusterRoles: &RbacV1.ClusterRoleList{Items: []RbacV1.ClusterRole{{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-admin"},
+			Rules:      []RbacV1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+		}}},
+		RoleBindings: &RbacV1.RoleBindingList{Items: []RbacV1.RoleBinding{groupBinding("payments", "dev-team", "ClusterRole", "secret-admin")}},
+	}
+
+	findings := DiffPermissions(EffectivePermissions(source, target, []string{"payments"}), EffectivePermissions(target, source, []string{"payments"}))
+
+	lines := map[string]bool{}
+	for _, finding := range findings {
+		lines[finding.Diff[0]] = true
+	}
+	if !lines["group dev-team gains `delete secrets` in namespace payments in target"] {
+		t.Errorf("Expected dev-team to gain delete secrets, got %v", lines)
+	}
+	if lines["group dev-team gains `get secrets` in namespace payments in target"] {
+		t.Errorf("Expected get secrets to be granted in both clusters")
+	}
+	if len(findings) != 6 {
+		t.Errorf("Expected 6 gained verbs, got %d: %v", len(findings), lines)
+	}
+}
+
+func TestAggregatedClusterRoles(t *testing.T) {
+	objects := RBACObjects{
+		ClusterRoles: &RbacV1.ClusterRoleList{Items: []RbacV1.ClusterRole{
+			{
+				ObjectMeta:      metav1.ObjectMeta{Name: "monitoring"},
+				AggregationRule: &RbacV1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"aggregate-to-monitoring": "true"}}}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-reader", Labels: map[string]string{"aggregate-to-monitoring": "true"}},
+				Rules:      []RbacV1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}, {NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}}},
+			},
+		}},
+		ClusterRoleBindings: &RbacV1.ClusterRoleBindingList{Items: []RbacV1.ClusterRoleBinding{{
+			Subjects: []RbacV1.Subject{{Kind: RbacV1.ServiceAccountKind, Name: "prometheus", Namespace: "monitoring"}},
+			RoleRef:  RbacV1.RoleRef{Kind: "ClusterRole", Name: "monitoring"},
+		}}},
+	}
+
+	permissions := EffectivePermissions(objects, RBACObjects{}, []string{"monitoring"})["serviceaccount monitoring/prometheus"]
+
+	if !permissions["monitoring"]["get pods"] || !permissions[""]["get /metrics"] {
+		t.Errorf("Expected the aggregated rules to be granted, got %v", permissions)
+	}
+}
+
+func TestWildcardVerbIncludesSpecialVerbs(t *testing.T) {
+	clusterRole := func(verbs ...string) RBACObjects {
+		return RBACObjects{
+			ClusterRoles: &RbacV1.ClusterRoleList{Items: []RbacV1.ClusterRole{{
+				ObjectMeta: metav1.ObjectMeta{Name: "role-admin"},
+				Rules:      []RbacV1.PolicyRule{{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"roles"}, Verbs: verbs}},
+			}}},
+			RoleBindings: &RbacV1.RoleBindingList{Items: []RbacV1.RoleBinding{groupBinding("payments", "dev-team", "ClusterRole", "role-admin")}},
+		}
+	}
+	source := clusterRole("*")
+	target := clusterRole("get", "list", "watch", "create", "update", "patch", "delete", "deletecollection", "bind", "escalate")
+
+	findings := DiffPermissions(EffectivePermissions(source, target, []string{"payments"}), EffectivePermissions(target, source, []string{"payments"}))
+
+	if len(findings) != 0 {
+		t.Errorf("Expected \"*\" to grant every verb on roles, got %v", findings)
+	}
+}
+
+func TestClusterRoleBindingGrantsInEachNamespace(t *testing.T) {
+	role := &RbacV1.ClusterRoleList{Items: []RbacV1.ClusterRole{{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-reader"},
+		Rules:      []RbacV1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods", "nodes"}, Verbs: []string{"get"}}},
+	}}}
+	source := RBACObjects{
+		ClusterRoles: role,
+		RoleBindings: &RbacV1.RoleBindingList{Items: []RbacV1.RoleBinding{groupBinding("payments", "dev-team", "ClusterRole", "pod-reader")}},
+	}
+	target := RBACObjects{
+		ClusterRoles: role,
+		ClusterRoleBindings: &RbacV1.ClusterRoleBindingList{Items: []RbacV1.ClusterRoleBinding{{
+			Subjects: []RbacV1.Subject{{Kind: RbacV1.GroupKind, Name: "dev-team"}},
+			RoleRef:  RbacV1.RoleRef{Kind: "ClusterRole", Name: "pod-reader"},
+		}}},
+	}
+	namespaces := []string{"payments", "shop"}
+
+	findings := DiffPermissions(EffectivePermissions(source, target, namespaces), EffectivePermissions(target, source, namespaces))
+
+	lines := map[string]bool{}
+	for _, finding := range findings {
+		lines[finding.Diff[0]] = true
+	}
+	want := map[string]bool{
+		"group dev-team gains `get nodes` cluster-wide in target":     true,
+		"group dev-team gains `get pods` in namespace shop in target": true,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected %v, got %v", want, lines)
+	}
+}
+
+func TestScopeIncludes(t *testing.T) {
+	scope := Scope{Namespace: "*-pci"}
+	if !scope.Includes("payments-pci") || scope.Includes("payments") || !scope.Includes("") {
+		t.Errorf("Expected only matching namespaces and cluster-wide findings to be included")
+	}
+}
@@ -0,0 +1,323 @@
+package analysis
+
+import (
+	"fmt"
+
+	"kompare/DAO"
+	"kompare/compare"
+	"kompare/plugins"
+	"kompare/query"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	Corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	RbacV1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func init() {
+	MustRegister(Analysis{
+		Name:        "references",
+		Description: "Reference integrity: references that resolve in source and would be broken in target",
+		Run:         runReferences,
+	})
+}
+
+// ReferenceObjects are the objects of one cluster that refer to others, or are referred to.
+type ReferenceObjects struct {
+	Workloads       []Workload
+	ConfigMaps      *Corev1.ConfigMapList
+	Secrets         *Corev1.SecretList
+	ServiceAccounts *Corev1.ServiceAccountList
+	PVCs            *Corev1.PersistentVolumeClaimList
+	Services        *Corev1.ServiceList
+	Ingresses       *networkingv1.IngressList
+	HPAs            *autoscalingv1.HorizontalPodAutoscalerList
+	Roles           *RbacV1.RoleList
+	RoleBindings    *RbacV1.RoleBindingList
+	ClusterRoles    *RbacV1.ClusterRoleList
+}
+
+// Reference is one object naming another. Kind "Pod" is a Service selector, with the selector as Name.
+type Reference struct {
+	From, FromNamespace string
+	// Via says where in the referring object the reference is, e.g. "envFrom" or "volume config".
+	Via                   string
+	Kind, Namespace, Name string
+	// Optional references do not stop the referring object from working.
+	Optional bool
+}
+
+func (r Reference) target() string {
+	if r.Kind == "Pod" {
+		return "a pod matching " + r.Name + " in namespace " + r.Namespace
+	}
+	if r.Namespace == "" {
+		return r.Kind + " " + r.Name
+	}
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+func listReferenceObjects(cluster compare.Cluster, namespaces []string) (ReferenceObjects, error) {
+	objects := ReferenceObjects{
+		ConfigMaps: &Corev1.ConfigMapList{}, Secrets: &Corev1.SecretList{}, ServiceAccounts: &Corev1.ServiceAccountList{},
+		PVCs: &Corev1.PersistentVolumeClaimList{}, Services: &Corev1.ServiceList{}, Ingresses: &networkingv1.IngressList{},
+		HPAs: &autoscalingv1.HorizontalPodAutoscalerList{}, Roles: &RbacV1.RoleList{}, RoleBindings: &RbacV1.RoleBindingList{},
+	}
+	var err error
+	if objects.Workloads, err = listWorkloads(cluster, namespaces); err != nil {
+		return objects, err
+	}
+	for _, namespace := range namespaces {
+		configMaps, err := query.ListConfigMaps(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.ConfigMaps.Items = append(objects.ConfigMaps.Items, configMaps.Items...)
+		secrets, err := query.ListSecrets(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Secrets.Items = append(objects.Secrets.Items, secrets.Items...)
+		serviceAccounts, err := query.ListServiceAccounts(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.ServiceAccounts.Items = append(objects.ServiceAccounts.Items, serviceAccounts.Items...)
+		pvcs, err := query.ListPersistentVolumeClaims(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.PVCs.Items = append(objects.PVCs.Items, pvcs.Items...)
+		services, err := query.ListServices(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Services.Items = append(objects.Services.Items, services.Items...)
+		ingresses, err := query.ListIngresses(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Ingresses.Items = append(objects.Ingresses.Items, ingresses.Items...)
+		hpas, err := query.ListHPAs(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.HPAs.Items = append(objects.HPAs.Items, hpas.Items...)
+		roles, err := query.ListRoles(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.Roles.Items = append(objects.Roles.Items, roles.Items...)
+		roleBindings, err := query.ListRoleBindings(cluster.Clientset, namespace)
+		if err != nil {
+			return objects, err
+		}
+		objects.RoleBindings.Items = append(objects.RoleBindings.Items, roleBindings.Items...)
+	}
+	objects.ClusterRoles, err = query.ListClusterRoles(cluster.Clientset)
+	return objects, err
+}
+
+func runReferences(scope Scope) ([]DAO.DiffWithName, error) {
+	namespaces, err := scope.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	source, err := listReferenceObjects(scope.Source, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the source cluster: %v", err)
+	}
+	target, err := listReferenceObjects(scope.Target, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects in the target cluster: %v", err)
+	}
+	return DanglingReferences(source, target), nil
+}
+
+// DanglingReferences reports the references of the source objects that resolve in the source
+// and not in the target, with the object that would break.
+func DanglingReferences(source, target ReferenceObjects) []DAO.DiffWithName {
+	var findings []DAO.DiffWithName
+	for _, ref := range References(source) {
+		if !Resolves(source, ref) || Resolves(target, ref) {
+			continue
+		}
+		severity := plugins.SeverityError
+		if ref.Optional {
+			severity = plugins.SeverityWarning
+		}
+		findings = append(findings, newFinding("references", ref.FromNamespace, ref.From, ref.Via+" "+ref.target(), severity,
+			fmt.Sprintf("%s references %s (%s), which is missing in target", ref.From, ref.target(), ref.Via)))
+	}
+	return findings
+}
+
+// References lists what the objects of a cluster refer to.
+func References(objects ReferenceObjects) []Reference {
+	var refs []Reference
+	for _, workload := range objects.Workloads {
+		refs = append(refs, podTemplateReferences(workload)...)
+	}
+	if objects.Ingresses != nil {
+		for _, ingress := range objects.Ingresses.Items {
+			from := "Ingress " + ingress.Namespace + "/" + ingress.Name
+			service := func(backend *networkingv1.IngressBackend, via string) {
+				if backend != nil && backend.Service != nil {
+					refs = append(refs, Reference{From: from, FromNamespace: ingress.Namespace, Via: via, Kind: "Service", Namespace: ingress.Namespace, Name: backend.Service.Name})
+				}
+			}
+			service(ingress.Spec.DefaultBackend, "default backend")
+			for _, rule := range ingress.Spec.Rules {
+				if rule.HTTP == nil {
+					continue
+				}
+				for _, path := range rule.HTTP.Paths {
+					path := path
+					service(&path.Backend, "backend "+rule.Host+path.Path)
+				}
+			}
+			for _, tls := range ingress.Spec.TLS {
+				if tls.SecretName != "" {
+					refs = append(refs, Reference{From: from, FromNamespace: ingress.Namespace, Via: "tls", Kind: "Secret", Namespace: ingress.Namespace, Name: tls.SecretName})
+				}
+			}
+		}
+	}
+	if objects.HPAs != nil {
+		for _, hpa := range objects.HPAs.Items {
+			refs = append(refs, Reference{From: "HorizontalPodAutoscaler " + hpa.Namespace + "/" + hpa.Name, FromNamespace: hpa.Namespace, Via: "scaleTargetRef",
+				Kind: hpa.Spec.ScaleTargetRef.Kind, Namespace: hpa.Namespace, Name: hpa.Spec.ScaleTargetRef.Name})
+		}
+	}
+	if objects.RoleBindings != nil {
+		for _, binding := range objects.RoleBindings.Items {
+			ref := Reference{From: "RoleBinding " + binding.Namespace + "/" + binding.Name, FromNamespace: binding.Namespace, Via: "roleRef", Kind: binding.RoleRef.Kind, Name: binding.RoleRef.Name}
+			if ref.Kind == "Role" {
+				ref.Namespace = binding.Namespace
+			}
+			refs = append(refs, ref)
+		}
+	}
+	if objects.Services != nil {
+		for _, service := range objects.Services.Items {
+			if len(service.Spec.Selector) == 0 {
+				continue
+			}
+			refs = append(refs, Reference{From: "Service " + service.Namespace + "/" + service.Name, FromNamespace: service.Namespace, Via: "selector",
+				Kind: "Pod", Namespace: service.Namespace, Name: labels.Set(service.Spec.Selector).String()})
+		}
+	}
+	return refs
+}
+
+// podTemplateReferences lists the ConfigMaps, Secrets, ServiceAccount and PVCs a workload's pods need.
+func podTemplateReferences(workload Workload) []Reference {
+	var refs []Reference
+	add := func(via, kind, name string, optional *bool) {
+		if name != "" {
+			refs = append(refs, Reference{From: workload.ID(), FromNamespace: workload.Namespace, Via: via, Kind: kind, Namespace: workload.Namespace, Name: name, Optional: optional != nil && *optional})
+		}
+	}
+	spec := workload.Template.Spec
+	if spec.ServiceAccountName != "" && spec.ServiceAccountName != "default" {
+		add("serviceAccountName", "ServiceAccount", spec.ServiceAccountName, nil)
+	}
+	for _, pullSecret := range spec.ImagePullSecrets {
+		add("imagePullSecrets", "Secret", pullSecret.Name, nil)
+	}
+	for _, volume := range spec.Volumes {
+		via := "volume " + volume.Name
+		if volume.ConfigMap != nil {
+			add(via, "ConfigMap", volume.ConfigMap.Name, volume.ConfigMap.Optional)
+		}
+		if volume.Secret != nil {
+			add(via, "Secret", volume.Secret.SecretName, volume.Secret.Optional)
+		}
+		if volume.PersistentVolumeClaim != nil {
+			add(via, "PersistentVolumeClaim", volume.PersistentVolumeClaim.ClaimName, nil)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(via, "ConfigMap", source.ConfigMap.Name, source.ConfigMap.Optional)
+				}
+				if source.Secret != nil {
+					add(via, "Secret", source.Secret.Name, source.Secret.Optional)
+				}
+			}
+		}
+	}
+	for _, container := range append(append([]Corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add("envFrom", "ConfigMap", envFrom.ConfigMapRef.Name, envFrom.ConfigMapRef.Optional)
+			}
+			if envFrom.SecretRef != nil {
+				add("envFrom", "Secret", envFrom.SecretRef.Name, envFrom.SecretRef.Optional)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				add("env "+env.Name, "ConfigMap", ref.Name, ref.Optional)
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				add("env "+env.Name, "Secret", ref.Name, ref.Optional)
+			}
+		}
+	}
+	return refs
+}
+
+// Resolves reports whether a reference names an existing object. References to kinds kompare
+// does not list are assumed to resolve.
+func Resolves(objects ReferenceObjects, ref Reference) bool {
+	switch ref.Kind {
+	case "ConfigMap":
+		return objects.ConfigMaps != nil && hasItem(objects.ConfigMaps.Items, ref, func(o Corev1.ConfigMap) (string, string) { return o.Namespace, o.Name })
+	case "Secret":
+		return objects.Secrets != nil && hasItem(objects.Secrets.Items, ref, func(o Corev1.Secret) (string, string) { return o.Namespace, o.Name })
+	case "ServiceAccount":
+		return objects.ServiceAccounts != nil && hasItem(objects.ServiceAccounts.Items, ref, func(o Corev1.ServiceAccount) (string, string) { return o.Namespace, o.Name })
+	case "PersistentVolumeClaim":
+		return objects.PVCs != nil && hasItem(objects.PVCs.Items, ref, func(o Corev1.PersistentVolumeClaim) (string, string) { return o.Namespace, o.Name })
+	case "Service":
+		return objects.Services != nil && hasItem(objects.Services.Items, ref, func(o Corev1.Service) (string, string) { return o.Namespace, o.Name })
+	case "Role":
+		return objects.Roles != nil && hasItem(objects.Roles.Items, ref, func(o RbacV1.Role) (string, string) { return o.Namespace, o.Name })
+	case "ClusterRole":
+		return objects.ClusterRoles != nil && hasItem(objects.ClusterRoles.Items, ref, func(o RbacV1.ClusterRole) (string, string) { return "", o.Name })
+	case "Pod":
+		selector, err := labels.Parse(ref.Name)
+		if err != nil {
+			return true
+		}
+		for _, workload := range objects.Workloads {
+			if workload.Namespace == ref.Namespace && selector.Matches(labels.Set(workload.Template.Labels)) {
+				return true
+			}
+		}
+		return false
+	}
+	if !isWorkloadKind(ref.Kind) {
+		return true
+	}
+	for _, workload := range objects.Workloads {
+		if workload.Kind == ref.Kind && workload.Namespace == ref.Namespace && workload.Name == ref.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasItem[T any](items []T, ref Reference, key func(T) (string, string)) bool {
+	for _, item := range items {
+		if namespace, name := key(item); namespace == ref.Namespace && name == ref.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"testing"
+
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDanglingReferences(t *testing.T) {
+	api := workload("payments", "api", map[string]string{"app": "api"})
+	api.Template.Spec.Containers[0].EnvFrom = []Corev1.EnvFromSource{{SecretRef: &Corev1.SecretEnvSource{LocalObjectReference: Corev1.LocalObjectReference{Name: "api-env"}}}}
+	optional := true
+	api.Template.Spec.Volumes = []Corev1.Volume{{Name: "flags", VolumeSource: Corev1.VolumeSource{ConfigMap: &Corev1.ConfigMapVolumeSource{
+		LocalObjectReference: Corev1.LocalObjectReference{Name: "flags"}, Optional: &optional,
+	}}}}
+	source := ReferenceObjects{
+		Workloads:  []Workload{api},
+		Secrets:    &Corev1.SecretList{Items: []Corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "api-env", Namespace: "payments"}}}},
+		ConfigMaps: &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "flags", Namespace: "payments"}}}},
+		Services: &Corev1.ServiceList{Items: []Corev1.Service{{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "payments"},
+			Spec:       Corev1.ServiceSpec{Selector: map[string]string{"app": "api"}},
+		}}},
+	}
+	renamed := workload("payments", "api", map[string]string{"app": "api-v2"})
+	target := ReferenceObjects{
+		Workloads: []Workload{renamed},
+		Services:  source.Services,
+	}
+
+	findings := DanglingReferences(source, target)
+
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %+v", findings)
+	}
+	expected := []struct{ severity, line string }{
+		{"error", "Deployment payments/api references Secret payments/api-env (envFrom), which is missing in target"},
+		{"warning", "Deployment payments/api references ConfigMap payments/flags (volume flags), which is missing in target"},
+		{"error", "Service payments/api references a pod matching app=api in namespace payments (selector), which is missing in target"},
+	}
+	lines := map[string]string{}
+	for _, finding := range findings {
+		lines[finding.Diff[0]] = finding.Severity
+	}
+	for _, e := range expected {
+		if lines[e.line] != e.severity {
+			t.Errorf("Expected %q with severity %s, got %v", e.line, e.severity, lines)
+		}
+	}
+}
+
+func TestResolvesUnknownKinds(t *testing.T) {
+	if !Resolves(ReferenceObjects{}, Reference{Kind: "Rollout", Namespace: "payments", Name: "api"}) {
+		t.Errorf("Expected references to kinds that are not listed to resolve")
+	}
+	if Resolves(ReferenceObjects{}, Reference{Kind: "Deployment", Namespace: "payments", Name: "api"}) {
+		t.Errorf("Expected a reference to a missing Deployment not to resolve")
+	}
+}
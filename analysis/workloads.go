@@ -0,0 +1,89 @@
+package analysis
+
+import (
+	"kompare/compare"
+	"kompare/query"
+	"kompare/tools"
+
+	Corev1 "k8s.io/api/core/v1"
+)
+
+// Workload is anything that runs pods from a pod template.
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Replicas is the desired number of pods, 1 for kinds without replicas.
+	Replicas int32
+	Template Corev1.PodTemplateSpec
+}
+
+// ID names a workload in findings, e.g. "Deployment payments/api".
+func (w Workload) ID() string {
+	return w.Kind + " " + w.Namespace + "/" + w.Name
+}
+
+// workloadKinds are the kinds listWorkloads lists.
+var workloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "CronJob"}
+
+func isWorkloadKind(kind string) bool {
+	return tools.IsInList(kind, workloadKinds)
+}
+
+// listWorkloads lists the workloads of the given namespaces.
+func listWorkloads(cluster compare.Cluster, namespaces []string) ([]Workload, error) {
+	var workloads []Workload
+	for _, namespace := range namespaces {
+		deployments, err := query.ListDeployments(cluster.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, deployment := range deployments.Items {
+			replicas := int32(1)
+			if deployment.Spec.Replicas != nil {
+				replicas = *deployment.Spec.Replicas
+			}
+			workloads = append(workloads, Workload{Kind: "Deployment", Namespace: deployment.Namespace, Name: deployment.Name, Replicas: replicas, Template: deployment.Spec.Template})
+		}
+		statefulSets, err := query.ListStatefulSets(cluster.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, statefulSet := range statefulSets.Items {
+			replicas := int32(1)
+			if statefulSet.Spec.Replicas != nil {
+				replicas = *statefulSet.Spec.Replicas
+			}
+			workloads = append(workloads, Workload{Kind: "StatefulSet", Namespace: statefulSet.Namespace, Name: statefulSet.Name, Replicas: replicas, Template: statefulSet.Spec.Template})
+		}
+		daemonSets, err := query.ListDaemonSets(cluster.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, daemonSet := range daemonSets.Items {
+			// One pod per node; the number of nodes is not known here.
+			workloads = append(workloads, Workload{Kind: "DaemonSet", Namespace: daemonSet.Namespace, Name: daemonSet.Name, Replicas: 1, Template: daemonSet.Spec.Template})
+		}
+		cronJobs, err := query.ListCronJobs(cluster.Clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, cronJob := range cronJobs.Items {
+			workloads = append(workloads, Workload{Kind: "CronJob", Namespace: cronJob.Namespace, Name: cronJob.Name, Replicas: 1, Template: cronJob.Spec.JobTemplate.Spec.Template})
+		}
+	}
+	return workloads, nil
+}
+
+// allNamespaces returns the names of every namespace of a cluster.
+func allNamespaces(cluster compare.Cluster) (*Corev1.NamespaceList, []string, error) {
+	list, err := query.ListNameSpaces(cluster.Clientset)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return list, names, nil
+}
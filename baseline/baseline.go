@@ -0,0 +1,187 @@
+// Package baseline records accepted findings so that later runs only report new drift.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"kompare/DAO"
+)
+
+// DefaultFile is where "kompare baseline accept" writes when --baseline is not given.
+const DefaultFile = "kompare-baseline.json"
+
+// Entry is one accepted finding. Its ID only depends on where the finding is, not on the values
+// compared, so an accepted difference stays accepted when one of its values changes again.
+type Entry struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+}
+
+type file struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Baseline is the set of accepted findings plus the findings seen during the current run.
+type Baseline struct {
+	mu       sync.Mutex
+	accepted map[string]Entry
+	seen     map[string]Entry
+	// compared are the namespace patterns compared during the run, by kind.
+	compared map[string][]string
+}
+
+// New returns an empty baseline; every finding is new.
+func New() *Baseline {
+	return &Baseline{accepted: map[string]Entry{}, seen: map[string]Entry{}, compared: map[string][]string{}}
+}
+
+// Load reads a baseline file written by Save.
+func Load(path string) (*Baseline, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline %s: %v", path, err)
+	}
+	var f file
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %v", path, err)
+	}
+	b := New()
+	for _, entry := range f.Entries {
+		b.accepted[entry.ID] = entry
+	}
+	return b, nil
+}
+
+// Save writes entries to a baseline file, sorted by ID so the file diffs well in version control.
+func Save(path string, entries []Entry) error {
+	sorted := append([]Entry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	content, err := json.MarshalIndent(file{Entries: sorted}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// Entries returns one entry per difference line of a finding.
+func Entries(diff DAO.DiffWithName) []Entry {
+	var entries []Entry
+	for _, line := range diff.Diff {
+		path := diff.PropertyName
+		if linePath := diffLinePath(line); linePath != "" {
+			path += "." + linePath
+		}
+		if path == "" {
+			// A finding without a property, like a plugin message without a path, is told apart by its text.
+			path = "#" + line
+		}
+		entries = append(entries, Entry{
+			ID:        strings.Join([]string{diff.Kind, diff.Namespace, diff.Name, path}, "/"),
+			Kind:      diff.Kind,
+			Namespace: diff.Namespace,
+			Name:      diff.Name,
+			Path:      path,
+		})
+	}
+	return entries
+}
+
+// diffLinePath returns the field path of a go-test/deep line like "map[app]: a != b", or "" for "a != b".
+func diffLinePath(line string) string {
+	colon := strings.Index(line, ": ")
+	different := strings.Index(line, " != ")
+	if colon < 0 || different < 0 || colon > different {
+		return ""
+	}
+	return line[:colon]
+}
+
+// Filter records every finding as seen and returns the findings with the difference lines
+// the baseline does not accept. Findings left without lines are dropped, findings that had none are kept.
+func (b *Baseline) Filter(diffs []DAO.DiffWithName) []DAO.DiffWithName {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var kept []DAO.DiffWithName
+	for _, diff := range diffs {
+		if len(diff.Diff) == 0 {
+			kept = append(kept, diff)
+			continue
+		}
+		var lines []string
+		for i, entry := range Entries(diff) {
+			b.seen[entry.ID] = entry
+			if _, accepted := b.accepted[entry.ID]; !accepted {
+				lines = append(lines, diff.Diff[i])
+			}
+		}
+		if len(lines) > 0 {
+			diff.Diff = lines
+			kept = append(kept, diff)
+		}
+	}
+	return kept
+}
+
+// Seen returns the findings filtered so far, accepted or not, sorted by ID.
+func (b *Baseline) Seen() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sortedEntries(b.seen, nil)
+}
+
+// Compared records that a kind was compared in a namespace during the run: a namespace name, "" for
+// cluster-scoped objects, or a wildcard pattern like "*" for the analyses covering several namespaces.
+func (b *Baseline) Compared(kind, namespace string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pattern := range b.compared[kind] {
+		if pattern == namespace {
+			return
+		}
+	}
+	b.compared[kind] = append(b.compared[kind], namespace)
+}
+
+// Disappeared returns the accepted findings that were not seen during the run, sorted by ID.
+// Only the findings of the kinds and namespaces compared during the run can disappear, so that
+// a run narrowed with -n or -i does not report the rest of the baseline.
+func (b *Baseline) Disappeared() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var disappeared []Entry
+	for _, entry := range sortedEntries(b.accepted, b.seen) {
+		if b.inScope(entry) {
+			disappeared = append(disappeared, entry)
+		}
+	}
+	return disappeared
+}
+
+func (b *Baseline) inScope(entry Entry) bool {
+	for _, pattern := range b.compared[entry.Kind] {
+		if match, err := filepath.Match(pattern, entry.Namespace); err == nil && match {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedEntries(entries, except map[string]Entry) []Entry {
+	var sorted []Entry
+	for id, entry := range entries {
+		if _, found := except[id]; !found {
+			sorted = append(sorted, entry)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
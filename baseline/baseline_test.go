@@ -0,0 +1,84 @@
+package baseline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"kompare/DAO"
+)
+
+func finding(name string, lines ...string) DAO.DiffWithName {
+	return DAO.DiffWithName{Kind: "deployment", Namespace: "payments", Name: name, PropertyName: "Spec", Diff: lines}
+}
+
+func TestEntriesUseStableIDs(t *testing.T) {
+	entries := Entries(finding("web", "Replicas: 3 != 5", "7 != 8"))
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID != "deployment/payments/web/Spec.Replicas" {
+		t.Errorf("Unexpected ID %q", entries[0].ID)
+	}
+	if entries[1].ID != "deployment/payments/web/Spec" {
+		t.Errorf("Unexpected ID %q", entries[1].ID)
+	}
+}
+
+func TestAcceptThenFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	recording := New()
+	recording.Filter([]DAO.DiffWithName{finding("web", "Replicas: 3 != 5"), finding("gone", "Replicas: 1 != 2")})
+	if err := Save(path, recording.Seen()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	b, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	kept := b.Filter([]DAO.DiffWithName{
+		finding("web", "Replicas: 3 != 6", "Paused: false != true"),
+		finding("new"),
+	})
+	b.Compared("deployment", "payments")
+
+	if len(kept) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", kept)
+	}
+	if len(kept[0].Diff) != 1 || kept[0].Diff[0] != "Paused: false != true" {
+		t.Errorf("Expected only the new line of 'web', got %v", kept[0].Diff)
+	}
+	disappeared := b.Disappeared()
+	if len(disappeared) != 1 || disappeared[0].Name != "gone" {
+		t.Errorf("Expected 'gone' to have disappeared, got %+v", disappeared)
+	}
+}
+
+func TestPathlessLinesAreToldApart(t *testing.T) {
+	entries := Entries(DAO.DiffWithName{Kind: "images", Namespace: "payments", Name: "web", Diff: []string{"tag differs", "digest differs"}})
+
+	if len(entries) != 2 || entries[0].ID == entries[1].ID {
+		t.Errorf("Expected two different IDs, got %+v", entries)
+	}
+}
+
+func TestDisappearedOnlyInComparedScope(t *testing.T) {
+	recording := New()
+	recording.Filter([]DAO.DiffWithName{
+		finding("web", "Replicas: 3 != 5"),
+		{Kind: "deployment", Namespace: "shop", Name: "cart", PropertyName: "Spec", Diff: []string{"Replicas: 1 != 2"}},
+		{Kind: "namespace", Name: "payments", PropertyName: "Labels", Diff: []string{"map[team]: a != b"}},
+		{Kind: "rbac", Namespace: "payments", Name: "ci", PropertyName: "verbs", Diff: []string{"delete only in the target cluster"}},
+	})
+	b := New()
+	b.accepted = recording.seen
+
+	b.Compared("deployment", "payments")
+	b.Compared("rbac", "pay*")
+	disappeared := b.Disappeared()
+
+	if len(disappeared) != 2 || disappeared[0].Name != "web" || disappeared[1].Name != "ci" {
+		t.Errorf("Expected only the findings of the compared kinds and namespaces, got %+v", disappeared)
+	}
+}
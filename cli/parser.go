@@ -5,6 +5,8 @@ import (
 	"kompare/tools"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/akamensky/argparse"
 )
@@ -13,6 +15,24 @@ type ArgumentsReceived struct {
 	KubeconfigFile, SourceClusterContext, TargetClusterContext, NamespaceName, FiltersForObject, Include, Exclude *string
 	VerboseDiffs                                                                                                  *int
 	FileOutput                                                                                                    *string
+	NoSuppress                                                                                                    *bool
+	PluginsDir                                                                                                    *string
+	Baseline                                                                                                      *string
+	BaselineAccept                                                                                                *bool
+	Analyze                                                                                                       *string
+	Contexts                                                                                                      *string
+	Fleet                                                                                                         *bool
+	Golden                                                                                                        *string
+	RegistryMirrors                                                                                               *string
+	ImageDigests                                                                                                  *bool
+	ImageInventory                                                                                                *bool
+	Prices                                                                                                        *string
+	Settle                                                                                                        *string
+	Repeat                                                                                                        *int
+	Interval                                                                                                      *string
+	State                                                                                                         *string
+	Lint                                                                                                          *string
+	ManagedAnnotations                                                                                            *string
 	Err                                                                                                           error
 }
 type ArgumentsReceivedValidated struct {
@@ -20,6 +40,24 @@ type ArgumentsReceivedValidated struct {
 	Include, Exclude                                                                            []string
 	VerboseDiffs                                                                                int
 	FileOutput                                                                                  string
+	NoSuppress                                                                                  bool
+	PluginsDir                                                                                  string
+	Baseline                                                                                    string
+	BaselineAccept                                                                              bool
+	Analyze                                                                                     []string
+	Contexts                                                                                    []string
+	Fleet                                                                                       bool
+	Golden                                                                                      string
+	RegistryMirrors                                                                             []string
+	ImageDigests                                                                                bool
+	ImageInventory                                                                              bool
+	Prices                                                                                      string
+	Settle                                                                                      SettleOptions
+	Repeat                                                                                      int
+	Interval                                                                                    time.Duration
+	State                                                                                       string
+	Lint                                                                                        string
+	ManagedAnnotations                                                                          []string
 	Err                                                                                         error
 }
 
@@ -28,12 +66,32 @@ type ArgumentsReceivedValidated struct {
 // The flags and options include:
 //   - 'c' or 'conf' flag for specifying the path to the kubeconfig file (optional).
 //   - 's' or 'src' flag for specifying the source cluster's context (optional).
-//   - 't' or 'target' flag for specifying the target cluster's context (required).
+//   - 't' or 'target' flag for specifying the target cluster's context (required, unless 'contexts' is given).
 //   - 'v' or 'verbose' flag for enabling verbose mode to show all diffs (optional).
 //   - 'i' or 'include' flag for specifying a list of Kubernetes objects to include (optional).
 //   - 'e' or 'exclude' flag for specifying a list of Kubernetes objects to exclude (optional).
 //   - 'n' or 'namespace' flag for specifying the namespace to be copied (optional, defaults to 'default').
 //   - 'f' or 'filter' flag for specifying what parts of the object to compare (optional).
+//   - 'no-suppress' flag for reporting differences the built-in suppression rules would ignore (optional).
+//   - 'managed-annotations' flag for a comma separated list of annotations written by operators, ignored like the built-in controller ones (optional).
+//   - 'plugins-dir' flag for a directory searched for kompare-compare-<kind> plugins before PATH (optional).
+//   - 'baseline' flag for a file of accepted findings; only findings not in it are reported (optional).
+//   - 'analyze' flag for a comma separated list of analyses to run instead of the per-kind comparison (optional).
+//   - 'contexts' flag for a comma separated list of two or more contexts compared all at once, instead of -s and -t (optional).
+//   - 'fleet' flag for reporting, with 'contexts', only the clusters that deviate from the majority (optional).
+//   - 'golden' flag for a context of 'contexts' the other clusters are compared to; implies 'fleet' (optional).
+//   - 'registry-mirrors' flag for a comma separated list of mirror=registry prefixes the images analysis treats as the same (optional).
+//   - 'image-digests' flag for the images analysis to also read the digests of running pods (optional).
+//   - 'image-inventory' flag for the images analysis to also print the image of every container in each cluster (optional).
+//   - 'prices' flag for a JSON price table per CPU and GiB of memory, used by the capacity analysis (optional).
+//   - 'settle' flag for a comma separated list of in-flight objects to leave out of the comparison, see ParseSettle (optional).
+//   - 'repeat' flag for the number of times the comparison runs, to classify findings as persistent, transient or flapping (optional).
+//   - 'interval' flag for the delay between two runs of 'repeat', defaults to 30s (optional).
+//   - 'state' flag for a file remembering the objects compared, so that the next run only diffs the ones that changed (optional).
+//   - 'lint' flag for a file of convention rules, e.g. required labels, checked on the objects of every cluster compared (optional).
+//
+// Running "kompare baseline accept [options]" compares the clusters as usual and writes
+// every finding to the --baseline file instead of filtering them.
 //
 // If an error occurs during parsing, it prints the error and usage information.
 // The function returns a struct containing validated arguments.
@@ -42,14 +100,68 @@ func PaserReader() ArgumentsReceivedValidated {
 	parser := argparse.NewParser("print", "Prints provided string to stdout")
 	kubeconfigFile := parser.String("c", "conf", &argparse.Options{Required: false, Help: "Path to the clusters kubeconfig; assume ~/.kube/config if not provided"})
 	sourceClusterContext := parser.String("s", "src", &argparse.Options{Required: false, Help: "The Source cluster's context. Origin cluster in the comparison (LHS-left hand side)"})
-	targetClusterContext := parser.String("t", "target", &argparse.Options{Required: true, Help: "*The target cluster's context (Required). Cluster used as destination or consequent (RHS - Right hand side)"})
+	targetClusterContext := parser.String("t", "target", &argparse.Options{Required: false, Help: "*The target cluster's context (Required, unless --contexts is given). Cluster used as destination or consequent (RHS - Right hand side)"})
 	verboseDiffs := parser.FlagCounter("v", "verbose", &argparse.Options{Help: "-v lists the differences and -vv just shows all the diffs too."})
 	IncludeK8sObjects := parser.String("i", "include", &argparse.Options{Help: "List of kubernetes objects names to include, this should be an element or a comma separated list."})
 	Excludek8sObjects := parser.String("e", "exclude", &argparse.Options{Help: "List of kubernetes objects to include, this should be an element or a comma separated list."})
 	namespaceName := parser.String("n", "namespace", &argparse.Options{Help: "Namespace that needs to be copied. defaults to 'default' namespace. The option also accepts wilcard matching of namespace. E.G.: '*-pci' would match any namespace that ends with -pci. Notice that the '' might be required in some consoles like iterm"})
 	filtersForObject := parser.String("f", "filter", &argparse.Options{Help: "Filter what parts of the object I want to compare. must be used together with -i option to apply to that type of objects"})
 	fileOutput := parser.String("l", "file", &argparse.Options{Required: false, Help: "Save the output to a file. If not provided, the output will be printed to the console."})
-	err := parser.Parse(os.Args)
+	noSuppress := parser.Flag("", "no-suppress", &argparse.Options{Help: "Report every difference, including the ones explained by other objects (e.g. replicas managed by an HPA in both clusters)."})
+	managedAnnotations := parser.String("", "managed-annotations", &argparse.Options{Help: "Comma separated list of annotations written by operators or controllers, ignored like the built-in controller-owned ones, e.g. argocd.argoproj.io/tracking-id. End a key with * to ignore every annotation starting with it."})
+	pluginsDir := parser.String("", "plugins-dir", &argparse.Options{Help: "Directory searched for kompare-compare-<kind> comparator plugins before the PATH."})
+	baselineFile := parser.String("", "baseline", &argparse.Options{Help: "File of accepted findings. Only findings not in it, and accepted ones that disappeared, are reported. Written by 'kompare baseline accept'."})
+	analyze := parser.String("", "analyze", &argparse.Options{Help: "Comma separated list of analyses to run instead of comparing objects kind by kind, e.g. rbac."})
+	contexts := parser.String("", "contexts", &argparse.Options{Help: "Comma separated list of two or more contexts to compare all at once, e.g. eu-west-1,eu-west-2,us-east-1. Replaces -s and -t."})
+	fleet := parser.Flag("", "fleet", &argparse.Options{Help: "With --contexts, report only the clusters that deviate from the value most clusters share, ranked by deviations."})
+	golden := parser.String("", "golden", &argparse.Options{Help: "With --contexts, the context every other cluster is compared to. Implies --fleet."})
+	registryMirrors := parser.String("", "registry-mirrors", &argparse.Options{Help: "Comma separated list of mirror=registry image prefixes the images analysis treats as the same, e.g. mirror.example.com/dockerhub=docker.io."})
+	prices := parser.String("", "prices", &argparse.Options{Help: "JSON file with the price of a CPU and of a GiB of memory, e.g. {\"cpu\": 25, \"memoryGiB\": 3.5}, for the capacity analysis to show cost deltas."})
+	settle := parser.String("", "settle", &argparse.Options{Help: "Comma separated list of in-flight objects to leave out of the comparison: age=<duration> for objects younger than that, deleting for objects being deleted, reconcile for objects their controller has not caught up with. Add recheck=<duration> to look at them again after a delay, and mark to report them as info instead of skipping them. E.g. age=2m,deleting,reconcile,recheck=30s."})
+	repeat := parser.Int("", "repeat", &argparse.Options{Default: 1, Help: "Run the comparison this many times and classify every finding as persistent, transient or flapping."})
+	interval := parser.String("", "interval", &argparse.Options{Default: "30s", Help: "Delay between two runs of --repeat, e.g. 30s or 5m."})
+	stateFile := parser.String("", "state", &argparse.Options{Help: "File remembering the resourceVersion and fingerprint of every object compared and the findings. The next run only diffs the objects that changed and reports the new findings."})
+	lintRules := parser.String("", "lint", &argparse.Options{Help: "YAML or JSON file of rules the objects of every cluster compared must follow: required labels and annotations, and a name pattern, per kind. Violations are reported per cluster."})
+	imageDigests := parser.Flag("", "image-digests", &argparse.Options{Help: "Make the images analysis also compare the digests running pods resolved their images to."})
+	imageInventory := parser.Flag("", "image-inventory", &argparse.Options{Help: "Make the images analysis also print the image, tag and digest of every container of every workload in each cluster."})
+	osArgs, baselineAccept := splitBaselineCommand(os.Args)
+	err := parser.Parse(osArgs)
+	if err == nil && *targetClusterContext == "" && len(tools.ParseCommaSeparateList(*contexts)) < 2 {
+		err = fmt.Errorf("[-t|--target] is required, unless --contexts lists at least two contexts")
+	}
+	if err == nil && *contexts != "" && (*sourceClusterContext != "" || *targetClusterContext != "" || *baselineFile != "" || baselineAccept || *analyze != "" || *pluginsDir != "") {
+		err = fmt.Errorf("[--contexts] cannot be used with -s, -t, --baseline, baseline accept, --analyze or --plugins-dir")
+	}
+	if err == nil && *fleet && *contexts == "" {
+		err = fmt.Errorf("[--fleet] needs --contexts")
+	}
+	if err == nil && *golden != "" && !tools.IsInList(*golden, tools.ParseCommaSeparateList(*contexts)) {
+		err = fmt.Errorf("[--golden] must be one of the --contexts")
+	}
+	if err == nil {
+		if parsed, settleErr := ParseSettle(*settle); settleErr != nil {
+			err = fmt.Errorf("[--settle] %v", settleErr)
+		} else if *contexts != "" && (parsed.Mark || parsed.Recheck != 0) {
+			err = fmt.Errorf("[--settle] mark and recheck cannot be used with --contexts")
+		}
+	}
+	if err == nil && *repeat < 1 {
+		err = fmt.Errorf("[--repeat] must be at least 1")
+	}
+	if err == nil && *repeat > 1 && *contexts != "" {
+		err = fmt.Errorf("[--repeat] cannot be used with --contexts")
+	}
+	if err == nil && *stateFile != "" && (*contexts != "" || *analyze != "" || *repeat > 1) {
+		err = fmt.Errorf("[--state] cannot be used with --contexts, --analyze or --repeat")
+	}
+	if err == nil && *lintRules != "" && *analyze != "" {
+		err = fmt.Errorf("[--lint] cannot be used with --analyze")
+	}
+	if err == nil {
+		if parsed, intervalErr := time.ParseDuration(*interval); intervalErr != nil || parsed < 0 {
+			err = fmt.Errorf("[--interval] needs a duration like 30s or 5m, got %q", *interval)
+		}
+	}
 	if err != nil {
 		// In case of error print error and print usage
 		// This can also be done by passing -h or --help flags
@@ -76,6 +188,24 @@ func PaserReader() ArgumentsReceivedValidated {
 		FiltersForObject:     filtersForObject,
 		VerboseDiffs:         verboseDiffs,
 		FileOutput:           fileOutput,
+		NoSuppress:           noSuppress,
+		PluginsDir:           pluginsDir,
+		Baseline:             baselineFile,
+		BaselineAccept:       &baselineAccept,
+		Analyze:              analyze,
+		Contexts:             contexts,
+		Fleet:                fleet,
+		Golden:               golden,
+		RegistryMirrors:      registryMirrors,
+		ImageDigests:         imageDigests,
+		ImageInventory:       imageInventory,
+		Prices:               prices,
+		Settle:               settle,
+		Repeat:               repeat,
+		Interval:             interval,
+		State:                stateFile,
+		Lint:                 lintRules,
+		ManagedAnnotations:   managedAnnotations,
 		Err:                  err}
 	ArgumentsReceivedValidated := ValidateParametersFromParserArgs(TheArgs)
 	return ArgumentsReceivedValidated
@@ -94,12 +224,17 @@ func ValidateParametersFromParserArgs(TheArgs ArgumentsReceived) ArgumentsReceiv
 	var strSourceClusterContext, strTargetClusterContext, strNamespaceName string
 	strSourceClusterContext = *TheArgs.SourceClusterContext
 	strTargetClusterContext = *TheArgs.TargetClusterContext
-	if strSourceClusterContext == "" {
+	contextsList := tools.ParseCommaSeparateList(stringValue(TheArgs.Contexts))
+	if len(contextsList) != 0 {
+		fmt.Printf("We will compare the kubeconfig contexts: %s.\n", strings.Join(contextsList, ", "))
+	} else if strSourceClusterContext == "" {
 		fmt.Println("We will use current kubeconfig context as 'source cluster'.")
 	} else {
 		fmt.Printf("We will use %s kubeconfig context as 'source cluster' or 'origin cluster'.\n", strSourceClusterContext)
 	}
-	fmt.Printf("We will use %s kubeconfig context as 'target cluster'.\n", strTargetClusterContext)
+	if len(contextsList) == 0 {
+		fmt.Printf("We will use %s kubeconfig context as 'target cluster'.\n", strTargetClusterContext)
+	}
 
 	strNamespaceName = *TheArgs.NamespaceName
 	configFile := ""
@@ -159,6 +294,24 @@ func ValidateParametersFromParserArgs(TheArgs ArgumentsReceived) ArgumentsReceiv
 			Exclude:              excludeStr,
 			VerboseDiffs:         *TheArgs.VerboseDiffs,
 			FileOutput:           filePath,
+			NoSuppress:           boolValue(TheArgs.NoSuppress),
+			PluginsDir:           stringValue(TheArgs.PluginsDir),
+			Baseline:             stringValue(TheArgs.Baseline),
+			BaselineAccept:       boolValue(TheArgs.BaselineAccept),
+			Analyze:              tools.ParseCommaSeparateList(stringValue(TheArgs.Analyze)),
+			Contexts:             contextsList,
+			Fleet:                boolValue(TheArgs.Fleet) || stringValue(TheArgs.Golden) != "",
+			Golden:               stringValue(TheArgs.Golden),
+			RegistryMirrors:      tools.ParseCommaSeparateList(stringValue(TheArgs.RegistryMirrors)),
+			ImageDigests:         boolValue(TheArgs.ImageDigests),
+			ImageInventory:       boolValue(TheArgs.ImageInventory),
+			Prices:               stringValue(TheArgs.Prices),
+			Settle:               settleValue(TheArgs.Settle),
+			Repeat:               repeatValue(TheArgs.Repeat),
+			Interval:             durationValue(TheArgs.Interval),
+			State:                stringValue(TheArgs.State),
+			Lint:                 stringValue(TheArgs.Lint),
+			ManagedAnnotations:   tools.ParseCommaSeparateList(stringValue(TheArgs.ManagedAnnotations)),
 			Err:                  nil}
 	}
 	return ArgumentsReceivedValidated{
@@ -171,31 +324,169 @@ func ValidateParametersFromParserArgs(TheArgs ArgumentsReceived) ArgumentsReceiv
 		Exclude:              excludeStr,
 		VerboseDiffs:         *TheArgs.VerboseDiffs,
 		FileOutput:           "",
+		NoSuppress:           boolValue(TheArgs.NoSuppress),
+		PluginsDir:           stringValue(TheArgs.PluginsDir),
+		Baseline:             stringValue(TheArgs.Baseline),
+		BaselineAccept:       boolValue(TheArgs.BaselineAccept),
+		Analyze:              tools.ParseCommaSeparateList(stringValue(TheArgs.Analyze)),
+		Contexts:             contextsList,
+		Fleet:                boolValue(TheArgs.Fleet) || stringValue(TheArgs.Golden) != "",
+		Golden:               stringValue(TheArgs.Golden),
+		RegistryMirrors:      tools.ParseCommaSeparateList(stringValue(TheArgs.RegistryMirrors)),
+		ImageDigests:         boolValue(TheArgs.ImageDigests),
+		ImageInventory:       boolValue(TheArgs.ImageInventory),
+		Prices:               stringValue(TheArgs.Prices),
+		Settle:               settleValue(TheArgs.Settle),
+		Repeat:               repeatValue(TheArgs.Repeat),
+		Interval:             durationValue(TheArgs.Interval),
+		State:                stringValue(TheArgs.State),
+		Lint:                 stringValue(TheArgs.Lint),
+		ManagedAnnotations:   tools.ParseCommaSeparateList(stringValue(TheArgs.ManagedAnnotations)),
 		Err:                  nil}
 }
 
+// splitBaselineCommand removes the "baseline accept" command from the arguments, reporting whether it was given.
+// argparse commands would make a command mandatory, so the command is recognized before parsing.
+func splitBaselineCommand(args []string) ([]string, bool) {
+	if len(args) >= 3 && args[1] == "baseline" && args[2] == "accept" {
+		return append([]string{args[0]}, args[3:]...), true
+	}
+	return args, false
+}
+
+// stringValue dereferences an optional option, treating a missing option as empty.
+func stringValue(option *string) string {
+	if option == nil {
+		return ""
+	}
+	return *option
+}
+
+// boolValue dereferences an optional flag, treating a missing flag as false.
+func boolValue(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+// repeatValue dereferences the optional --repeat option, a missing option meaning a single run.
+func repeatValue(option *int) int {
+	if option == nil || *option < 1 {
+		return 1
+	}
+	return *option
+}
+
+// durationValue parses an optional duration option that PaserReader already validated, treating a missing option as 0.
+func durationValue(option *string) time.Duration {
+	duration, _ := time.ParseDuration(stringValue(option))
+	return duration
+}
+
+// SettleOptions say which in-flight objects, e.g. in the middle of a deploy, are left out of the comparison.
+type SettleOptions struct {
+	// Age leaves out the objects created less than Age ago, in any cluster.
+	Age time.Duration
+	// Deleting leaves out the objects being deleted, and the namespaces being deleted in the source cluster.
+	Deleting bool
+	// Reconcile leaves out the objects their controller has not caught up with: an observedGeneration behind
+	// the generation, or a rollout in progress.
+	Reconcile bool
+	// Recheck lists the objects again after this delay when some are in flight, and only leaves out those still in flight.
+	Recheck time.Duration
+	// Mark reports the differences of in-flight objects as info instead of leaving the objects out.
+	Mark bool
+}
+
+// Enabled reports whether any object can be in flight.
+func (s SettleOptions) Enabled() bool {
+	return s.Age > 0 || s.Deleting || s.Reconcile
+}
+
+// ParseSettle parses the --settle options: "age=<duration>", "deleting", "reconcile", "recheck=<duration>" and "mark".
+func ParseSettle(value string) (SettleOptions, error) {
+	var options SettleOptions
+	for _, option := range tools.ParseCommaSeparateList(value) {
+		name, duration, hasValue := strings.Cut(strings.TrimSpace(option), "=")
+		switch {
+		case name == "age" && hasValue, name == "recheck" && hasValue:
+			parsed, err := time.ParseDuration(duration)
+			if err != nil || parsed <= 0 {
+				return options, fmt.Errorf("%s needs a positive duration like 30s or 2m, got %q", name, duration)
+			}
+			if name == "age" {
+				options.Age = parsed
+			} else {
+				options.Recheck = parsed
+			}
+		case name == "deleting" && !hasValue:
+			options.Deleting = true
+		case name == "reconcile" && !hasValue:
+			options.Reconcile = true
+		case name == "mark" && !hasValue:
+			options.Mark = true
+		default:
+			return options, fmt.Errorf("unknown option %q, expected age=<duration>, deleting, reconcile, recheck=<duration> or mark", option)
+		}
+	}
+	if (options.Recheck > 0 || options.Mark) && !options.Enabled() {
+		return options, fmt.Errorf("recheck and mark need one of age=<duration>, deleting or reconcile")
+	}
+	return options, nil
+}
+
+// settleValue parses an optional --settle option that PaserReader already validated.
+func settleValue(option *string) SettleOptions {
+	options, _ := ParseSettle(stringValue(option))
+	return options
+}
+
+// validObjects maps the canonical name of each comparable Kubernetes object to its accepted aliases.
+var validObjects = map[string][]string{
+	"deployment":         {"deployment", "deployments", "deploy"},
+	"ingress":            {"ingress", "ing"},
+	"service":            {"service", "svc", "services"},
+	"serviceaccount":     {"sa", "serviceaccount", "serviceaccounts"},
+	"configmap":          {"configmap", "configmaps", "cm"},
+	"secret":             {"secret", "secrets"},
+	"namespace":          {"namespace", "ns", "namespaces"},
+	"hpa":                {"hpa", "horizontalpodautoscaler", "horizontalpodautoscalers", "hpas"},
+	"role":               {"role", "roles"},
+	"rolebinding":        {"rolebinding", "rolebindings"},
+	"clusterrole":        {"clusterrole", "clusterroles"},
+	"clusterrolebinding": {"clusterrolebinding", "clusterrolebindings"},
+	"crd":                {"crd", "crds", "customresourcedefinition", "customresourcedefinitions"},
+	"networkpolicy":      {"networkpolicy", "networkpolicies"},
+	// More objects are added by compare.Register
+}
+
+// RegisterObjectAliases adds a Kubernetes object and its aliases to the names accepted by -i and -e.
+// Aliases of an object that is already known are merged.
+func RegisterObjectAliases(standardName string, aliases []string) {
+	for _, alias := range aliases {
+		if !tools.IsInList(alias, validObjects[standardName]) {
+			validObjects[standardName] = append(validObjects[standardName], alias)
+		}
+	}
+}
+
+// ObjectAliasOwner returns the Kubernetes object a name or alias accepted by -i and -e stands for.
+func ObjectAliasOwner(alias string) (string, bool) {
+	for standardName, aliases := range validObjects {
+		if tools.IsInList(alias, aliases) {
+			return standardName, true
+		}
+	}
+	return "", false
+}
+
+// UnregisterObjectAliases removes a Kubernetes object and its aliases from the names accepted by -i and -e.
+func UnregisterObjectAliases(standardName string) {
+	delete(validObjects, standardName)
+}
+
 // ValidateKubernetesObjects validates the given list of Kubernetes object names
 // against a list of valid object names and their aliases
 // It returns two slices: invalidObjects and validObjects
 func ValidateKubernetesObjects(objects []string) ([]string, []string) {
-	validObjects := map[string][]string{
-		"deployment":         {"deployment", "deployments", "deploy"},
-		"ingress":            {"ingress", "ing"},
-		"service":            {"service", "svc", "services"},
-		"serviceaccount":     {"sa", "serviceaccount", "serviceaccounts"},
-		"configmap":          {"configmap", "configmaps", "cm"},
-		"secret":             {"secret", "secrets"},
-		"namespace":          {"namespace", "ns", "namespaces"},
-		"hpa":                {"hpa", "horizontalpodautoscaler", "horizontalpodautoscalers", "hpas"},
-		"role":               {"role", "roles"},
-		"rolebinding":        {"rolebinding", "rolebindings"},
-		"clusterrole":        {"clusterrole", "clusterroles"},
-		"clusterrolebinding": {"clusterrolebinding", "clusterrolebindings"},
-		"crd":                {"crd", "crds", "customresourcedefinition", "customresourcedefinitions"},
-		"networkpolicy":      {"networkpolicy", "networkpolicies"},
-		// Add more valid objects and their aliases as needed
-	}
-
 	var invalidObjects []string
 	var validObjectsStr []string
 
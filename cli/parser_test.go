@@ -4,6 +4,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestPaserReader(t *testing.T) {
@@ -162,3 +163,101 @@ func TestValidateKubernetesObjects(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitBaselineCommand(t *testing.T) {
+	args, accept := splitBaselineCommand([]string{"kompare", "baseline", "accept", "-t", "prod"})
+	if !accept || !reflect.DeepEqual(args, []string{"kompare", "-t", "prod"}) {
+		t.Errorf("Expected the baseline command to be removed, got %v, %v", args, accept)
+	}
+	args, accept = splitBaselineCommand([]string{"kompare", "-t", "baseline"})
+	if accept || !reflect.DeepEqual(args, []string{"kompare", "-t", "baseline"}) {
+		t.Errorf("Expected arguments without the command to be kept, got %v, %v", args, accept)
+	}
+}
+
+func TestPaserReaderContexts(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	os.Args = []string{"program_name", "--contexts", "eu-west-1,eu-west-2,us-east-1"}
+	args := PaserReader()
+	if args.Err != nil {
+		t.Fatalf("Expected -t not to be required with --contexts, got: %v", args.Err)
+	}
+	if !reflect.DeepEqual(args.Contexts, []string{"eu-west-1", "eu-west-2", "us-east-1"}) {
+		t.Errorf("Expected three contexts, got %v", args.Contexts)
+	}
+
+	os.Args = []string{"program_name", "--contexts", "eu-west-1"}
+	if args := PaserReader(); args.Err == nil {
+		t.Error("Expected an error without -t and with a single context")
+	}
+
+	os.Args = []string{"program_name", "--contexts", "eu-west-1,eu-west-2", "--golden", "eu-west-2"}
+	if args := PaserReader(); args.Err != nil || !args.Fleet || args.Golden != "eu-west-2" {
+		t.Errorf("Expected --golden to imply --fleet, got %+v", args)
+	}
+
+	os.Args = []string{"program_name", "--contexts", "eu-west-1,eu-west-2", "--golden", "us-east-1"}
+	if args := PaserReader(); args.Err == nil {
+		t.Error("Expected an error for a --golden context not in --contexts")
+	}
+
+	for _, ignored := range [][]string{{"-s", "eu-west-1"}, {"-t", "eu-west-2"}, {"--baseline", "kompare-baseline.json"}, {"--analyze", "rbac"}, {"--plugins-dir", "plugins"}, {"--settle", "age=2m,mark"}, {"--settle", "deleting,recheck=30s"}} {
+		os.Args = append([]string{"program_name", "--contexts", "eu-west-1,eu-west-2"}, ignored...)
+		if args := PaserReader(); args.Err == nil {
+			t.Errorf("Expected an error for --contexts with %v", ignored)
+		}
+	}
+	os.Args = []string{"program_name", "--contexts", "eu-west-1,eu-west-2", "--settle", "age=2m,deleting"}
+	if args := PaserReader(); args.Err != nil {
+		t.Errorf("Expected --settle without mark or recheck to work with --contexts, got: %v", args.Err)
+	}
+	os.Args = []string{"program_name", "baseline", "accept", "--contexts", "eu-west-1,eu-west-2"}
+	if args := PaserReader(); args.Err == nil {
+		t.Error("Expected an error for baseline accept with --contexts")
+	}
+
+	os.Args = []string{"program_name", "-t", "eu-west-2", "--fleet"}
+	if args := PaserReader(); args.Err == nil {
+		t.Error("Expected an error for --fleet without --contexts")
+	}
+}
+
+func TestParseSettle(t *testing.T) {
+	options, err := ParseSettle("age=2m, deleting,reconcile,recheck=30s,mark")
+	expected := SettleOptions{Age: 2 * time.Minute, Deleting: true, Reconcile: true, Recheck: 30 * time.Second, Mark: true}
+	if err != nil || options != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, options, err)
+	}
+	if options, err := ParseSettle(""); err != nil || options.Enabled() {
+		t.Errorf("Expected no settling by default, got %+v, %v", options, err)
+	}
+	for _, invalid := range []string{"age", "age=soon", "recheck=-1s", "deleting=true", "rollout", "recheck=30s", "mark"} {
+		if _, err := ParseSettle(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestPaserReaderRepeat(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	os.Args = []string{"program_name", "-t", "prod"}
+	if args := PaserReader(); args.Err != nil || args.Repeat != 1 || args.Interval != 30*time.Second {
+		t.Errorf("Expected a single run with a 30s interval by default, got %+v", args)
+	}
+
+	os.Args = []string{"program_name", "-t", "prod", "--repeat", "3", "--interval", "5m"}
+	if args := PaserReader(); args.Err != nil || args.Repeat != 3 || args.Interval != 5*time.Minute {
+		t.Errorf("Expected 3 runs 5 minutes apart, got %+v", args)
+	}
+
+	for _, invalid := range [][]string{{"--repeat", "0"}, {"--interval", "soon"}, {"--repeat", "2", "--contexts", "eu-west-1,eu-west-2"}} {
+		os.Args = append([]string{"program_name", "-t", "prod"}, invalid...)
+		if args := PaserReader(); args.Err == nil {
+			t.Errorf("Expected an error for %v", invalid)
+		}
+	}
+}
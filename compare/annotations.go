@@ -0,0 +1,191 @@
+package compare
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"kompare/tools"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Annotations service owners put on live objects to describe intentional, cluster-specific behavior.
+const (
+	// IgnoreAnnotation set to "true" skips the object in both clusters.
+	IgnoreAnnotation = "kompare.io/ignore"
+	// IgnoreFieldsAnnotation is a comma separated list of JSON paths removed from both sides before comparing,
+	// e.g. "spec.replicas,metadata.labels.canary". Keys containing dots go in brackets: "metadata.labels[app.kubernetes.io/name]".
+	IgnoreFieldsAnnotation = "kompare.io/ignore-fields"
+	// ExpectedDiffAnnotation downgrades the differences of the object to info and shows its value as the reason.
+	ExpectedDiffAnnotation = "kompare.io/expected-diff"
+
+	annotationPrefix = "kompare.io/"
+)
+
+// objectAnnotations is what the kompare.io annotations of one object, on either side, ask for.
+type objectAnnotations struct {
+	ignore       bool
+	ignoreFields []string
+	expectedDiff string
+}
+
+// applyObjectAnnotations honors the kompare.io annotations of the listed objects, whichever cluster they are set in.
+// Ignored objects are dropped from every list and ignored fields are removed from every side.
+// The kompare.io annotations themselves are then removed so they never show up as differences.
+// It returns the expected-diff reasons keyed by "namespace/name", and one message per change for verbose mode.
+func applyObjectAnnotations(lists ...interface{}) (map[string]string, []string) {
+	var messages []string
+	wanted := make(map[string]*objectAnnotations)
+	for _, list := range lists {
+		for _, item := range listItems(list) {
+			obj, err := meta.Accessor(item.Addr().Interface())
+			if err != nil {
+				continue
+			}
+			annotations := obj.GetAnnotations()
+			key := obj.GetNamespace() + "/" + obj.GetName()
+			if wanted[key] == nil {
+				wanted[key] = &objectAnnotations{}
+			}
+			if strings.EqualFold(annotations[IgnoreAnnotation], "true") {
+				wanted[key].ignore = true
+			}
+			for _, path := range tools.ParseCommaSeparateList(annotations[IgnoreFieldsAnnotation]) {
+				path = strings.TrimSpace(path)
+				if path != "" && !tools.IsInList(path, wanted[key].ignoreFields) {
+					wanted[key].ignoreFields = append(wanted[key].ignoreFields, path)
+				}
+			}
+			if reason := annotations[ExpectedDiffAnnotation]; reason != "" && wanted[key].expectedDiff == "" {
+				wanted[key].expectedDiff = reason
+			}
+		}
+	}
+
+	expected := make(map[string]string)
+	for _, list := range lists {
+		removeItems(list, func(item reflect.Value) bool {
+			namespace, name := objectKey(item)
+			return wanted[namespace+"/"+name] != nil && wanted[namespace+"/"+name].ignore
+		})
+		for _, item := range listItems(list) {
+			namespace, name := objectKey(item)
+			key := namespace + "/" + name
+			if w := wanted[key]; w != nil {
+				if len(w.ignoreFields) > 0 {
+					if err := removeFields(item.Addr().Interface(), w.ignoreFields); err != nil {
+						messages = append(messages, fmt.Sprintf("Could not ignore fields of %s: %v", name, err))
+					}
+				}
+				if w.expectedDiff != "" {
+					expected[key] = w.expectedDiff
+				}
+			}
+			if obj, err := meta.Accessor(item.Addr().Interface()); err == nil {
+				stripKompareAnnotations(obj)
+			}
+		}
+	}
+	keys := make([]string, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	// Sorted, so that verbose output is the same from one run to the next.
+	sort.Strings(keys)
+	for _, key := range keys {
+		w := wanted[key]
+		if w.ignore {
+			messages = append(messages, fmt.Sprintf("Skipping %s: annotated with %s", key, IgnoreAnnotation))
+		} else if len(w.ignoreFields) > 0 {
+			messages = append(messages, fmt.Sprintf("Ignoring %s of %s: annotated with %s", strings.Join(w.ignoreFields, ", "), key, IgnoreFieldsAnnotation))
+		}
+	}
+	return expected, messages
+}
+
+// removeItems drops the items of a list for which drop returns true.
+func removeItems(list interface{}, drop func(item reflect.Value) bool) {
+	if list == nil || !hasItemsField(list) {
+		return
+	}
+	itemsField := reflect.ValueOf(list).Elem().FieldByName("Items")
+	kept := reflect.MakeSlice(itemsField.Type(), 0, itemsField.Len())
+	for i := 0; i < itemsField.Len(); i++ {
+		if !drop(itemsField.Index(i)) {
+			kept = reflect.Append(kept, itemsField.Index(i))
+		}
+	}
+	itemsField.Set(kept)
+}
+
+// removeFields deletes JSON paths from an object. Typed objects make a round trip through their unstructured form.
+func removeFields(obj interface{}, paths []string) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		for _, path := range paths {
+			unstructured.RemoveNestedField(u.Object, SplitFieldPath(path)...)
+		}
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		unstructured.RemoveNestedField(content, SplitFieldPath(path)...)
+	}
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
+
+// SplitFieldPath splits a JSON path like "metadata.labels[app.kubernetes.io/name]" into its keys.
+// Brackets hold keys that contain dots.
+func SplitFieldPath(path string) []string {
+	var fields []string
+	var current strings.Builder
+	inBrackets := false
+	for _, r := range path {
+		switch {
+		case r == '[' && !inBrackets:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			inBrackets = true
+		case r == ']' && inBrackets:
+			fields = append(fields, current.String())
+			current.Reset()
+			inBrackets = false
+		case r == '.' && !inBrackets:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// stripKompareAnnotations removes the kompare.io annotations of an object, and its annotations altogether
+// when none are left, so that it compares equal to an object without annotations.
+func stripKompareAnnotations(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	for key := range annotations {
+		if strings.HasPrefix(key, annotationPrefix) {
+			delete(annotations, key)
+		}
+	}
+	if annotations != nil && len(annotations) == 0 {
+		obj.SetAnnotations(nil)
+	}
+}
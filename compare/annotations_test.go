@@ -0,0 +1,118 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func annotatedDeployment(name string, replicas int32, labels, annotations map[string]string) v1.Deployment {
+	deployment := deploymentWithReplicas(name, replicas, nil)
+	deployment.Labels = labels
+	deployment.Annotations = annotations
+	return deployment
+}
+
+func TestIgnoreAnnotationDropsObjectFromBothLists(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{
+		annotatedDeployment("web", 1, nil, nil),
+		annotatedDeployment("scratch", 1, nil, map[string]string{IgnoreAnnotation: "true"}),
+	}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{
+		annotatedDeployment("web", 1, nil, nil),
+		annotatedDeployment("scratch", 2, nil, nil),
+	}}
+
+	_, messages := applyObjectAnnotations(source, target)
+
+	if len(source.Items) != 1 || len(target.Items) != 1 || target.Items[0].Name != "web" {
+		t.Errorf("Expected 'scratch' to be dropped from both lists, got %d and %d items", len(source.Items), len(target.Items))
+	}
+	if len(messages) != 1 {
+		t.Errorf("Expected 1 message, got %v", messages)
+	}
+}
+
+func TestAnnotationMessagesAreSorted(t *testing.T) {
+	ignored := map[string]string{IgnoreAnnotation: "true"}
+	expected := []string{
+		"Skipping default/alpha: annotated with " + IgnoreAnnotation,
+		"Skipping default/bravo: annotated with " + IgnoreAnnotation,
+		"Skipping default/charlie: annotated with " + IgnoreAnnotation,
+	}
+	for i := 0; i < 10; i++ {
+		source := &v1.DeploymentList{Items: []v1.Deployment{
+			annotatedDeployment("charlie", 1, nil, ignored),
+			annotatedDeployment("alpha", 1, nil, ignored),
+			annotatedDeployment("bravo", 1, nil, ignored),
+		}}
+		_, messages := applyObjectAnnotations(source, &v1.DeploymentList{})
+		if !reflect.DeepEqual(messages, expected) {
+			t.Fatalf("Expected %v, got %v", expected, messages)
+		}
+	}
+}
+
+func TestIgnoreFieldsAnnotation(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{
+		annotatedDeployment("web", 3, map[string]string{"app": "web", "canary": "true"}, map[string]string{IgnoreFieldsAnnotation: "spec.replicas, metadata.labels.canary"}),
+	}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{
+		annotatedDeployment("web", 5, map[string]string{"app": "web"}, nil),
+	}}
+
+	applyObjectAnnotations(source, target)
+
+	if source.Items[0].Spec.Replicas != nil || target.Items[0].Spec.Replicas != nil {
+		t.Errorf("Expected spec.replicas to be removed on both sides")
+	}
+	if !reflect.DeepEqual(source.Items[0].Labels, map[string]string{"app": "web"}) {
+		t.Errorf("Expected only the canary label to be removed, got %v", source.Items[0].Labels)
+	}
+	if len(source.Items[0].Annotations) != 0 {
+		t.Errorf("Expected kompare.io annotations to be removed, got %v", source.Items[0].Annotations)
+	}
+	if source.Items[0].Name != "web" || source.Items[0].Namespace != "default" {
+		t.Errorf("Expected the rest of the object to be kept, got %+v", source.Items[0].ObjectMeta)
+	}
+}
+
+func TestOnlyKompareAnnotationsLeaveNoAnnotations(t *testing.T) {
+	source := &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default", Annotations: map[string]string{IgnoreFieldsAnnotation: "data.debug"}},
+	}}}
+	target := &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}}}}
+
+	applyObjectAnnotations(source, target)
+
+	if source.Items[0].Annotations != nil {
+		t.Errorf("Expected no annotations to be left, so that they equal the nil annotations of the target, got %#v", source.Items[0].Annotations)
+	}
+}
+
+func TestExpectedDiffAnnotation(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{annotatedDeployment("web", 3, nil, nil)}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{
+		annotatedDeployment("web", 5, nil, map[string]string{ExpectedDiffAnnotation: "prod runs more replicas"}),
+	}}
+
+	expected, _ := applyObjectAnnotations(source, target)
+
+	if expected["default/web"] != "prod runs more replicas" {
+		t.Errorf("Expected the reason to be keyed by namespace/name, got %v", expected)
+	}
+	if target.Items[0].Annotations[ExpectedDiffAnnotation] != "" {
+		t.Errorf("Expected the annotation itself not to be compared")
+	}
+}
+
+func TestSplitFieldPath(t *testing.T) {
+	got := SplitFieldPath("metadata.labels[app.kubernetes.io/name]")
+	want := []string{"metadata", "labels", "app.kubernetes.io/name"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -1,32 +1,26 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// ClusterRoleBindingComparator lists and compares cluster role bindings.
+var ClusterRoleBindingComparator = &BaseComparator{
+	KindName:      "clusterrolebinding",
+	KindAliases:   []string{"clusterrolebinding", "clusterrolebindings"},
+	Group:         "rbac.authorization.k8s.io",
+	Kind:          "ClusterRoleBinding",
+	ClusterScoped: true,
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListClusterRoleBindings(cluster.Clientset)
+	},
+	Criteria: []string{"RoleRef", "Name", "Annotations"},
+}
+
 func CompareClusterRoleBindings(clientsetToSource, clientsetToTarget *kubernetes.Clientset, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceClusterRoleBindings, err := query.ListClusterRoleBindings(clientsetToSource)
-	if err != nil {
-		fmt.Printf("Error getting cluster role list: %v\n", err)
-		return TheDiff, err
-	}
-	targetClusterRoleBindings, err := query.ListClusterRoleBindings(clientsetToTarget)
-	if err != nil {
-		fmt.Printf("Error getting cluster role list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"RoleRef", "Name", "Annotations"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceClusterRoleBindings, targetClusterRoleBindings, diffCriteria, TheArgs)
+	return CompareKind(ClusterRoleBindingComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, "", TheArgs)
 }
@@ -0,0 +1,500 @@
+package compare
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-test/deep"
+
+	"kompare/DAO"
+	"kompare/cli"
+	"kompare/plugins"
+	"kompare/state"
+	"kompare/tools"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Cluster is one side of a comparison as seen by a Comparator.
+// Context and Kubeconfig are there for comparators that need a client other than the Clientset,
+// e.g. a dynamic client for custom resources built with connect.BuildConfigWithContextFromFlags.
+type Cluster struct {
+	Clientset  *kubernetes.Clientset
+	Context    string
+	Kubeconfig string
+}
+
+// Scope is what a Normalizer knows about the comparison it runs in.
+type Scope struct {
+	Source, Target Cluster
+	Namespace      string
+	Args           cli.ArgumentsReceivedValidated
+}
+
+// Normalizer rewrites the source and target lists in place before they are paired and diffed.
+// It returns one message per change, printed in verbose mode.
+type Normalizer func(source, target interface{}, scope Scope) []string
+
+// Pair is a source object and the target object it is compared with.
+// Source or Target is nil when the object only exists in one of the clusters.
+type Pair struct {
+	Name, Namespace string
+	Source, Target  interface{}
+}
+
+// Comparator describes how kompare lists, normalizes, pairs and diffs one kind of object.
+// Implementations are registered once with Register and then become available from the CLI
+// under their name and aliases. Embed BaseComparator to only override the steps that differ.
+type Comparator interface {
+	// Name is the canonical name used on the command line, e.g. "deployment".
+	Name() string
+	Aliases() []string
+	GroupKind() schema.GroupKind
+	Namespaced() bool
+	// List returns a pointer to a list object with an "Items" slice.
+	List(cluster Cluster, namespace string) (interface{}, error)
+	Normalizers() []Normalizer
+	// DefaultCriteria are the fields compared when -f is not given.
+	DefaultCriteria() []string
+	Pair(source, target interface{}) []Pair
+	Diff(pair Pair, criteria []string) []DAO.DiffWithName
+}
+
+// BaseComparator is a field-driven Comparator. It pairs objects by namespace and name and diffs
+// the criteria fields with deep.Equal, which is what every built-in kind does.
+type BaseComparator struct {
+	KindName      string
+	KindAliases   []string
+	Group, Kind   string
+	ClusterScoped bool
+	Lister        func(cluster Cluster, namespace string) (interface{}, error)
+	// VersionedListers list the kind at the versions the clusters may serve, the preferred one first.
+	// When set, both sides are read at the first version they all serve, see CommonVersion; Lister is the fallback.
+	VersionedListers []VersionedLister
+	Criteria         []string
+	// Related looks up the objects of other kinds the suppression rules need, it may be nil.
+	Related func(scope Scope) Related
+	// ExtraNormalizers run after the default suppression rules.
+	ExtraNormalizers []Normalizer
+}
+
+func (b *BaseComparator) Name() string {
+	return b.KindName
+}
+
+func (b *BaseComparator) Aliases() []string {
+	return b.KindAliases
+}
+
+func (b *BaseComparator) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: b.Group, Kind: b.Kind}
+}
+
+func (b *BaseComparator) Namespaced() bool {
+	return !b.ClusterScoped
+}
+
+func (b *BaseComparator) List(cluster Cluster, namespace string) (interface{}, error) {
+	if b.Lister == nil {
+		return nil, fmt.Errorf("no lister configured for %s", b.KindName)
+	}
+	return b.Lister(cluster, namespace)
+}
+
+// Normalizers returns the default suppression rules followed by ExtraNormalizers.
+func (b *BaseComparator) Normalizers() []Normalizer {
+	return append([]Normalizer{SuppressionNormalizer(b.Related)}, b.ExtraNormalizers...)
+}
+
+func (b *BaseComparator) DefaultCriteria() []string {
+	return b.Criteria
+}
+
+// Pair matches source and target items by namespace and name.
+func (b *BaseComparator) Pair(source, target interface{}) []Pair {
+	return PairByName(source, target)
+}
+
+// Diff compares every criteria field of the pair with deep.Equal.
+func (b *BaseComparator) Diff(pair Pair, criteria []string) []DAO.DiffWithName {
+	return DiffByCriteria(pair, criteria)
+}
+
+// PairByName matches the items of two lists by namespace and name through an index of the target list.
+// Pairs follow the order of the source list, followed by the objects only found in the target.
+func PairByName(source, target interface{}) []Pair {
+	sourceItems := listItems(source)
+	targetItems := listItems(target)
+	pairs := make([]Pair, 0, len(sourceItems)+len(targetItems))
+	targetIndex := objectIndex(targetItems)
+	matched := make(map[int]bool, len(targetItems))
+	for _, sourceItem := range sourceItems {
+		sourceNamespace, sourceName := objectKey(sourceItem)
+		pair := Pair{Name: sourceName, Namespace: sourceNamespace, Source: sourceItem.Interface()}
+		if j, found := targetIndex[sourceNamespace+"/"+sourceName]; found && !matched[j] {
+			pair.Target = targetItems[j].Interface()
+			matched[j] = true
+		}
+		pairs = append(pairs, pair)
+	}
+	for j, targetItem := range targetItems {
+		if !matched[j] {
+			targetNamespace, targetName := objectKey(targetItem)
+			pairs = append(pairs, Pair{Name: targetName, Namespace: targetNamespace, Target: targetItem.Interface()})
+		}
+	}
+	return pairs
+}
+
+// DiffByCriteria compares each criteria field of a pair with deep.Equal.
+// Like DeepCompare, the target value is on the left of the "!=" and the source value on the right.
+func DiffByCriteria(pair Pair, criteria []string) []DAO.DiffWithName {
+	var diffs []DAO.DiffWithName
+	if pair.Source == nil || pair.Target == nil {
+		return diffs
+	}
+	for _, v := range criteria {
+		sourceField, err := getNestedFieldValue(reflect.ValueOf(pair.Source), strings.Split(v, "."))
+		if err != nil {
+			fmt.Printf("Error accessing field: %v\n", err)
+			continue
+		}
+		targetField, err := getNestedFieldValue(reflect.ValueOf(pair.Target), strings.Split(v, "."))
+		if err != nil {
+			fmt.Printf("Error accessing field: %v\n", err)
+			continue
+		}
+		diffs = append(diffs, DAO.DiffWithName{
+			Name:         pair.Name,
+			Namespace:    pair.Namespace,
+			Diff:         deep.Equal(targetField.Interface(), sourceField.Interface()),
+			PropertyName: v,
+		})
+	}
+	return diffs
+}
+
+// Findings, when set, receives the differences of every CompareKind call before they are printed
+// and returns the ones to report, e.g. baseline.Baseline.Filter.
+var Findings func(diffs []DAO.DiffWithName) []DAO.DiffWithName
+
+// Compared, when set, is told each kind and namespace CompareKind listed in both clusters,
+// "" for cluster-scoped kinds, e.g. baseline.Baseline.Compared.
+var Compared func(kind, namespace string)
+
+// Incremental, when set, carries forward the findings of the pairs whose objects have not changed
+// since the run that saved it, instead of diffing them again, and records the pairs compared.
+var Incremental *state.State
+
+// CompareKind runs a Comparator against both clusters: it lists the objects, applies the
+// normalizers, reports objects missing on either side and diffs the pairs found in both.
+func CompareKind(c Comparator, source, target Cluster, namespaceName string, args cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
+	return compareKind(c, source, target, namespaceName, args, nil)
+}
+
+// compareKind is CompareKind, restricted to the objects whose "namespace/name" is in only when only is not nil,
+// for RecheckInFlight to compare the objects that were in flight again.
+func compareKind(c Comparator, source, target Cluster, namespaceName string, args cli.ArgumentsReceivedValidated, only map[string]string) ([]DAO.DiffWithName, error) {
+	var TheDiff []DAO.DiffWithName
+	version := negotiateVersion(c, source, target)
+	if version != "" && args.VerboseDiffs != 0 {
+		fmt.Printf("- Comparing %s at %s\n", c.Name(), version)
+	}
+	list := func() (interface{}, interface{}, error) {
+		sourceList, err := listVersion(c, source, namespaceName, version)
+		if err != nil {
+			fmt.Printf("Error getting %s list: %v\n", c.Name(), err)
+			return nil, nil, err
+		}
+		targetList, err := listVersion(c, target, namespaceName, version)
+		if err != nil {
+			fmt.Printf("Error getting %s list: %v\n", c.Name(), err)
+			return nil, nil, err
+		}
+		return sourceList, targetList, nil
+	}
+	sourceList, targetList, err := list()
+	if err != nil {
+		return TheDiff, err
+	}
+	if Compared != nil {
+		Compared(c.Name(), namespaceName)
+	}
+	if only != nil {
+		for _, list := range []interface{}{sourceList, targetList} {
+			removeItems(list, func(item reflect.Value) bool {
+				namespace, name := objectKey(item)
+				_, found := only[namespace+"/"+name]
+				return !found
+			})
+		}
+	} else {
+		// The rechecked objects were linted with the others.
+		lintLists(c, []Cluster{source, target}, []interface{}{sourceList, targetList})
+	}
+	inFlight := InFlight(args.Settle, time.Now(), sourceList, targetList)
+	namespaceInFlight(args.Settle, inFlight, namespaceName, sourceList, targetList)
+	settle := args.Settle
+	if only == nil && len(inFlight) != 0 && settle.Recheck > 0 {
+		fmt.Printf("%d %s objects in flight, checking them again at the end of the run\n", len(inFlight), c.Name())
+		deferRecheck(c, source, target, namespaceName, inFlight)
+		// Left out until the recheck, which marks the ones still in flight.
+		settle.Mark = false
+	}
+	marked, messages := settleObjects(settle, inFlight, sourceList, targetList)
+	// Per-object annotations apply to every kind, whatever normalizers its comparator has.
+	expected, annotationMessages := applyObjectAnnotations(sourceList, targetList)
+	messages = append(messages, annotationMessages...)
+	for key, reason := range marked {
+		if expected[key] == "" {
+			expected[key] = reason
+		}
+	}
+	if args.VerboseDiffs != 0 {
+		for _, message := range messages {
+			fmt.Printf("- %s\n", message)
+		}
+	}
+	scope := Scope{Source: source, Target: target, Namespace: namespaceName, Args: args}
+	for _, normalize := range c.Normalizers() {
+		messages := normalize(sourceList, targetList, scope)
+		if args.VerboseDiffs != 0 {
+			for _, message := range messages {
+				fmt.Printf("- %s\n", message)
+			}
+		}
+	}
+	diffCriteria := c.DefaultCriteria()
+	if args.FiltersForObject != "" {
+		diffCriteria = tools.ParseCommaSeparateList(args.FiltersForObject)
+	}
+	TheDiff = showComparison(c, sourceList, targetList, diffCriteria, args, expected)
+	for i := range TheDiff {
+		TheDiff[i].APIVersion = version
+	}
+	if Findings != nil {
+		TheDiff = Findings(TheDiff)
+	}
+	printPresence(c, sourceList, targetList, TheDiff, args)
+	if args.VerboseDiffs > 1 {
+		fmt.Println(tools.FormatDiffHumanReadable(TheDiff))
+	}
+	return TheDiff, nil
+}
+
+// PresenceProperty is the PropertyName of the findings about an object found in only one cluster.
+const PresenceProperty = "Presence"
+
+const (
+	onlyInSource = "only in the source cluster"
+	onlyInTarget = "only in the target cluster"
+)
+
+// showComparison returns the differences of the pairs found in both clusters, plus one Presence finding
+// per object found in only one of them, tagged with the comparator's kind.
+// Differences of objects with an expected-diff reason are downgraded to info.
+func showComparison(c Comparator, sourceResource, targetResource interface{}, diffCriteria []string, args cli.ArgumentsReceivedValidated, expected map[string]string) []DAO.DiffWithName {
+	var TheDiff []DAO.DiffWithName
+	pairs := c.Pair(sourceResource, targetResource)
+
+	pluginPath, hasPlugin := plugins.Find(args.PluginsDir, append([]string{c.Name()}, c.Aliases()...)...)
+	if hasPlugin && args.VerboseDiffs != 0 {
+		fmt.Printf("Using comparator plugin %s\n", pluginPath)
+	}
+	for _, pair := range pairs {
+		if pair.Source == nil || pair.Target == nil {
+			diff := DAO.DiffWithName{Kind: c.Name(), Name: pair.Name, Namespace: pair.Namespace, PropertyName: PresenceProperty, Diff: []string{onlyInTarget}}
+			if pair.Target == nil {
+				diff.Diff = []string{onlyInSource}
+			}
+			if reason := expected[pair.Namespace+"/"+pair.Name]; reason != "" {
+				diff.Severity = plugins.SeverityInfo
+				diff.Reason = reason
+			}
+			TheDiff = append(TheDiff, diff)
+			continue
+		}
+		sourceFingerprint := Fingerprint(pair.Source, diffCriteria)
+		targetFingerprint := Fingerprint(pair.Target, diffCriteria)
+		snapshot := state.Snapshot{
+			SourceResourceVersion: resourceVersion(pair.Source),
+			TargetResourceVersion: resourceVersion(pair.Target),
+			SourceFingerprint:     sourceFingerprint,
+			TargetFingerprint:     targetFingerprint,
+		}
+		key := strings.Join([]string{c.Name(), pair.Namespace, pair.Name}, "/")
+		var diffs []DAO.DiffWithName
+		unchanged := false
+		if Incremental != nil {
+			diffs, unchanged = Incremental.Unchanged(key, snapshot)
+		}
+		if !unchanged {
+			if sourceFingerprint == targetFingerprint {
+				diffs = identicalPair(pair, diffCriteria)
+			} else {
+				diffs = c.Diff(pair, diffCriteria)
+			}
+			if hasPlugin {
+				diffs = append(diffs, pluginFindings(c, pluginPath, pair)...)
+			}
+			for i := range diffs {
+				diffs[i].Kind = c.Name()
+			}
+			if Incremental != nil {
+				Incremental.Record(key, snapshot, diffs)
+			}
+		}
+		reason := expected[pair.Namespace+"/"+pair.Name]
+		for _, diff := range diffs {
+			diff.SourceFingerprint = sourceFingerprint
+			diff.TargetFingerprint = targetFingerprint
+			if reason != "" && len(diff.Diff) != 0 {
+				diff.Severity = plugins.SeverityInfo
+				diff.Reason = reason
+			}
+			TheDiff = append(TheDiff, diff)
+		}
+	}
+	return TheDiff
+}
+
+// identicalPair reports a pair whose compared fields have the same fingerprint without diffing it.
+func identicalPair(pair Pair, criteria []string) []DAO.DiffWithName {
+	diffs := make([]DAO.DiffWithName, 0, len(criteria))
+	for _, v := range criteria {
+		diffs = append(diffs, DAO.DiffWithName{Name: pair.Name, Namespace: pair.Namespace, PropertyName: v})
+	}
+	return diffs
+}
+
+// resourceVersion returns the resourceVersion of a listed object, or "" when it has none.
+func resourceVersion(obj interface{}) string {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Ptr {
+		// Pairs hold the list items by value, the accessor needs a pointer.
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+		value = pointer
+	}
+	accessor, err := meta.Accessor(value.Interface())
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
+
+// pluginFindings sends a pair to an external comparator plugin and turns its findings into differences.
+// A failing plugin is reported and skipped, it does not stop the comparison.
+func pluginFindings(c Comparator, pluginPath string, pair Pair) []DAO.DiffWithName {
+	var TheDiff []DAO.DiffWithName
+	findings, err := plugins.Run(pluginPath, plugins.Request{
+		Kind:      c.GroupKind().Kind,
+		Name:      pair.Name,
+		Namespace: pair.Namespace,
+		Source:    pair.Source,
+		Target:    pair.Target,
+	})
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return TheDiff
+	}
+	for _, finding := range findings {
+		TheDiff = append(TheDiff, DAO.DiffWithName{
+			Kind:           c.Name(),
+			Name:           pair.Name,
+			Namespace:      pair.Namespace,
+			Diff:           []string{finding.Message},
+			PropertyName:   finding.Path,
+			MessageHeading: filepath.Base(pluginPath),
+			Severity:       finding.Severity,
+		})
+	}
+	return TheDiff
+}
+
+// printPresence prints the count and presence messages of ShowResourceComparison for the Presence findings
+// left after Findings, so that an accepted missing object is not reported again.
+func printPresence(c Comparator, sourceResource, targetResource interface{}, diffs []DAO.DiffWithName, args cli.ArgumentsReceivedValidated) {
+	var missing []DAO.DiffWithName
+	for _, diff := range diffs {
+		if diff.PropertyName == PresenceProperty && len(diff.Diff) != 0 {
+			missing = append(missing, diff)
+		}
+	}
+	lensourceResource := len(listItems(sourceResource))
+	lentargetResource := len(listItems(targetResource))
+	resourceType := humanReadableKind(c, sourceResource)
+
+	messageheading := "* These two cluster do not have the same number of " + resourceType + ", please check it manually! *"
+	lenMessageheading := len(messageheading)
+	if lentargetResource != lensourceResource && len(missing) != 0 {
+		fmt.Println(strings.Repeat("*", lenMessageheading))
+		fmt.Println(messageheading)
+		fmt.Println(strings.Repeat("*", lenMessageheading))
+		fmt.Printf("The number of %s in the source cluster is %d and there are %d in the target cluster.\n",
+			resourceType, lensourceResource, lentargetResource)
+	}
+	if args.VerboseDiffs != 0 {
+		fmt.Println(strings.Repeat("*", lenMessageheading))
+		printMissing(missing, onlyInSource, "- First cluster has %s: %s, but it's not in the second cluster\n", resourceType, lenMessageheading)
+		printMissing(missing, onlyInTarget, "- Second cluster has %s: %s, but it's not in the first cluster\n", resourceType, lenMessageheading)
+	}
+}
+
+// printMissing prints the objects present only in the source (onlyInSource) or only in the target.
+func printMissing(missing []DAO.DiffWithName, side string, template, resourceType string, lenMessageheading int) {
+	found := false
+	for _, diff := range missing {
+		if diff.Diff[0] == side {
+			fmt.Println(generateMessage(template, resourceType, diff.Name))
+			found = true
+		}
+	}
+	if found {
+		fmt.Println(strings.Repeat("*", lenMessageheading))
+	} else if side == onlyInSource {
+		fmt.Println("Done compering source cluster versus target cluster's ", resourceType)
+	} else {
+		fmt.Println("Done compering target cluster versus source cluster's ", resourceType)
+	}
+}
+
+// humanReadableKind names the compared objects like ConvertTypeStringToHumanReadable does for the built-in lists,
+// falling back to the comparator's kind for lists kompare does not know, e.g. unstructured custom resources.
+func humanReadableKind(c Comparator, list interface{}) string {
+	if typeName, _ := GetTypeInfo(list); typeName != "unknown" || c.GroupKind().Kind == "" {
+		return tools.ConvertTypeStringToHumanReadable(list)
+	}
+	return c.GroupKind().Kind + " in the list"
+}
+
+// listItems returns the elements of the "Items" slice of a list object as addressable values.
+func listItems(list interface{}) []reflect.Value {
+	var items []reflect.Value
+	if list == nil || !hasItemsField(list) {
+		return items
+	}
+	itemsField := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if itemsField.Kind() != reflect.Slice {
+		return items
+	}
+	for i := 0; i < itemsField.Len(); i++ {
+		items = append(items, itemsField.Index(i))
+	}
+	return items
+}
+
+// objectKey returns the namespace and name of a list item, typed or unstructured.
+func objectKey(item reflect.Value) (string, string) {
+	if item.CanAddr() {
+		if obj, err := meta.Accessor(item.Addr().Interface()); err == nil {
+			return obj.GetNamespace(), obj.GetName()
+		}
+	}
+	return "", getName(item.Interface())
+}
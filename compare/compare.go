@@ -5,14 +5,13 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/go-test/deep"
-
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/tools"
 
 	v1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	Corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -39,6 +38,10 @@ var typeAssertions = map[string]TypeAssertionFunc{
 		val, ok := obj.(*autoscalingv1.HorizontalPodAutoscalerList)
 		return ok, val
 	},
+	"*autoscalingv2.HorizontalPodAutoscalerList": func(obj interface{}) (bool, interface{}) {
+		val, ok := obj.(*autoscalingv2.HorizontalPodAutoscalerList)
+		return ok, val
+	},
 	"*batchv1.CronJobList": func(obj interface{}) (bool, interface{}) {
 		val, ok := obj.(*batchv1.CronJobList)
 		return ok, val
@@ -184,30 +187,20 @@ func IterateGenericSimpleDiff(sourceInterface, targetInterface interface{}) ([]s
 // CompareByName compares objects in two interfaces by their names.
 // It takes two interface{} parameters (firstInterface and secondInterface) representing lists of objects,
 // and a string message_heading as input.
-// It extracts the "Items" field from both interfaces and iterates through the items in the first interface.
-// For each item in the first interface, it checks if the item is present in the second interface.
+// It indexes the items of the second interface by namespace and name and looks up each item of the first interface.
 // If an item is not present in the second interface, it generates and prints a message using the message_heading
 // and appends the item's name to the diffNameList.
 // The function returns a slice containing the names of the items that are present in the first interface
 // but not in the second interface.
 func CompareByName(firstInterface, secondInterface interface{}, message_heading string) []string {
 	var diffNameList []string
-
-	// Extract the "Items" field from the first and second interfaces
-	firstItems := reflect.ValueOf(firstInterface).Elem().FieldByName("Items")
-	secondItems := reflect.ValueOf(secondInterface).Elem().FieldByName("Items")
-
-	// Check if both fields are slices
-	if firstItems.Kind() == reflect.Slice && secondItems.Kind() == reflect.Slice {
-		// Loop through the items in the first interface
-		for i := 0; i < firstItems.Len(); i++ {
-			item := firstItems.Index(i).Interface()
-
-			// Check if the item is not present in the second interface
-			if !containsItem(item, secondItems) {
-				fmt.Println(generateMessage(message_heading, tools.ConvertTypeStringToHumanReadable(item), getName(item)))
-				diffNameList = append(diffNameList, getName(item))
-			}
+	secondIndex := objectIndex(listItems(secondInterface))
+	for _, item := range listItems(firstInterface) {
+		namespace, name := objectKey(item)
+		// Check if the item is not present in the second interface
+		if _, found := secondIndex[namespace+"/"+name]; !found {
+			fmt.Println(generateMessage(message_heading, tools.ConvertTypeStringToHumanReadable(item.Interface()), name))
+			diffNameList = append(diffNameList, name)
 		}
 	}
 	return diffNameList
@@ -220,24 +213,6 @@ func generateMessage(template, objectType, ItemName string) string {
 	return fmt.Sprintf(template, objectType, ItemName)
 }
 
-// containsItem checks if an item is present in the second interface.
-// It takes an item interface{} and a reflect.Value (secondItems) representing the second interface.
-// It loops through the items in the second interface and compares each item's name with the name of the provided item.
-// If a matching item is found, it returns true; otherwise, it returns false.
-func containsItem(item interface{}, secondItems reflect.Value) bool {
-	// Loop through the items in the second interface
-	for i := 0; i < secondItems.Len(); i++ {
-		secondItem := secondItems.Index(i).Interface()
-
-		// Compare items by name
-		if getName(item) == getName(secondItem) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // getName retrieves the name of an item assuming it has a "Name" field.
 // It takes an item interface{} as input and extracts the value of the "Name" field.
 // If the field is valid and of type string, it returns the string value of the field.
@@ -263,8 +238,19 @@ func getNestedFieldValue(obj reflect.Value, fieldNames []string) (reflect.Value,
 			obj = obj.Elem()
 		}
 
-		// Access the nested field
-		obj = obj.FieldByName(fieldName)
+		// Unwrap interfaces, e.g. the values of an unstructured object's map
+		if obj.Kind() == reflect.Interface {
+			obj = obj.Elem()
+		}
+
+		// Access the nested field, or the map key for unstructured objects
+		if obj.Kind() == reflect.Map {
+			obj = obj.MapIndex(reflect.ValueOf(fieldName))
+		} else if obj.Kind() == reflect.Struct {
+			obj = obj.FieldByName(fieldName)
+		} else {
+			obj = reflect.Value{}
+		}
 
 		// Check if the field is valid
 		if !obj.IsValid() {
@@ -278,55 +264,43 @@ func getNestedFieldValue(obj reflect.Value, fieldNames []string) (reflect.Value,
 // DeepCompare performs a deep comparison between two interfaces representing lists of objects.
 // It compares the objects based on specified criteria and returns a list of differences along with their names and namespaces.
 // It takes sourceInterface and targetInterface as input interfaces and DiffCriteria as a slice of strings representing comparison criteria.
-// It iterates over the 'Items' fields of both sourceInterface and targetInterface and compares each item's 'Name' field.
-// If the 'Name' fields match, it compares the specified DiffCriteria fields of the objects using the deep.Equal function from the 'github.com/go-test/deep' package.
-// It constructs DiffWithName structs containing the object name, namespace, difference details, and property name for each difference found.
+// It pairs the 'Items' of both interfaces through an index keyed by namespace and name, instead of scanning the target for every source item.
+// Pairs whose criteria fields have the same Fingerprint are reported without running deep.Equal; the others
+// are compared field by field with the deep.Equal function from the 'github.com/go-test/deep' package.
 // The function returns a slice of DiffWithName containing the differences between the source and target interfaces based on the specified criteria.
 func DeepCompare(sourceInterface, targetInterface interface{}, DiffCriteria []string) ([]DAO.DiffWithName, error) {
-	var tmpDiff DAO.DiffWithName
 	var diffSourceTarget []DAO.DiffWithName
 	// Get type information for source and target
 	_, sourceObject := GetTypeInfo(sourceInterface)
 	_, targetObject := GetTypeInfo(targetInterface)
-	sourceItemsField := reflect.ValueOf(sourceObject).Elem().FieldByName("Items")
-	targetItemsField := reflect.ValueOf(targetObject).Elem().FieldByName("Items")
-	// Check if 'Items' is a slice in both source and target objects
-	if sourceItemsField.Kind() == reflect.Slice && targetItemsField.Kind() == reflect.Slice {
-		// Iterate over sourceItems
-		for i := 0; i < sourceItemsField.Len(); i++ {
-			sourceItem := sourceItemsField.Index(i).Interface()
-
-			// Iterate over targetItems
-			for j := 0; j < targetItemsField.Len(); j++ {
-				targetItem := targetItemsField.Index(j).Interface()
-				// Compare 'Name' fields
-				sourceName, _ := getNestedFieldValue(reflect.ValueOf(sourceItem), []string{"Name"})
-				targetName, _ := getNestedFieldValue(reflect.ValueOf(targetItem), []string{"Name"})
-				sourceNamespace, _ := getNestedFieldValue(reflect.ValueOf(sourceItem), []string{"Namespace"})
-				if sourceName.String() == targetName.String() {
-					for _, v := range DiffCriteria {
-						sourceDiffCriteriaField, err := getNestedFieldValue(reflect.ValueOf(sourceItem), strings.Split(v, "."))
-						if err != nil {
-							fmt.Printf("Error accessing field: %v\n", err)
-							continue
-						}
-						targetDiffCriteriaField, err := getNestedFieldValue(reflect.ValueOf(targetItem), strings.Split(v, "."))
-						if err != nil {
-							fmt.Printf("Error accessing field: %v\n", err)
-							continue
-						}
-						xdiff := deep.Equal(sourceDiffCriteriaField.Interface(), targetDiffCriteriaField.Interface())
-						tmpDiff.Name = targetName.String()
-						tmpDiff.Namespace = sourceNamespace.String()
-						tmpDiff.Diff = xdiff
-						tmpDiff.PropertyName = v
-						diffSourceTarget = append(diffSourceTarget, tmpDiff)
-					}
-				}
-			}
-		}
-	} else {
+	if sourceObject == nil || targetObject == nil {
 		fmt.Println("'Items' field is not a slice in source or target object.")
+		return diffSourceTarget, nil
+	}
+	sourceItems := listItems(sourceObject)
+	targetItems := listItems(targetObject)
+	targetIndex := objectIndex(targetItems)
+	for _, sourceItem := range sourceItems {
+		namespace, name := objectKey(sourceItem)
+		j, found := targetIndex[namespace+"/"+name]
+		if !found {
+			continue
+		}
+		// DiffByCriteria puts the pair's Target on the left of "!=", which is this function's source.
+		pair := Pair{Name: name, Namespace: namespace, Source: targetItems[j].Interface(), Target: sourceItem.Interface()}
+		sourceFingerprint := Fingerprint(pair.Target, DiffCriteria)
+		targetFingerprint := Fingerprint(pair.Source, DiffCriteria)
+		var diffs []DAO.DiffWithName
+		if sourceFingerprint == targetFingerprint {
+			diffs = identicalPair(pair, DiffCriteria)
+		} else {
+			diffs = DiffByCriteria(pair, DiffCriteria)
+		}
+		for _, diff := range diffs {
+			diff.SourceFingerprint = sourceFingerprint
+			diff.TargetFingerprint = targetFingerprint
+			diffSourceTarget = append(diffSourceTarget, diff)
+		}
 	}
 
 	return diffSourceTarget, nil
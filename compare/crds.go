@@ -1,31 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 )
 
+// CRDComparator lists and compares custom resource definitions.
+var CRDComparator = &BaseComparator{
+	KindName:      "crd",
+	KindAliases:   []string{"crd", "crds", "customresourcedefinition", "customresourcedefinitions"},
+	Group:         "apiextensions.k8s.io",
+	Kind:          "CustomResourceDefinition",
+	ClusterScoped: true,
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListCRDs(cluster.Context, cluster.Kubeconfig)
+	},
+	Criteria: []string{"Spec", "Name"},
+}
+
 // Compare CRDs using generic functions from module "compare"
 func CompareCRDs(targetContext, configFile string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceCRDs, err := query.ListCRDs("", configFile)
-	if err != nil {
-		fmt.Printf("Error getting CRDs list: %v\n", err)
-		return TheDiff, err
-	}
-	targetCRDs, err := query.ListCRDs(targetContext, configFile)
-	if err != nil {
-		fmt.Printf("Error getting CRDs list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceCRDs, targetCRDs, diffCriteria, TheArgs)
+	return CompareKind(CRDComparator, Cluster{Kubeconfig: configFile}, Cluster{Context: targetContext, Kubeconfig: configFile}, "", TheArgs)
 }
@@ -1,32 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// CronJobComparator lists and compares cron jobs.
+var CronJobComparator = &BaseComparator{
+	KindName:    "cronjob",
+	KindAliases: []string{"cronjob", "cronjobs", "cj"},
+	Group:       "batch",
+	Kind:        "CronJob",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListCronJobs(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Spec", "Name"},
+}
+
 func CompareCronJobs(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceCronJobs, err := query.ListCronJobs(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	targetCronJobs, err := query.ListCronJobs(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceCronJobs, targetCronJobs, diffCriteria, TheArgs)
+	return CompareKind(CronJobComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
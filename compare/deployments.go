@@ -5,29 +5,43 @@ import (
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// DeploymentComparator lists and compares deployments.
+var DeploymentComparator = &BaseComparator{
+	KindName:    "deployment",
+	KindAliases: []string{"deployment", "deployments", "deploy"},
+	Group:       "apps",
+	Kind:        "Deployment",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListDeployments(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Spec.Template.Spec", "Name"},
+	Related:  relatedHPAs,
+}
+
 // compare deployments for a namespace
 func CompareDeployments(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceDeployments, err := query.ListDeployments(clientsetToSource, namespaceName)
+	return CompareKind(DeploymentComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
+}
+
+// relatedHPAs lists the HPAs of both clusters so replica counts they manage can be ignored.
+// A failure only disables the HPA-aware rule, it does not stop the Deployment comparison.
+func relatedHPAs(scope Scope) Related {
+	var related Related
+	sourceHPAs, err := query.ListHPAs(scope.Source.Clientset, scope.Namespace)
 	if err != nil {
-		fmt.Printf("Error getting deployments list: %v\n", err)
-		return TheDiff, err
+		fmt.Printf("Warning: could not list HPAs, replica counts will be compared: %v\n", err)
+		return related
 	}
-	targetDeplotments, err := query.ListDeployments(clientsetToTarget, namespaceName)
+	targetHPAs, err := query.ListHPAs(scope.Target.Clientset, scope.Namespace)
 	if err != nil {
-		fmt.Printf("Error getting deployments list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec.Template.Spec", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
+		fmt.Printf("Warning: could not list HPAs, replica counts will be compared: %v\n", err)
+		return related
 	}
-	return CompareVerboseVSNonVerbose(sourceDeployments, targetDeplotments, diffCriteria, TheArgs)
+	related.SourceHPAs = sourceHPAs
+	related.TargetHPAs = targetHPAs
+	return related
 }
@@ -0,0 +1,142 @@
+package compare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Fingerprint returns a canonical hash of the compared fields of an object.
+// Two objects with the same fingerprint for the same criteria have no differences on those fields,
+// so their deep diff can be skipped. The fields are hashed the way deep.Equal compares them, see
+// writeCanonical. Fingerprints are stable across runs: map keys are sorted and the criteria are
+// hashed in the order given.
+func Fingerprint(obj interface{}, criteria []string) string {
+	hash := sha256.New()
+	value := reflect.ValueOf(obj)
+	for _, criterion := range criteria {
+		hash.Write([]byte(criterion))
+		hash.Write([]byte{0})
+		field, err := getNestedFieldValue(value, strings.Split(criterion, "."))
+		if err != nil {
+			hash.Write([]byte("<missing>"))
+		} else {
+			writeCanonical(hash, field)
+		}
+		hash.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	quantityType = reflect.TypeOf(resource.Quantity{})
+)
+
+// writeCanonical writes a value so that two values deep.Equal finds equal are written the same:
+// unexported fields are left out, nil and empty slices and maps differ, and the types deep.Equal
+// compares with their Equal method are written in a canonical form, e.g. "1000m" and "1" as the
+// same quantity. Other types with an Equal method are written as JSON, the closest to their semantics.
+func writeCanonical(w io.Writer, v reflect.Value) {
+	if !v.IsValid() {
+		io.WriteString(w, "<invalid>")
+		return
+	}
+	if v.CanInterface() {
+		switch v.Type() {
+		case timeType:
+			fmt.Fprintf(w, "time(%s)", v.Interface().(time.Time).UTC().Format(time.RFC3339Nano))
+			return
+		case quantityType:
+			quantity := v.Interface().(resource.Quantity)
+			fmt.Fprintf(w, "quantity(%s)", canonicalDecimal(quantity.AsDec().String()))
+			return
+		}
+		if _, hasEqual := v.Type().MethodByName("Equal"); hasEqual {
+			if encoded, err := json.Marshal(v.Interface()); err == nil {
+				w.Write(encoded)
+				return
+			}
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			io.WriteString(w, "nil")
+			return
+		}
+		if v.Kind() == reflect.Interface {
+			fmt.Fprintf(w, "%s:", v.Elem().Type())
+		}
+		writeCanonical(w, v.Elem())
+	case reflect.Struct:
+		io.WriteString(w, "{")
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.PkgPath == "" {
+				fmt.Fprintf(w, "%s:", field.Name)
+				writeCanonical(w, v.Field(i))
+				io.WriteString(w, ",")
+			}
+		}
+		io.WriteString(w, "}")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			io.WriteString(w, "nil")
+			return
+		}
+		io.WriteString(w, "[")
+		for i := 0; i < v.Len(); i++ {
+			writeCanonical(w, v.Index(i))
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "]")
+	case reflect.Map:
+		if v.IsNil() {
+			io.WriteString(w, "nil")
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		io.WriteString(w, "map[")
+		for _, key := range keys {
+			writeCanonical(w, key)
+			io.WriteString(w, ":")
+			writeCanonical(w, v.MapIndex(key))
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "]")
+	case reflect.String:
+		io.WriteString(w, strconv.Quote(v.String()))
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		fmt.Fprintf(w, "<%s>", v.Kind())
+	default:
+		fmt.Fprintf(w, "%v", v)
+	}
+}
+
+// canonicalDecimal drops the trailing zeros of a decimal, "1.000" being the same as "1".
+func canonicalDecimal(decimal string) string {
+	if !strings.Contains(decimal, ".") {
+		return decimal
+	}
+	return strings.TrimSuffix(strings.TrimRight(decimal, "0"), ".")
+}
+
+// objectIndex maps "namespace/name" to the position of each item in a list.
+func objectIndex(items []reflect.Value) map[string]int {
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		namespace, name := objectKey(item)
+		index[namespace+"/"+name] = i
+	}
+	return index
+}
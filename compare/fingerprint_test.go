@@ -0,0 +1,78 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFingerprint(t *testing.T) {
+	web := v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", ResourceVersion: "1"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(2)}}
+	sameSpec := v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", ResourceVersion: "2"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(2)}}
+	otherSpec := v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(3)}}
+	criteria := []string{"Spec", "Name"}
+
+	if Fingerprint(web, criteria) != Fingerprint(sameSpec, criteria) {
+		t.Errorf("Expected fields outside the criteria not to change the fingerprint")
+	}
+	if Fingerprint(web, criteria) == Fingerprint(otherSpec, criteria) {
+		t.Errorf("Expected a different spec to change the fingerprint")
+	}
+	if Fingerprint(web, []string{"Spec"}) == Fingerprint(web, []string{"Name"}) {
+		t.Errorf("Expected the criteria to be part of the fingerprint")
+	}
+}
+
+func TestFingerprintFollowsDeepEqual(t *testing.T) {
+	withResources := func(cpu string, args []string) Corev1.Container {
+		return Corev1.Container{
+			Name:      "web",
+			Args:      args,
+			Resources: Corev1.ResourceRequirements{Requests: Corev1.ResourceList{Corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}
+	}
+	for _, c := range []struct {
+		name           string
+		source, target Corev1.Container
+	}{
+		{"quantity spellings", withResources("1000m", nil), withResources("1", nil)},
+		{"binary and decimal quantities", withResources("1Gi", nil), withResources("1073741824", nil)},
+		{"nil and empty slices", withResources("1", nil), withResources("1", []string{})},
+		{"different values", withResources("1", []string{"--debug"}), withResources("1", []string{"--verbose"})},
+	} {
+		equal := len(deep.Equal(c.source, c.target)) == 0
+		sameFingerprint := Fingerprint(c.source, []string{"Resources", "Args"}) == Fingerprint(c.target, []string{"Resources", "Args"})
+		if equal != sameFingerprint {
+			t.Errorf("%s: deep.Equal finds them equal: %v, same fingerprint: %v", c.name, equal, sameFingerprint)
+		}
+	}
+}
+
+func TestDeepCompareSkipsIdenticalPairs(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "same"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(1)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "changed"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(1)}},
+	}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "changed"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(4)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "same"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(1)}},
+	}}
+
+	diffs, err := DeepCompare(source, target, []string{"Spec.Replicas"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(diffs))
+	}
+	if diffs[0].Name != "same" || diffs[0].Diff != nil || diffs[0].SourceFingerprint != diffs[0].TargetFingerprint {
+		t.Errorf("Expected 'same' to be identical, got %+v", diffs[0])
+	}
+	if diffs[1].Name != "changed" || len(diffs[1].Diff) != 1 || diffs[1].Diff[0] != "1 != 4" {
+		t.Errorf("Expected 'changed' to differ by 1 != 4, got %+v", diffs[1])
+	}
+}
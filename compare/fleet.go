@@ -0,0 +1,107 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+
+	"kompare/plugins"
+)
+
+// severityWeights rank clusters: a missing object weighs more than a differing field.
+var severityWeights = map[string]int{
+	plugins.SeverityError:   3,
+	plugins.SeverityWarning: 2,
+	plugins.SeverityInfo:    1,
+}
+
+// Deviation is a cluster whose value of a field is not the fleet's expected value.
+type Deviation struct {
+	Cluster  string
+	Row      MatrixRow
+	Expected string
+	Actual   string
+	Severity string
+}
+
+func (d Deviation) String() string {
+	object := d.Row.Kind + " " + d.Row.Namespace + "/" + d.Row.Name
+	if d.Row.Namespace == "" {
+		object = d.Row.Kind + " " + d.Row.Name
+	}
+	if d.Row.Field == "" {
+		if d.Actual == missingValue {
+			return fmt.Sprintf("%s: %s is missing", d.Cluster, object)
+		}
+		return fmt.Sprintf("%s: %s is not expected", d.Cluster, object)
+	}
+	return fmt.Sprintf("%s: %s %s is %s, expected %s", d.Cluster, object, d.Row.Field, d.Actual, d.Expected)
+}
+
+// ClusterRank is how far a cluster is from the rest of the fleet.
+type ClusterRank struct {
+	Cluster    string
+	Deviations int
+	// Score sums the weights of the deviations' severities.
+	Score int
+}
+
+// FleetDeviations returns, for every matrix row, the clusters that do not have the expected value.
+// The expected value is the golden cluster's when it has one, otherwise the value most clusters share;
+// on a tie, the value of the cluster listed first wins.
+func FleetDeviations(rows []MatrixRow, golden string) []Deviation {
+	var deviations []Deviation
+	for _, row := range rows {
+		expected := -1
+		for i, group := range row.Groups {
+			for _, cluster := range group.Clusters {
+				if cluster == golden {
+					expected = i
+				}
+			}
+		}
+		if expected < 0 {
+			expected = 0
+			for i, group := range row.Groups {
+				if len(group.Clusters) > len(row.Groups[expected].Clusters) {
+					expected = i
+				}
+			}
+		}
+		for i, group := range row.Groups {
+			if i == expected {
+				continue
+			}
+			severity := plugins.SeverityWarning
+			if group.Value == missingValue || row.Groups[expected].Value == missingValue {
+				severity = plugins.SeverityError
+			}
+			for _, cluster := range group.Clusters {
+				deviations = append(deviations, Deviation{Cluster: cluster, Row: row, Expected: row.Groups[expected].Value, Actual: group.Value, Severity: severity})
+			}
+		}
+	}
+	return deviations
+}
+
+// RankClusters orders the clusters from the most to the least deviating. Clusters without deviations are listed last.
+func RankClusters(clusters []string, deviations []Deviation) []ClusterRank {
+	ranks := make([]ClusterRank, len(clusters))
+	index := map[string]int{}
+	for i, cluster := range clusters {
+		ranks[i] = ClusterRank{Cluster: cluster}
+		index[cluster] = i
+	}
+	for _, deviation := range deviations {
+		if i, found := index[deviation.Cluster]; found {
+			ranks[i].Deviations++
+			ranks[i].Score += severityWeights[deviation.Severity]
+		}
+	}
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].Score != ranks[j].Score {
+			return ranks[i].Score > ranks[j].Score
+		}
+		return ranks[i].Deviations > ranks[j].Deviations
+	})
+	return ranks
+}
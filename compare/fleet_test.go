@@ -0,0 +1,48 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+)
+
+func fleetRows() []MatrixRow {
+	return []MatrixRow{
+		{Kind: "deployment", Namespace: "payments", Name: "api", Field: "Spec.Template.Spec.containers[0].image", Groups: []ValueGroup{
+			{Value: `"api:1.2"`, Clusters: []string{"eu-west-1", "us-east-1"}},
+			{Value: `"api:1.3"`, Clusters: []string{"eu-west-2"}},
+		}},
+		{Kind: "deployment", Namespace: "payments", Name: "worker", Groups: []ValueGroup{
+			{Value: "present", Clusters: []string{"eu-west-1", "eu-west-2"}},
+			{Value: missingValue, Clusters: []string{"us-east-1"}},
+		}},
+	}
+}
+
+func TestFleetDeviationsByMajority(t *testing.T) {
+	deviations := FleetDeviations(fleetRows(), "")
+
+	var lines []string
+	for _, deviation := range deviations {
+		lines = append(lines, deviation.String())
+	}
+	expected := []string{
+		`eu-west-2: deployment payments/api Spec.Template.Spec.containers[0].image is "api:1.3", expected "api:1.2"`,
+		"us-east-1: deployment payments/worker is missing",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+
+	ranks := RankClusters([]string{"eu-west-1", "eu-west-2", "us-east-1"}, deviations)
+	if ranks[0].Cluster != "us-east-1" || ranks[1].Cluster != "eu-west-2" || ranks[2].Deviations != 0 {
+		t.Errorf("Expected us-east-1 (missing object) to rank first, got %+v", ranks)
+	}
+}
+
+func TestFleetDeviationsAgainstGolden(t *testing.T) {
+	deviations := FleetDeviations(fleetRows()[:1], "eu-west-2")
+
+	if len(deviations) != 2 || deviations[0].Cluster != "eu-west-1" || deviations[0].Expected != `"api:1.3"` {
+		t.Errorf("Expected the clusters that differ from the golden cluster, got %+v", deviations)
+	}
+}
@@ -1,32 +1,34 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// HPAComparator lists and compares horizontal pod autoscalers.
+var HPAComparator = &BaseComparator{
+	KindName:    "hpa",
+	KindAliases: []string{"hpa", "horizontalpodautoscaler", "horizontalpodautoscalers", "hpas"},
+	Group:       "autoscaling",
+	Kind:        "HorizontalPodAutoscaler",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListHPAs(cluster.Clientset, namespace)
+	},
+	// autoscaling/v1 only knows CPU utilization; v2 keeps memory and custom metrics, so it is preferred.
+	VersionedListers: []VersionedLister{
+		{Version: "autoscaling/v2", List: func(cluster Cluster, namespace string) (interface{}, error) {
+			return query.ListHPAsV2(cluster.Clientset, namespace)
+		}},
+		{Version: "autoscaling/v1", List: func(cluster Cluster, namespace string) (interface{}, error) {
+			return query.ListHPAs(cluster.Clientset, namespace)
+		}},
+	},
+	Criteria: []string{"Spec", "Name"},
+}
+
 func CompareHPAs(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceHPAs, err := query.ListHPAs(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	targetHPAs, err := query.ListHPAs(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceHPAs, targetHPAs, diffCriteria, TheArgs)
+	return CompareKind(HPAComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
@@ -0,0 +1,64 @@
+package compare
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kompare/DAO"
+	"kompare/cli"
+	"kompare/state"
+
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// countingComparator counts the pairs it diffs.
+type countingComparator struct {
+	Comparator
+	diffed int
+}
+
+func (c *countingComparator) Diff(pair Pair, criteria []string) []DAO.DiffWithName {
+	c.diffed++
+	return c.Comparator.Diff(pair, criteria)
+}
+
+func TestShowComparisonIncremental(t *testing.T) {
+	deployments, _ := Lookup("deployment")
+	c := &countingComparator{Comparator: deployments}
+	path := filepath.Join(t.TempDir(), "state.json")
+	clusters := []state.Cluster{{Context: "source", UID: "1"}, {Context: "target", UID: "2"}}
+	now := time.Now()
+	defer func() { Incremental = nil }()
+	list := func(sourceVersion string, replicas int32) (*v1.DeploymentList, *v1.DeploymentList) {
+		return &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", ResourceVersion: sourceVersion}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(replicas)}}}},
+			&v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", ResourceVersion: "7"}, Spec: v1.DeploymentSpec{Replicas: int32Ptr(3)}}}}
+	}
+	run := func(sourceVersion string, replicas int32) []DAO.DiffWithName {
+		var err error
+		Incremental, _, err = state.Load(path, clusters[0], clusters[1], now)
+		if err != nil {
+			t.Fatalf("Expected no error loading the state, got: %v", err)
+		}
+		source, target := list(sourceVersion, replicas)
+		diffs := showComparison(c, source, target, []string{"Spec.Replicas"}, cli.ArgumentsReceivedValidated{}, map[string]string{})
+		if err := Incremental.Save(path); err != nil {
+			t.Fatalf("Expected no error saving the state, got: %v", err)
+		}
+		return diffs
+	}
+
+	first := run("1", 2)
+	second := run("1", 2)
+	if c.diffed != 1 {
+		t.Errorf("Expected the unchanged pair not to be diffed again, diffed %d times", c.diffed)
+	}
+	if len(second) != 1 || len(first) != 1 || second[0].Diff[0] != first[0].Diff[0] || second[0].Kind != "deployment" {
+		t.Errorf("Expected the findings to be carried forward, got %+v then %+v", first, second)
+	}
+	third := run("2", 4)
+	if c.diffed != 2 || len(third) != 1 || third[0].Diff[0] == first[0].Diff[0] {
+		t.Errorf("Expected the changed pair to be diffed again, diffed %d times, got %+v", c.diffed, third)
+	}
+}
@@ -1,32 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// IngressComparator lists and compares ingresses.
+var IngressComparator = &BaseComparator{
+	KindName:    "ingress",
+	KindAliases: []string{"ingress", "ing"},
+	Group:       "networking.k8s.io",
+	Kind:        "Ingress",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListIngresses(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Spec", "Name", "Annotations"},
+}
+
 func CompareIngresses(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceIngresses, err := query.ListIngresses(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	targetIngresses, err := query.ListIngresses(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec", "Name", "Annotations"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceIngresses, targetIngresses, diffCriteria, TheArgs)
+	return CompareKind(IngressComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
@@ -0,0 +1,29 @@
+package compare
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Lint, when set, receives the objects of every kind listed for a comparison, once per cluster and
+// before any is left out or normalized, e.g. lint.Linter.Check. Kinds are canonical names.
+var Lint func(kind, cluster string, objects []metav1.Object)
+
+// lintLists sends the objects of the lists, listed in the clusters of the same index, to Lint.
+func lintLists(c Comparator, clusters []Cluster, lists []interface{}) {
+	if Lint == nil {
+		return
+	}
+	for i, list := range lists {
+		var objects []metav1.Object
+		for _, item := range listItems(list) {
+			if !item.CanAddr() {
+				continue
+			}
+			if obj, err := meta.Accessor(item.Addr().Interface()); err == nil {
+				objects = append(objects, obj)
+			}
+		}
+		Lint(c.Name(), ClusterName(clusters[i]), objects)
+	}
+}
@@ -0,0 +1,318 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"kompare/cli"
+	"kompare/tools"
+)
+
+// missingValue is the value of any field of an object a cluster does not have.
+const missingValue = "<missing>"
+
+// ValueGroup is a value and the clusters that have it.
+type ValueGroup struct {
+	Value    string
+	Clusters []string
+}
+
+// MatrixRow is one field of one object that does not have the same value in every cluster.
+type MatrixRow struct {
+	Kind, Namespace, Name string
+	// Field is the criteria field followed by the path inside it, e.g. "Spec.Template.Spec.containers[0].image".
+	// It is empty for an object that some clusters do not have.
+	Field  string
+	Groups []ValueGroup
+}
+
+// ClusterName is how a cluster is named in reports: its context, or "current context".
+func ClusterName(cluster Cluster) string {
+	if cluster.Context == "" {
+		return "current context"
+	}
+	return cluster.Context
+}
+
+// CompareKindMatrix lists a kind in every cluster and returns the fields that are not the same in all of them.
+// Objects are matched with the comparator's Pair and fields compared with its Diff, as in a comparison of two
+// clusters; the values shown are the JSON leaves of the fields that Diff finds different.
+// Object annotations apply across all clusters; the normalizers see each cluster on its own, so that,
+// e.g., the replicas of a Deployment are ignored in the clusters where an HPA manages them.
+func CompareKindMatrix(c Comparator, clusters []Cluster, namespaceName string, args cli.ArgumentsReceivedValidated) ([]MatrixRow, error) {
+	lists := make([]interface{}, len(clusters))
+	version := negotiateVersion(c, clusters...)
+	if version != "" && args.VerboseDiffs != 0 {
+		fmt.Printf("- Comparing %s at %s\n", c.Name(), version)
+	}
+	for i, cluster := range clusters {
+		list, err := listVersion(c, cluster, namespaceName, version)
+		if err != nil {
+			fmt.Printf("Error getting %s list in %s: %v\n", c.Name(), ClusterName(cluster), err)
+			return nil, err
+		}
+		lists[i] = list
+	}
+	lintLists(c, clusters, lists)
+	// --settle mark and recheck cannot be used with --contexts: in-flight objects are left out.
+	settleObjects(cli.SettleOptions{}, InFlight(args.Settle, time.Now(), lists...), lists...)
+	applyObjectAnnotations(lists...)
+	for i, cluster := range clusters {
+		scope := Scope{Source: cluster, Target: cluster, Namespace: namespaceName, Args: args}
+		for _, normalize := range c.Normalizers() {
+			normalize(lists[i], lists[i], scope)
+		}
+	}
+	criteria := c.DefaultCriteria()
+	if args.FiltersForObject != "" {
+		criteria = tools.ParseCommaSeparateList(args.FiltersForObject)
+	}
+
+	names := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = ClusterName(cluster)
+	}
+	var rows []MatrixRow
+	for _, object := range pairClusters(c, lists) {
+		presence := make([]string, len(clusters))
+		// Fields are only compared between the clusters that have the object.
+		var present []int
+		for i, item := range object.items {
+			presence[i] = missingValue
+			if item != nil {
+				presence[i] = "present"
+				present = append(present, i)
+			}
+		}
+		fields := []matrixField{{values: presence, clusters: names}}
+		for _, criterion := range criteria {
+			fields = append(fields, diffCriterion(c, object, present, names, criterion)...)
+		}
+		for _, field := range fields {
+			if groups := groupValues(field.clusters, field.values); len(groups) > 1 {
+				rows = append(rows, MatrixRow{Kind: c.Name(), Namespace: object.namespace, Name: object.name, Field: field.name, Groups: groups})
+			}
+		}
+	}
+	return rows, nil
+}
+
+// matrixObject is an object of the matrix and its item in each cluster, nil in the clusters that do not have it.
+type matrixObject struct {
+	namespace, name string
+	items           []interface{}
+}
+
+// matrixField is the value of a field in each of the clusters named.
+type matrixField struct {
+	name     string
+	clusters []string
+	values   []string
+}
+
+// pairClusters matches the objects of each cluster, with the comparator's Pair, to the objects of the clusters before it.
+func pairClusters(c Comparator, lists []interface{}) []*matrixObject {
+	var objects []*matrixObject
+	index := map[string]*matrixObject{}
+	var seen []interface{}
+	for i, list := range lists {
+		for _, pair := range c.Pair(listWithItems(list, seen), list) {
+			if pair.Target == nil {
+				continue
+			}
+			key := pair.Namespace + "/" + pair.Name
+			object := index[key]
+			if pair.Source == nil || object == nil || object.items[i] != nil {
+				object = &matrixObject{namespace: pair.Namespace, name: pair.Name, items: make([]interface{}, len(lists))}
+				objects = append(objects, object)
+				seen = append(seen, pair.Target)
+				if index[key] == nil {
+					index[key] = object
+				}
+			}
+			object.items[i] = pair.Target
+		}
+	}
+	return objects
+}
+
+// listWithItems returns a new list of the type of list holding items.
+func listWithItems(list interface{}, items []interface{}) interface{} {
+	if list == nil || !hasItemsField(list) {
+		return list
+	}
+	copied := reflect.New(reflect.TypeOf(list).Elem())
+	itemsField := copied.Elem().FieldByName("Items")
+	values := reflect.MakeSlice(itemsField.Type(), 0, len(items))
+	for _, item := range items {
+		value := reflect.Indirect(reflect.ValueOf(item))
+		if value.Type().AssignableTo(itemsField.Type().Elem()) {
+			values = reflect.Append(values, value)
+		}
+	}
+	itemsField.Set(values)
+	return copied.Interface()
+}
+
+// diffCriterion groups the clusters that have an object by the comparator's Diff of one criterion: a cluster joins
+// the first group whose first cluster it has no difference with. When there is more than one group, it returns
+// the JSON leaves of the criterion that differ between the groups, or, when the leaves are the same or cannot be
+// read, the differences Diff reports against the first group.
+func diffCriterion(c Comparator, object *matrixObject, present []int, names []string, criterion string) []matrixField {
+	differences := func(first, other int) []string {
+		var lines []string
+		pair := Pair{Name: object.name, Namespace: object.namespace, Source: object.items[first], Target: object.items[other]}
+		for _, diff := range c.Diff(pair, []string{criterion}) {
+			lines = append(lines, diff.Diff...)
+		}
+		return lines
+	}
+	var groups [][]int
+	group := map[int]int{}
+	for _, i := range present {
+		found := false
+		for g := range groups {
+			if len(differences(groups[g][0], i)) == 0 {
+				groups[g] = append(groups[g], i)
+				group[i], found = g, true
+				break
+			}
+		}
+		if !found {
+			group[i] = len(groups)
+			groups = append(groups, []int{i})
+		}
+	}
+	if len(groups) < 2 {
+		return nil
+	}
+	clusters := make([]string, len(present))
+	for i, cluster := range present {
+		clusters[i] = names[cluster]
+	}
+
+	leaves := make([]map[string]string, len(groups))
+	paths := map[string]bool{}
+	for g := range groups {
+		field, err := getNestedFieldValue(reflect.ValueOf(object.items[groups[g][0]]), strings.Split(criterion, "."))
+		if err != nil {
+			paths = nil
+			break
+		}
+		leaves[g] = flattenValue(field.Interface())
+		for path := range leaves[g] {
+			paths[path] = true
+		}
+	}
+	sortedPaths := make([]string, 0, len(paths))
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+	var fields []matrixField
+	for _, path := range sortedPaths {
+		values := make([]string, len(present))
+		for i, cluster := range present {
+			value, found := leaves[group[cluster]][path]
+			if !found {
+				value = missingValue
+			}
+			values[i] = value
+		}
+		if len(groupValues(clusters, values)) > 1 {
+			fields = append(fields, matrixField{name: criterion + path, clusters: clusters, values: values})
+		}
+	}
+	if len(fields) != 0 {
+		return fields
+	}
+	values := make([]string, len(present))
+	for i, cluster := range present {
+		values[i] = "as in " + names[groups[0][0]]
+		if g := group[cluster]; g != 0 {
+			values[i] = strings.Join(differences(groups[0][0], groups[g][0]), "; ")
+		}
+	}
+	return []matrixField{{name: criterion, clusters: clusters, values: values}}
+}
+
+// groupValues groups the clusters by value, in the order the values first appear.
+func groupValues(names, values []string) []ValueGroup {
+	var groups []ValueGroup
+	index := map[string]int{}
+	for i, value := range values {
+		position, found := index[value]
+		if !found {
+			position = len(groups)
+			index[value] = position
+			groups = append(groups, ValueGroup{Value: value})
+		}
+		groups[position].Clusters = append(groups[position].Clusters, names[i])
+	}
+	return groups
+}
+
+// flattenValue returns the JSON leaves of a value keyed by their path, e.g. ".containers[0].image".
+// The value itself is the leaf with path "" when it is a scalar.
+func flattenValue(value interface{}) map[string]string {
+	leaves := map[string]string{}
+	content, err := json.Marshal(value)
+	if err != nil {
+		leaves[""] = fmt.Sprintf("%v", value)
+		return leaves
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		leaves[""] = string(content)
+		return leaves
+	}
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		switch typed := v.(type) {
+		case map[string]interface{}:
+			if len(typed) == 0 {
+				leaves[path] = "{}"
+			}
+			for key, child := range typed {
+				walk(path+"."+key, child)
+			}
+		case []interface{}:
+			if len(typed) == 0 {
+				leaves[path] = "[]"
+			}
+			for i, child := range typed {
+				walk(fmt.Sprintf("%s[%d]", path, i), child)
+			}
+		default:
+			encoded, _ := json.Marshal(typed)
+			leaves[path] = string(encoded)
+		}
+	}
+	walk("", decoded)
+	return leaves
+}
+
+// FormatMatrix writes matrix rows grouped by object, with the clusters sharing each value.
+func FormatMatrix(rows []MatrixRow) string {
+	var formatted strings.Builder
+	object := ""
+	for _, row := range rows {
+		if current := row.Kind + " " + row.Namespace + "/" + row.Name; current != object {
+			object = current
+			formatted.WriteString(fmt.Sprintf("%s\n", strings.Replace(current, " /", " ", 1)))
+		}
+		field := row.Field
+		if field == "" {
+			field = "object"
+		}
+		formatted.WriteString(fmt.Sprintf("  %s:\n", field))
+		for _, group := range row.Groups {
+			formatted.WriteString(fmt.Sprintf("    %s in %s\n", group.Value, strings.Join(group.Clusters, ", ")))
+		}
+	}
+	return formatted.String()
+}
@@ -0,0 +1,136 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"kompare/DAO"
+	"kompare/cli"
+
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deploymentWithImage(name, image string) v1.Deployment {
+	return v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.DeploymentSpec{Template: Corev1.PodTemplateSpec{Spec: Corev1.PodSpec{
+			Containers: []Corev1.Container{{Name: "app", Image: image}},
+		}}},
+	}
+}
+
+func TestCompareKindMatrix(t *testing.T) {
+	lists := map[string]*v1.DeploymentList{
+		"eu-west-1": {Items: []v1.Deployment{deploymentWithImage("api", "api:1.2"), deploymentWithImage("worker", "worker:1")}},
+		"eu-west-2": {Items: []v1.Deployment{deploymentWithImage("api", "api:1.3"), deploymentWithImage("worker", "worker:1")}},
+		"us-east-1": {Items: []v1.Deployment{deploymentWithImage("api", "api:1.2")}},
+	}
+	comparator := &BaseComparator{
+		KindName: "deployment",
+		Kind:     "Deployment",
+		Group:    "apps",
+		Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+			return lists[cluster.Context], nil
+		},
+		Criteria: []string{"Spec.Template.Spec"},
+	}
+	clusters := []Cluster{{Context: "eu-west-1"}, {Context: "eu-west-2"}, {Context: "us-east-1"}}
+
+	rows, err := CompareKindMatrix(comparator, clusters, "default", cli.ArgumentsReceivedValidated{})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := `deployment default/api
+  Spec.Template.Spec.containers[0].image:
+    "api:1.2" in eu-west-1, us-east-1
+    "api:1.3" in eu-west-2
+deployment default/worker
+  object:
+    present in eu-west-1, eu-west-2
+    <missing> in us-east-1
+`
+	if got := FormatMatrix(rows); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if !strings.Contains(rows[0].Field, "containers[0].image") || len(rows) != 2 {
+		t.Errorf("Unexpected rows %+v", rows)
+	}
+}
+
+// appComparator pairs Deployments by their app label, whatever their name, and can ignore every difference.
+type appComparator struct {
+	*BaseComparator
+	ignoreAll bool
+}
+
+func (c *appComparator) Pair(source, target interface{}) []Pair {
+	var pairs []Pair
+	matched := map[string]bool{}
+	for _, s := range source.(*v1.DeploymentList).Items {
+		pair := Pair{Name: s.Name, Namespace: s.Namespace, Source: s}
+		for _, t := range target.(*v1.DeploymentList).Items {
+			if t.Labels["app"] == s.Labels["app"] {
+				pair.Target = t
+				matched[t.Name] = true
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+	for _, t := range target.(*v1.DeploymentList).Items {
+		if !matched[t.Name] {
+			pairs = append(pairs, Pair{Name: t.Name, Namespace: t.Namespace, Target: t})
+		}
+	}
+	return pairs
+}
+
+func (c *appComparator) Diff(pair Pair, criteria []string) []DAO.DiffWithName {
+	if c.ignoreAll {
+		return nil
+	}
+	return c.BaseComparator.Diff(pair, criteria)
+}
+
+func TestCompareKindMatrixUsesPairAndDiff(t *testing.T) {
+	labeled := func(name, image string) v1.Deployment {
+		deployment := deploymentWithImage(name, image)
+		deployment.Labels = map[string]string{"app": "api"}
+		return deployment
+	}
+	lists := map[string]*v1.DeploymentList{
+		"eu-west-1": {Items: []v1.Deployment{labeled("api-eu1", "api:1.2")}},
+		"eu-west-2": {Items: []v1.Deployment{labeled("api-eu2", "api:1.3")}},
+	}
+	comparator := &appComparator{BaseComparator: &BaseComparator{
+		KindName: "deployment",
+		Kind:     "Deployment",
+		Group:    "apps",
+		Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+			return lists[cluster.Context], nil
+		},
+		Criteria: []string{"Spec.Template.Spec"},
+	}}
+	clusters := []Cluster{{Context: "eu-west-1"}, {Context: "eu-west-2"}}
+
+	rows, err := CompareKindMatrix(comparator, clusters, "default", cli.ArgumentsReceivedValidated{})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := `deployment default/api-eu1
+  Spec.Template.Spec.containers[0].image:
+    "api:1.2" in eu-west-1
+    "api:1.3" in eu-west-2
+`
+	if got := FormatMatrix(rows); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	comparator.ignoreAll = true
+	if rows, _ := CompareKindMatrix(comparator, clusters, "default", cli.ArgumentsReceivedValidated{}); len(rows) != 0 {
+		t.Errorf("Expected no rows when Diff finds no difference, got %+v", rows)
+	}
+}
@@ -0,0 +1,129 @@
+package compare
+
+import (
+	"fmt"
+	"sync"
+
+	"kompare/cli"
+)
+
+var (
+	registryMu    sync.RWMutex
+	registry      = map[string]Comparator{}
+	registryOrder []string
+)
+
+func init() {
+	// Built-in kinds, in the order they are compared.
+	for _, c := range []Comparator{
+		NamespaceComparator,
+		CRDComparator,
+		ClusterRoleComparator,
+		ClusterRoleBindingComparator,
+		DeploymentComparator,
+		IngressComparator,
+		ServiceComparator,
+		ServiceAccountComparator,
+		ConfigMapComparator,
+		SecretComparator,
+		RoleComparator,
+		RoleBindingComparator,
+		HPAComparator,
+		CronJobComparator,
+		NetworkPolicyComparator,
+	} {
+		MustRegister(c)
+	}
+}
+
+// Register makes a Comparator available to every comparison and to the -i and -e options,
+// under its name and aliases. It fails when the name or the GroupKind is already registered, or when
+// the name or an alias already stands for another kind.
+func Register(c Comparator) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if c.Name() == "" {
+		return fmt.Errorf("comparator for %s has no name", c.GroupKind())
+	}
+	for _, existing := range registry {
+		if existing.Name() == c.Name() {
+			return fmt.Errorf("comparator %q is already registered", c.Name())
+		}
+		if existing.GroupKind() == c.GroupKind() {
+			return fmt.Errorf("comparator %q already handles %s", existing.Name(), c.GroupKind())
+		}
+	}
+	for _, alias := range append([]string{c.Name()}, c.Aliases()...) {
+		if owner, found := cli.ObjectAliasOwner(alias); found && owner != c.Name() {
+			return fmt.Errorf("alias %q of comparator %q already stands for %q", alias, c.Name(), owner)
+		}
+	}
+	registry[c.Name()] = c
+	registryOrder = append(registryOrder, c.Name())
+	cli.RegisterObjectAliases(c.Name(), append([]string{c.Name()}, c.Aliases()...))
+	return nil
+}
+
+// MustRegister is Register for init functions; it panics when the comparator cannot be registered.
+func MustRegister(c Comparator) {
+	if err := Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the Comparator registered under a canonical name, and its -i and -e aliases.
+// It is meant for tests registering their own kinds.
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, found := registry[name]; !found {
+		return
+	}
+	delete(registry, name)
+	for i, registered := range registryOrder {
+		if registered == name {
+			registryOrder = append(registryOrder[:i:i], registryOrder[i+1:]...)
+			break
+		}
+	}
+	cli.UnregisterObjectAliases(name)
+}
+
+// Lookup returns the Comparator registered under a canonical name.
+func Lookup(name string) (Comparator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[name]
+	return c, ok
+}
+
+// Comparators returns every registered Comparator in registration order.
+func Comparators() []Comparator {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	comparators := make([]Comparator, 0, len(registryOrder))
+	for _, name := range registryOrder {
+		comparators = append(comparators, registry[name])
+	}
+	return comparators
+}
+
+// NamespacedKinds returns the names of the registered namespaced kinds in registration order.
+func NamespacedKinds() []string {
+	return kindNames(true)
+}
+
+// ClusterScopedKinds returns the names of the registered cluster-scoped kinds in registration order.
+func ClusterScopedKinds() []string {
+	return kindNames(false)
+}
+
+func kindNames(namespaced bool) []string {
+	var names []string
+	for _, c := range Comparators() {
+		if c.Namespaced() == namespaced {
+			names = append(names, c.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,131 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+
+	"kompare/cli"
+	"kompare/tools"
+
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestBuiltinComparatorsAreRegistered(t *testing.T) {
+	expectedNamespaced := []string{"deployment", "ingress", "service", "serviceaccount", "configmap", "secret", "role", "rolebinding", "hpa", "cronjob", "networkpolicy"}
+	if !reflect.DeepEqual(NamespacedKinds(), expectedNamespaced) {
+		t.Errorf("Expected namespaced kinds %v, got %v", expectedNamespaced, NamespacedKinds())
+	}
+	expectedClusterScoped := []string{"namespace", "crd", "clusterrole", "clusterrolebinding"}
+	if !reflect.DeepEqual(ClusterScopedKinds(), expectedClusterScoped) {
+		t.Errorf("Expected cluster-scoped kinds %v, got %v", expectedClusterScoped, ClusterScopedKinds())
+	}
+}
+
+func TestRegisterAddsAliasesToCLI(t *testing.T) {
+	widget := &BaseComparator{
+		KindName:    "widget",
+		KindAliases: []string{"widgets", "wdg"},
+		Group:       "example.com",
+		Kind:        "Widget",
+		Criteria:    []string{"Object.spec"},
+	}
+	if err := Register(widget); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	t.Cleanup(func() { Unregister("widget") })
+
+	invalid, valid := cli.ValidateKubernetesObjects([]string{"wdg", "cj"})
+	if invalid != nil {
+		t.Errorf("Expected no invalid objects, got %v", invalid)
+	}
+	if !reflect.DeepEqual(valid, []string{"widget", "cronjob"}) {
+		t.Errorf("Expected [widget cronjob], got %v", valid)
+	}
+	if c, found := Lookup("widget"); !found || c != Comparator(widget) {
+		t.Errorf("Expected to find the widget comparator")
+	}
+
+	duplicate := &BaseComparator{KindName: "gadget", Group: "example.com", Kind: "Widget"}
+	if err := Register(duplicate); err == nil {
+		t.Errorf("Expected an error when registering the same GroupKind twice")
+	}
+}
+
+func TestRegisterRejectsAliasOfAnotherKind(t *testing.T) {
+	for _, c := range []*BaseComparator{
+		{KindName: "deployer", KindAliases: []string{"deploy"}, Group: "example.com", Kind: "Deployer"},
+		{KindName: "cm", Group: "example.com", Kind: "ClusterManager"},
+	} {
+		if err := Register(c); err == nil {
+			Unregister(c.KindName)
+			t.Errorf("Expected an error when %s claims an alias of another kind", c.KindName)
+		}
+	}
+	if owner, _ := cli.ObjectAliasOwner("deploy"); owner != "deployment" {
+		t.Errorf("Expected deploy to still stand for deployment, got %q", owner)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	if err := Register(&BaseComparator{KindName: "gizmo", KindAliases: []string{"gz"}, Group: "example.com", Kind: "Gizmo"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	Unregister("gizmo")
+	if _, found := Lookup("gizmo"); found {
+		t.Errorf("Expected the gizmo comparator to be removed")
+	}
+	if invalid, _ := cli.ValidateKubernetesObjects([]string{"gz"}); len(invalid) != 1 {
+		t.Errorf("Expected the gizmo aliases to be removed")
+	}
+	if tools.IsInList("gizmo", NamespacedKinds()) {
+		t.Errorf("Expected gizmo not to be compared anymore")
+	}
+}
+
+func TestPairByName(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "only-source", Namespace: "default"}},
+	}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{
+		{ObjectMeta: metav1.ObjectMeta{Name: "only-target", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+	}}
+
+	pairs := PairByName(source, target)
+
+	if len(pairs) != 3 {
+		t.Fatalf("Expected 3 pairs, got %d", len(pairs))
+	}
+	if pairs[0].Name != "web" || pairs[0].Source == nil || pairs[0].Target == nil {
+		t.Errorf("Expected 'web' to be paired, got %+v", pairs[0])
+	}
+	if pairs[1].Name != "only-source" || pairs[1].Target != nil {
+		t.Errorf("Expected 'only-source' to have no target, got %+v", pairs[1])
+	}
+	if pairs[2].Name != "only-target" || pairs[2].Source != nil {
+		t.Errorf("Expected 'only-target' to have no source, got %+v", pairs[2])
+	}
+}
+
+func TestDiffByCriteriaOnUnstructured(t *testing.T) {
+	newWidget := func(size int64) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "w1", "namespace": "default"},
+			"spec":     map[string]interface{}{"size": size},
+		}}
+	}
+	source := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{newWidget(1)}}
+	target := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{newWidget(2)}}
+
+	pairs := PairByName(source, target)
+	if len(pairs) != 1 || pairs[0].Name != "w1" {
+		t.Fatalf("Expected unstructured objects to be paired by name, got %+v", pairs)
+	}
+	diffs := DiffByCriteria(pairs[0], []string{"Object.spec"})
+	if len(diffs) != 1 || len(diffs[0].Diff) != 1 {
+		t.Fatalf("Expected one difference, got %+v", diffs)
+	}
+}
@@ -1,33 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
-func CompareRoleBindings(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
+// RoleBindingComparator lists and compares role bindings.
+var RoleBindingComparator = &BaseComparator{
+	KindName:    "rolebinding",
+	KindAliases: []string{"rolebinding", "rolebindings"},
+	Group:       "rbac.authorization.k8s.io",
+	Kind:        "RoleBinding",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListRoleBindings(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"RoleRef", "Subjects"},
+}
 
-	var TheDiff []DAO.DiffWithName
-	sourceRoleBindings, err := query.ListRoleBindings(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting role bindings list: %v\n", err)
-		return TheDiff, err
-	}
-	targetRoleBindings, err := query.ListRoleBindings(clientsetToTarget, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting role bindings list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"RoleRef", "Subjects"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceRoleBindings, targetRoleBindings, diffCriteria, TheArgs)
+func CompareRoleBindings(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
+	return CompareKind(RoleBindingComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
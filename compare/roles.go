@@ -1,33 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
-func CompareRoles(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
+// RoleComparator lists and compares roles.
+var RoleComparator = &BaseComparator{
+	KindName:    "role",
+	KindAliases: []string{"role", "roles"},
+	Group:       "rbac.authorization.k8s.io",
+	Kind:        "Role",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListRoles(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Rules", "Name"},
+}
 
-	var TheDiff []DAO.DiffWithName
-	sourceRoles, err := query.ListRoles(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting roles list: %v\n", err)
-		return TheDiff, err
-	}
-	targetRoles, err := query.ListRoles(clientsetToTarget, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting roles list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Rules", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceRoles, targetRoles, diffCriteria, TheArgs)
+func CompareRoles(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
+	return CompareKind(RoleComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
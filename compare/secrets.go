@@ -1,32 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// SecretComparator lists and compares secrets.
+var SecretComparator = &BaseComparator{
+	KindName:    "secret",
+	KindAliases: []string{"secret", "secrets"},
+	Group:       "",
+	Kind:        "Secret",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListSecrets(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Annotations", "Name"},
+}
+
 func CompareSecrets(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceSecrets, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting secrets list: %v\n", err)
-		return TheDiff, err
-	}
-	targetSecrets, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting secrets list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Annotations", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceSecrets, targetSecrets, diffCriteria, TheArgs)
+	return CompareKind(SecretComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
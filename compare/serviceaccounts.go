@@ -1,32 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// ServiceAccountComparator lists and compares service accounts.
+var ServiceAccountComparator = &BaseComparator{
+	KindName:    "serviceaccount",
+	KindAliases: []string{"sa", "serviceaccount", "serviceaccounts"},
+	Group:       "",
+	Kind:        "ServiceAccount",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListServiceAccounts(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Annotations", "Name"},
+}
+
 func CompareServiceAccounts(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceServiceAccounts, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting service accounts list: %v\n", err)
-		return TheDiff, err
-	}
-	targetServiceAccounts, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting service accounts list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Annotations", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceServiceAccounts, targetServiceAccounts, diffCriteria, TheArgs)
+	return CompareKind(ServiceAccountComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
@@ -1,32 +1,25 @@
 package compare
 
 import (
-	"fmt"
 	"kompare/DAO"
 	"kompare/cli"
 	"kompare/query"
-	"kompare/tools"
 
 	"k8s.io/client-go/kubernetes"
 )
 
+// ServiceComparator lists and compares services.
+var ServiceComparator = &BaseComparator{
+	KindName:    "service",
+	KindAliases: []string{"service", "svc", "services"},
+	Group:       "",
+	Kind:        "Service",
+	Lister: func(cluster Cluster, namespace string) (interface{}, error) {
+		return query.ListServices(cluster.Clientset, namespace)
+	},
+	Criteria: []string{"Spec", "Name"},
+}
+
 func CompareServices(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespaceName string, TheArgs cli.ArgumentsReceivedValidated) ([]DAO.DiffWithName, error) {
-	var TheDiff []DAO.DiffWithName
-	sourceServices, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	targetServices, err := query.ListServices(clientsetToSource, namespaceName)
-	if err != nil {
-		fmt.Printf("Error getting services list: %v\n", err)
-		return TheDiff, err
-	}
-	var diffCriteria []string
-	if TheArgs.FiltersForObject == "" {
-		diffCriteria = []string{"Spec", "Name"}
-	} else {
-		diffCriteria = tools.ParseCommaSeparateList(TheArgs.FiltersForObject)
-	}
-	return CompareVerboseVSNonVerbose(sourceServices, targetServices, diffCriteria, TheArgs)
+	return CompareKind(ServiceComparator, Cluster{Clientset: clientsetToSource}, Cluster{Clientset: clientsetToTarget}, namespaceName, TheArgs)
 }
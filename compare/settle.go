@@ -0,0 +1,214 @@
+package compare
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"kompare/cli"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// InFlight returns why the objects of the lists are in flight, by "namespace/name", following the --settle options.
+// An object in flight in one cluster is in flight for the whole comparison.
+func InFlight(settle cli.SettleOptions, now time.Time, lists ...interface{}) map[string]string {
+	reasons := map[string]string{}
+	if !settle.Enabled() {
+		return reasons
+	}
+	for _, list := range lists {
+		for _, item := range listItems(list) {
+			namespace, name := objectKey(item)
+			key := namespace + "/" + name
+			if _, found := reasons[key]; found {
+				continue
+			}
+			if reason := inFlightReason(settle, now, item); reason != "" {
+				reasons[key] = reason
+			}
+		}
+	}
+	return reasons
+}
+
+// inFlightReason explains why an object is in flight, or returns "" when it has settled.
+func inFlightReason(settle cli.SettleOptions, now time.Time, item reflect.Value) string {
+	if !item.CanAddr() {
+		return ""
+	}
+	obj, err := meta.Accessor(item.Addr().Interface())
+	if err != nil {
+		return ""
+	}
+	if settle.Deleting && obj.GetDeletionTimestamp() != nil {
+		return "being deleted since " + obj.GetDeletionTimestamp().UTC().Format(time.RFC3339)
+	}
+	if created := obj.GetCreationTimestamp(); settle.Age > 0 && !created.IsZero() && now.Sub(created.Time) < settle.Age {
+		return fmt.Sprintf("created %s ago", now.Sub(created.Time).Round(time.Second))
+	}
+	if !settle.Reconcile {
+		return ""
+	}
+	if observed, found := int64Field(item, "Status", "ObservedGeneration"); found && observed < obj.GetGeneration() {
+		return fmt.Sprintf("observedGeneration %d is behind generation %d", observed, obj.GetGeneration())
+	}
+	return rolloutInProgress(item)
+}
+
+// rolloutInProgress describes the rollout of a Deployment, StatefulSet or DaemonSet that has not finished,
+// or returns "" for other objects and finished rollouts.
+func rolloutInProgress(item reflect.Value) string {
+	if desired, found := int64Field(item, "Status", "DesiredNumberScheduled"); found {
+		updated, _ := int64Field(item, "Status", "UpdatedNumberScheduled")
+		available, _ := int64Field(item, "Status", "NumberAvailable")
+		if updated < desired || available < desired {
+			return fmt.Sprintf("rollout in progress: %d of %d pods updated, %d available", updated, desired, available)
+		}
+		return ""
+	}
+	updated, hasUpdated := int64Field(item, "Status", "UpdatedReplicas")
+	current, hasCurrent := int64Field(item, "Status", "Replicas")
+	if !hasUpdated || !hasCurrent {
+		return ""
+	}
+	// A nil spec.replicas defaults to one replica.
+	desired := int64(1)
+	if replicas, found := int64Field(item, "Spec", "Replicas"); found {
+		desired = replicas
+	}
+	available, _ := int64Field(item, "Status", "AvailableReplicas")
+	if updated < desired || current > updated || available < updated {
+		return fmt.Sprintf("rollout in progress: %d of %d replicas updated, %d available", updated, desired, available)
+	}
+	return ""
+}
+
+// int64Field reads an integer field, or a non-nil pointer to one, of a typed object.
+func int64Field(item reflect.Value, fieldNames ...string) (int64, bool) {
+	field, err := getNestedFieldValue(item, fieldNames)
+	if err != nil {
+		return 0, false
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return 0, false
+		}
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return field.Int(), true
+	}
+	return 0, false
+}
+
+// settleObjects leaves the in-flight objects out of every list or, with Mark, returns their reasons to report their
+// differences as expected. It returns one message per in-flight object for verbose mode.
+func settleObjects(settle cli.SettleOptions, reasons map[string]string, lists ...interface{}) (map[string]string, []string) {
+	marked := map[string]string{}
+	if len(reasons) == 0 {
+		return marked, nil
+	}
+	keys := make([]string, 0, len(reasons))
+	for key := range reasons {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var messages []string
+	for _, key := range keys {
+		if settle.Mark {
+			marked[key] = "in flight: " + reasons[key]
+			messages = append(messages, fmt.Sprintf("Marking %s: in flight, %s", key, reasons[key]))
+		} else {
+			messages = append(messages, fmt.Sprintf("Skipping %s: in flight, %s", key, reasons[key]))
+		}
+	}
+	if !settle.Mark {
+		for _, list := range lists {
+			removeItems(list, func(item reflect.Value) bool {
+				namespace, name := objectKey(item)
+				_, found := reasons[namespace+"/"+name]
+				return found
+			})
+		}
+	}
+	return marked, messages
+}
+
+// pendingRecheck is a kind, in a namespace, whose in-flight objects RecheckInFlight compares again.
+type pendingRecheck struct {
+	comparator     Comparator
+	source, target Cluster
+	namespace      string
+	inFlight       map[string]string
+}
+
+var (
+	settleMu           sync.Mutex
+	pendingRechecks    []pendingRecheck
+	namespacesInFlight = map[string]string{}
+)
+
+// deferRecheck remembers the in-flight objects of a kind for RecheckInFlight.
+func deferRecheck(c Comparator, source, target Cluster, namespace string, inFlight map[string]string) {
+	settleMu.Lock()
+	defer settleMu.Unlock()
+	pendingRechecks = append(pendingRechecks, pendingRecheck{comparator: c, source: source, target: target, namespace: namespace, inFlight: inFlight})
+}
+
+// RecheckInFlight waits for the --settle recheck delay once, then compares again the objects that were in flight
+// during the run, so that only the ones still in flight are left out, or marked.
+func RecheckInFlight(args cli.ArgumentsReceivedValidated) {
+	settleMu.Lock()
+	pending := pendingRechecks
+	pendingRechecks = nil
+	settleMu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	count := 0
+	for _, recheck := range pending {
+		count += len(recheck.inFlight)
+	}
+	fmt.Printf("%d objects in flight, checking them again in %s\n", count, args.Settle.Recheck)
+	time.Sleep(args.Settle.Recheck)
+	for _, recheck := range pending {
+		if recheck.namespace != "" {
+			fmt.Printf("Checking %s again in namespace: %s\n", recheck.comparator.Name(), recheck.namespace)
+		} else {
+			fmt.Printf("Checking %s again\n", recheck.comparator.Name())
+		}
+		if _, err := compareKind(recheck.comparator, recheck.source, recheck.target, recheck.namespace, args, recheck.inFlight); err != nil {
+			fmt.Printf("Error checking %s again: %v\n", recheck.comparator.Name(), err)
+		}
+	}
+}
+
+// SetNamespacesInFlight replaces the namespaces whose objects are all in flight, with the reason,
+// e.g. the namespaces being deleted in a cluster when --settle mark keeps them in the comparison.
+func SetNamespacesInFlight(reasons map[string]string) {
+	settleMu.Lock()
+	defer settleMu.Unlock()
+	namespacesInFlight = reasons
+}
+
+// namespaceInFlight adds the objects of the lists to the in-flight objects when their namespace is in flight.
+func namespaceInFlight(settle cli.SettleOptions, reasons map[string]string, namespace string, lists ...interface{}) {
+	settleMu.Lock()
+	reason := namespacesInFlight[namespace]
+	settleMu.Unlock()
+	if reason == "" || !settle.Enabled() {
+		return
+	}
+	for _, list := range lists {
+		for _, item := range listItems(list) {
+			namespace, name := objectKey(item)
+			if _, found := reasons[namespace+"/"+name]; !found {
+				reasons[namespace+"/"+name] = reason
+			}
+		}
+	}
+}
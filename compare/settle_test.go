@@ -0,0 +1,102 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"kompare/cli"
+
+	v1 "k8s.io/api/apps/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rolledOut is a Deployment whose controller caught up with its 3 replicas.
+func rolledOut(name string, created time.Time) v1.Deployment {
+	deployment := deploymentWithReplicas(name, 3, nil)
+	deployment.CreationTimestamp = metav1.NewTime(created)
+	deployment.Generation = 2
+	deployment.Status = v1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}
+	return deployment
+}
+
+func TestInFlight(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-time.Hour)
+	young := rolledOut("young", now.Add(-20*time.Second))
+	deleting := rolledOut("deleting", old)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now.Add(-time.Minute)}
+	lagging := rolledOut("lagging", old)
+	lagging.Generation = 3
+	rollingOut := rolledOut("rolling-out", old)
+	rollingOut.Status.UpdatedReplicas, rollingOut.Status.Replicas = 1, 4
+	source := &v1.DeploymentList{Items: []v1.Deployment{rolledOut("settled", old), young, deleting, lagging}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{rolledOut("settled", old), rollingOut}}
+
+	reasons := InFlight(cli.SettleOptions{Age: time.Minute, Deleting: true, Reconcile: true}, now, source, target)
+
+	expected := map[string]string{
+		"default/young":       "created 20s ago",
+		"default/deleting":    "being deleted since 2024-03-01T11:59:00Z",
+		"default/lagging":     "observedGeneration 2 is behind generation 3",
+		"default/rolling-out": "rollout in progress: 1 of 3 replicas updated, 3 available",
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("Expected %v, got %v", expected, reasons)
+	}
+	if reasons := InFlight(cli.SettleOptions{Deleting: true}, now, source, target); len(reasons) != 1 {
+		t.Errorf("Expected only the deleted object to be in flight, got %v", reasons)
+	}
+}
+
+func TestInFlightDaemonSetRollout(t *testing.T) {
+	daemonSet := v1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Status: v1.DaemonSetStatus{DesiredNumberScheduled: 5, UpdatedNumberScheduled: 5, NumberAvailable: 4}}
+	reasons := InFlight(cli.SettleOptions{Reconcile: true}, time.Now(), &v1.DaemonSetList{Items: []v1.DaemonSet{daemonSet}})
+	if reasons["default/agent"] != "rollout in progress: 5 of 5 pods updated, 4 available" {
+		t.Errorf("Expected the DaemonSet rollout to be in flight, got %v", reasons)
+	}
+	configMaps := &Corev1.ConfigMapList{Items: []Corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}}}}
+	if reasons := InFlight(cli.SettleOptions{Reconcile: true}, time.Now(), configMaps); len(reasons) != 0 {
+		t.Errorf("Expected objects without a controller status to be settled, got %v", reasons)
+	}
+}
+
+func TestSettleObjects(t *testing.T) {
+	reasons := map[string]string{"default/web": "created 5s ago"}
+	lists := func() (*v1.DeploymentList, *v1.DeploymentList) {
+		return &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 1, nil), deploymentWithReplicas("api", 1, nil)}},
+			&v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 2, nil)}}
+	}
+
+	source, target := lists()
+	marked, _ := settleObjects(cli.SettleOptions{Age: time.Minute}, reasons, source, target)
+	if len(marked) != 0 || len(source.Items) != 1 || source.Items[0].Name != "api" || len(target.Items) != 0 {
+		t.Errorf("Expected in-flight objects to be left out of both lists, got %v and %v", source.Items, target.Items)
+	}
+
+	source, target = lists()
+	marked, _ = settleObjects(cli.SettleOptions{Age: time.Minute, Mark: true}, reasons, source, target)
+	if marked["default/web"] != "in flight: created 5s ago" || len(source.Items) != 2 || len(target.Items) != 1 {
+		t.Errorf("Expected in-flight objects to be marked and kept, got %v", marked)
+	}
+}
+
+func TestNamespaceInFlight(t *testing.T) {
+	SetNamespacesInFlight(map[string]string{"default": "namespace being deleted in the target cluster"})
+	defer SetNamespacesInFlight(map[string]string{})
+	source := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 1, nil)}}
+	reasons := map[string]string{}
+
+	namespaceInFlight(cli.SettleOptions{Deleting: true, Mark: true}, reasons, "default", source)
+	if reasons["default/web"] != "namespace being deleted in the target cluster" {
+		t.Errorf("Expected the objects of the namespace to be in flight, got %v", reasons)
+	}
+
+	reasons = map[string]string{}
+	namespaceInFlight(cli.SettleOptions{Deleting: true, Mark: true}, reasons, "payments", source)
+	if len(reasons) != 0 {
+		t.Errorf("Expected only the namespaces in flight to count, got %v", reasons)
+	}
+}
@@ -0,0 +1,246 @@
+package compare
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SuppressionRule silences a difference that is explained by another object or by a controller.
+// Apply normalizes the source and target lists in place, before they are compared, so the
+// expected difference never reaches the report. It returns one message per object it touched.
+type SuppressionRule struct {
+	Name        string
+	Description string
+	Apply       func(source, target interface{}, related Related) []string
+}
+
+// Related holds the objects of other kinds that suppression rules look at.
+// Fields are nil when the related kind could not be listed; rules must treat that as "no relation".
+type Related struct {
+	SourceHPAs, TargetHPAs *autoscalingv1.HorizontalPodAutoscalerList
+}
+
+// controllerOwnedAnnotations are annotations written by built-in controllers rather than by whoever applied the object.
+// --managed-annotations adds the ones written by operators.
+var controllerOwnedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"autoscaling.alpha.kubernetes.io/conditions",
+	"autoscaling.alpha.kubernetes.io/current-metrics",
+	"control-plane.alpha.kubernetes.io/leader",
+}
+
+// restartedAtAnnotation is set on the pod template by `kubectl rollout restart`.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// HPAManagedReplicasRule ignores Deployment replica counts when an HPA targets the Deployment in both clusters.
+// The default Deployment criteria leave Spec.Replicas out, so the rule only matters with -f, e.g. -f Spec.
+var HPAManagedReplicasRule = SuppressionRule{
+	Name:        "hpa-managed-replicas",
+	Description: "Spec.Replicas is ignored for Deployments targeted by an HPA in both clusters",
+	Apply: func(source, target interface{}, related Related) []string {
+		var messages []string
+		sourceDeployments, okSource := source.(*v1.DeploymentList)
+		targetDeployments, okTarget := target.(*v1.DeploymentList)
+		if !okSource || !okTarget {
+			return messages
+		}
+		sourceScaled := hpaTargets(related.SourceHPAs, "Deployment")
+		targetScaled := hpaTargets(related.TargetHPAs, "Deployment")
+		for i := range sourceDeployments.Items {
+			name := sourceDeployments.Items[i].Name
+			if !sourceScaled[name] || !targetScaled[name] {
+				continue
+			}
+			for j := range targetDeployments.Items {
+				if targetDeployments.Items[j].Name == name {
+					sourceDeployments.Items[i].Spec.Replicas = nil
+					targetDeployments.Items[j].Spec.Replicas = nil
+					messages = append(messages, fmt.Sprintf("Ignoring Spec.Replicas of Deployment %s: an HPA scales it in both clusters", name))
+				}
+			}
+		}
+		return messages
+	},
+}
+
+// RestartedAtRule ignores the pod template annotation left behind by a rollout restart.
+// The default Deployment criteria leave the template metadata out, so the rule only matters with -f, e.g. -f Spec.
+var RestartedAtRule = SuppressionRule{
+	Name:        "restarted-at-annotation",
+	Description: "The " + restartedAtAnnotation + " pod template annotation is ignored",
+	Apply: func(source, target interface{}, related Related) []string {
+		var messages []string
+		for _, list := range []interface{}{source, target} {
+			deployments, ok := list.(*v1.DeploymentList)
+			if !ok {
+				continue
+			}
+			for i := range deployments.Items {
+				annotations := deployments.Items[i].Spec.Template.Annotations
+				if _, found := annotations[restartedAtAnnotation]; found {
+					delete(annotations, restartedAtAnnotation)
+					messages = append(messages, fmt.Sprintf("Ignoring %s on Deployment %s", restartedAtAnnotation, deployments.Items[i].Name))
+				}
+			}
+		}
+		return messages
+	},
+}
+
+// ControllerOwnedAnnotationsRule ignores annotations that built-in controllers keep rewriting.
+// It matters for the kinds whose criteria include Annotations, and for the others with -f.
+var ControllerOwnedAnnotationsRule = ManagedAnnotationsRule(controllerOwnedAnnotations)
+
+// ManagedAnnotationsRule ignores the object annotations a controller or an operator writes.
+// A key ending with "*" matches every annotation starting with the rest of the key.
+func ManagedAnnotationsRule(keys []string) SuppressionRule {
+	return SuppressionRule{
+		Name:        "controller-owned-annotations",
+		Description: "Annotations written by controllers and operators are ignored",
+		Apply: func(source, target interface{}, related Related) []string {
+			var messages []string
+			for _, list := range []interface{}{source, target} {
+				forEachObjectMeta(list, func(obj metav1.Object) {
+					annotations := obj.GetAnnotations()
+					names := make([]string, 0, len(annotations))
+					for name := range annotations {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					for _, name := range names {
+						if managedAnnotation(name, keys) {
+							delete(annotations, name)
+							messages = append(messages, fmt.Sprintf("Ignoring controller-owned annotation %s on %s", name, obj.GetName()))
+						}
+					}
+				})
+			}
+			return messages
+		},
+	}
+}
+
+// managedAnnotation reports whether an annotation is one of the keys, or starts with a key ending with "*".
+func managedAnnotation(name string, keys []string) bool {
+	for _, key := range keys {
+		prefix, wildcard := strings.CutSuffix(key, "*")
+		if name == key || (wildcard && strings.HasPrefix(name, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuspendedCronJobRule ignores scheduling fields of CronJobs that are suspended in both clusters, since they never run.
+var SuspendedCronJobRule = SuppressionRule{
+	Name:        "suspended-cronjob-schedule",
+	Description: "Scheduling fields are ignored for CronJobs suspended in both clusters",
+	Apply: func(source, target interface{}, related Related) []string {
+		var messages []string
+		sourceCronJobs, okSource := source.(*batchv1.CronJobList)
+		targetCronJobs, okTarget := target.(*batchv1.CronJobList)
+		if !okSource || !okTarget {
+			return messages
+		}
+		for i := range sourceCronJobs.Items {
+			if !isSuspended(&sourceCronJobs.Items[i]) {
+				continue
+			}
+			for j := range targetCronJobs.Items {
+				if targetCronJobs.Items[j].Name == sourceCronJobs.Items[i].Name && isSuspended(&targetCronJobs.Items[j]) {
+					clearSchedule(&sourceCronJobs.Items[i])
+					clearSchedule(&targetCronJobs.Items[j])
+					messages = append(messages, fmt.Sprintf("Ignoring schedule of CronJob %s: suspended in both clusters", sourceCronJobs.Items[i].Name))
+				}
+			}
+		}
+		return messages
+	},
+}
+
+// DefaultSuppressionRules are the built-in rules applied unless --no-suppress is given.
+var DefaultSuppressionRules = []SuppressionRule{
+	HPAManagedReplicasRule,
+	RestartedAtRule,
+	ControllerOwnedAnnotationsRule,
+	SuspendedCronJobRule,
+}
+
+// ApplySuppressionRules runs every rule over the source and target lists and returns the messages they produced.
+func ApplySuppressionRules(rules []SuppressionRule, source, target interface{}, related Related) []string {
+	var messages []string
+	for _, rule := range rules {
+		messages = append(messages, rule.Apply(source, target, related)...)
+	}
+	return messages
+}
+
+// SuppressionNormalizer turns the default suppression rules, plus the --managed-annotations, into a Normalizer.
+// related looks up the objects of other kinds the rules need; nil means the kind has no relations.
+// Nothing is suppressed when --no-suppress was given.
+func SuppressionNormalizer(related func(scope Scope) Related) Normalizer {
+	return func(source, target interface{}, scope Scope) []string {
+		if scope.Args.NoSuppress {
+			return nil
+		}
+		var rel Related
+		if related != nil {
+			rel = related(scope)
+		}
+		rules := DefaultSuppressionRules
+		if len(scope.Args.ManagedAnnotations) != 0 {
+			rules = append(append([]SuppressionRule(nil), rules...), ManagedAnnotationsRule(scope.Args.ManagedAnnotations))
+		}
+		return ApplySuppressionRules(rules, source, target, rel)
+	}
+}
+
+// hpaTargets returns the names of the objects of the given kind that an HPA in the list scales.
+func hpaTargets(hpas *autoscalingv1.HorizontalPodAutoscalerList, kind string) map[string]bool {
+	targets := make(map[string]bool)
+	if hpas == nil {
+		return targets
+	}
+	for _, hpa := range hpas.Items {
+		if hpa.Spec.ScaleTargetRef.Kind == kind {
+			targets[hpa.Spec.ScaleTargetRef.Name] = true
+		}
+	}
+	return targets
+}
+
+func isSuspended(cronJob *batchv1.CronJob) bool {
+	return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+}
+
+func clearSchedule(cronJob *batchv1.CronJob) {
+	cronJob.Spec.Schedule = ""
+	cronJob.Spec.TimeZone = nil
+	cronJob.Spec.StartingDeadlineSeconds = nil
+}
+
+// forEachObjectMeta calls fn with the object metadata of every item in a list that has an "Items" slice.
+func forEachObjectMeta(list interface{}, fn func(metav1.Object)) {
+	if list == nil || !hasItemsField(list) {
+		return
+	}
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if items.Kind() != reflect.Slice {
+		return
+	}
+	for i := 0; i < items.Len(); i++ {
+		obj, err := meta.Accessor(items.Index(i).Addr().Interface())
+		if err != nil {
+			continue
+		}
+		fn(obj)
+	}
+}
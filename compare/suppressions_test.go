@@ -0,0 +1,140 @@
+package compare
+
+import (
+	"testing"
+
+	"kompare/cli"
+
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	Corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func deploymentWithReplicas(name string, replicas int32, annotations map[string]string) v1.Deployment {
+	return v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1.DeploymentSpec{
+			Replicas: int32Ptr(replicas),
+			Template: Corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}},
+		},
+	}
+}
+
+func hpaFor(kind, name string) autoscalingv1.HorizontalPodAutoscaler {
+	return autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-hpa", Namespace: "default"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: kind, Name: name},
+		},
+	}
+}
+
+func TestHPAManagedReplicasRule(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 3, nil), deploymentWithReplicas("worker", 1, nil)}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 7, nil), deploymentWithReplicas("worker", 2, nil)}}
+	related := Related{
+		SourceHPAs: &autoscalingv1.HorizontalPodAutoscalerList{Items: []autoscalingv1.HorizontalPodAutoscaler{hpaFor("Deployment", "web"), hpaFor("Deployment", "worker")}},
+		TargetHPAs: &autoscalingv1.HorizontalPodAutoscalerList{Items: []autoscalingv1.HorizontalPodAutoscaler{hpaFor("Deployment", "web")}},
+	}
+
+	messages := HPAManagedReplicasRule.Apply(source, target, related)
+
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d: %v", len(messages), messages)
+	}
+	if source.Items[0].Spec.Replicas != nil || target.Items[0].Spec.Replicas != nil {
+		t.Errorf("Expected replicas of 'web' to be cleared on both sides")
+	}
+	if source.Items[1].Spec.Replicas == nil || target.Items[1].Spec.Replicas == nil {
+		t.Errorf("Expected replicas of 'worker' to be kept, the target has no HPA for it")
+	}
+}
+
+func TestHPAManagedReplicasRuleWithoutHPAs(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 3, nil)}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 7, nil)}}
+
+	messages := HPAManagedReplicasRule.Apply(source, target, Related{})
+
+	if len(messages) != 0 || source.Items[0].Spec.Replicas == nil {
+		t.Errorf("Expected no suppression without HPAs, got %v", messages)
+	}
+}
+
+func TestRestartedAtRule(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 1, map[string]string{restartedAtAnnotation: "2024-01-01T00:00:00Z", "team": "a"})}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{deploymentWithReplicas("web", 1, map[string]string{"team": "a"})}}
+
+	RestartedAtRule.Apply(source, target, Related{})
+
+	if _, found := source.Items[0].Spec.Template.Annotations[restartedAtAnnotation]; found {
+		t.Errorf("Expected %s to be removed", restartedAtAnnotation)
+	}
+	if source.Items[0].Spec.Template.Annotations["team"] != "a" {
+		t.Errorf("Expected other annotations to be kept")
+	}
+}
+
+func TestControllerOwnedAnnotationsRule(t *testing.T) {
+	source := &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"deployment.kubernetes.io/revision": "4"}}}}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"deployment.kubernetes.io/revision": "9"}}}}}
+
+	messages := ControllerOwnedAnnotationsRule.Apply(source, target, Related{})
+
+	if len(messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(messages))
+	}
+	if len(source.Items[0].Annotations) != 0 || len(target.Items[0].Annotations) != 0 {
+		t.Errorf("Expected controller-owned annotations to be removed")
+	}
+}
+
+func TestSuppressionNormalizerManagedAnnotations(t *testing.T) {
+	annotations := func() map[string]string {
+		return map[string]string{"argocd.argoproj.io/tracking-id": "shop:apps/Deployment:shop/web", "meta.helm.sh/release-name": "web", "team": "a"}
+	}
+	source := &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: annotations()}}}}
+	target := &v1.DeploymentList{Items: []v1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: annotations()}}}}
+	args := cli.ArgumentsReceivedValidated{ManagedAnnotations: []string{"argocd.argoproj.io/tracking-id", "meta.helm.sh/*"}}
+
+	messages := SuppressionNormalizer(nil)(source, target, Scope{Args: args})
+
+	if len(messages) != 4 {
+		t.Errorf("Expected 4 messages, got %v", messages)
+	}
+	for _, list := range []*v1.DeploymentList{source, target} {
+		if got := list.Items[0].Annotations; len(got) != 1 || got["team"] != "a" {
+			t.Errorf("Expected only the team annotation to be kept, got %v", got)
+		}
+	}
+}
+
+func TestSuspendedCronJobRule(t *testing.T) {
+	cronJob := func(name, schedule string, suspend bool) batchv1.CronJob {
+		return batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       batchv1.CronJobSpec{Schedule: schedule, Suspend: boolPtr(suspend)},
+		}
+	}
+	source := &batchv1.CronJobList{Items: []batchv1.CronJob{cronJob("report", "0 * * * *", true), cronJob("backup", "0 1 * * *", true)}}
+	target := &batchv1.CronJobList{Items: []batchv1.CronJob{cronJob("report", "30 * * * *", true), cronJob("backup", "0 2 * * *", false)}}
+
+	SuspendedCronJobRule.Apply(source, target, Related{})
+
+	if source.Items[0].Spec.Schedule != "" || target.Items[0].Spec.Schedule != "" {
+		t.Errorf("Expected the schedule of a CronJob suspended in both clusters to be ignored")
+	}
+	if source.Items[1].Spec.Schedule == "" || target.Items[1].Spec.Schedule == "" {
+		t.Errorf("Expected the schedule of a CronJob running in one cluster to be kept")
+	}
+}
@@ -0,0 +1,118 @@
+package compare
+
+import (
+	"fmt"
+	"sync"
+
+	"kompare/query"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// VersionedLister lists a kind at one API version, e.g. "autoscaling/v2".
+type VersionedLister struct {
+	Version string
+	List    func(cluster Cluster, namespace string) (interface{}, error)
+}
+
+// VersionedComparator is implemented by comparators of kinds served under several API versions.
+// Both sides of a comparison are read at the same version, so that a field one version drops
+// is not reported as a difference between clusters of different Kubernetes versions.
+type VersionedComparator interface {
+	Comparator
+	// Versions are the group-versions the comparator can list, the preferred one first.
+	Versions() []string
+	// ListVersion lists the kind at one of Versions, or with List when version is "".
+	ListVersion(cluster Cluster, namespace, version string) (interface{}, error)
+}
+
+// Versions returns the versions of the VersionedListers, the preferred one first.
+func (b *BaseComparator) Versions() []string {
+	versions := make([]string, 0, len(b.VersionedListers))
+	for _, lister := range b.VersionedListers {
+		versions = append(versions, lister.Version)
+	}
+	return versions
+}
+
+// ListVersion lists the kind with the VersionedLister of a version, or with Lister when version is "".
+func (b *BaseComparator) ListVersion(cluster Cluster, namespace, version string) (interface{}, error) {
+	if version == "" {
+		return b.List(cluster, namespace)
+	}
+	for _, lister := range b.VersionedListers {
+		if lister.Version == version {
+			return lister.List(cluster, namespace)
+		}
+	}
+	return nil, fmt.Errorf("no lister configured for %s at %s", b.KindName, version)
+}
+
+// CommonVersion returns the first of the comparator's versions every cluster serves its kind at.
+// It returns "" for comparators with a single version, and an error when the clusters share no version
+// or discovery fails, in which case the comparator's default List is used.
+func CommonVersion(c Comparator, clusters ...Cluster) (string, error) {
+	versioned, ok := c.(VersionedComparator)
+	if !ok || len(versioned.Versions()) == 0 {
+		return "", nil
+	}
+	kind := c.GroupKind().Kind
+	for _, version := range versioned.Versions() {
+		servedEverywhere := true
+		for _, cluster := range clusters {
+			served, err := servesKind(cluster.Clientset, version, kind)
+			if err != nil {
+				return "", err
+			}
+			if !served {
+				servedEverywhere = false
+				break
+			}
+		}
+		if servedEverywhere {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("no version of %s is served by every cluster, tried %v", kind, versioned.Versions())
+}
+
+// listVersion lists a kind at a version returned by CommonVersion.
+func listVersion(c Comparator, cluster Cluster, namespace, version string) (interface{}, error) {
+	if versioned, ok := c.(VersionedComparator); ok && version != "" {
+		return versioned.ListVersion(cluster, namespace, version)
+	}
+	return c.List(cluster, namespace)
+}
+
+// negotiateVersion is CommonVersion falling back to the comparator's default List, with a warning.
+func negotiateVersion(c Comparator, clusters ...Cluster) string {
+	version, err := CommonVersion(c, clusters...)
+	if err != nil {
+		fmt.Printf("Warning: could not pick a common version of %s, using the default one: %v\n", c.Name(), err)
+	}
+	return version
+}
+
+var (
+	servedKindsMu sync.Mutex
+	// servedKinds caches discovery answers by clientset and "groupVersion kind", as every namespace asks again.
+	servedKinds = map[*kubernetes.Clientset]map[string]bool{}
+)
+
+func servesKind(clientset *kubernetes.Clientset, groupVersion, kind string) (bool, error) {
+	servedKindsMu.Lock()
+	defer servedKindsMu.Unlock()
+	key := groupVersion + " " + kind
+	if served, found := servedKinds[clientset][key]; found {
+		return served, nil
+	}
+	served, err := query.ServesKind(clientset, groupVersion, kind)
+	if err != nil {
+		return false, err
+	}
+	if servedKinds[clientset] == nil {
+		servedKinds[clientset] = map[string]bool{}
+	}
+	servedKinds[clientset][key] = served
+	return served, nil
+}
@@ -0,0 +1,33 @@
+package compare
+
+import "testing"
+
+func TestListVersion(t *testing.T) {
+	lister := func(version string) func(Cluster, string) (interface{}, error) {
+		return func(Cluster, string) (interface{}, error) { return version, nil }
+	}
+	comparator := &BaseComparator{
+		KindName:         "widget",
+		Lister:           lister("default"),
+		VersionedListers: []VersionedLister{{"example.com/v2", lister("v2")}, {"example.com/v1", lister("v1")}},
+	}
+
+	if versions := comparator.Versions(); len(versions) != 2 || versions[0] != "example.com/v2" {
+		t.Errorf("Expected the versions in order of preference, got %v", versions)
+	}
+	for version, expected := range map[string]string{"": "default", "example.com/v1": "v1", "example.com/v2": "v2"} {
+		if list, err := listVersion(comparator, Cluster{}, "", version); err != nil || list != expected {
+			t.Errorf("Expected %q to be listed with the %s lister, got %v, %v", version, expected, list, err)
+		}
+	}
+	if _, err := comparator.ListVersion(Cluster{}, "", "example.com/v3"); err == nil {
+		t.Errorf("Expected an error for a version without lister")
+	}
+}
+
+func TestCommonVersionOfSingleVersionKind(t *testing.T) {
+	version, err := CommonVersion(DeploymentComparator, Cluster{}, Cluster{})
+	if version != "" || err != nil {
+		t.Errorf("Expected no version negotiation for a single version kind, got %q, %v", version, err)
+	}
+}
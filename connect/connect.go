@@ -102,3 +102,17 @@ func ConnectToSource(strSourceClusterContext string, configFile *string) (*kuber
 	}
 	return clientsetToSource, nil
 }
+
+// ConnectToContexts establishes a connection to the cluster of every context, in order.
+// It returns one Kubernetes clientset per context, or the first error encountered.
+func ConnectToContexts(contexts []string, configFile *string) ([]*kubernetes.Clientset, error) {
+	clientsets := make([]*kubernetes.Clientset, 0, len(contexts))
+	for _, context := range contexts {
+		clientset, err := ConnectToSource(context, configFile)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to context %s: %w", context, err)
+		}
+		clientsets = append(clientsets, clientset)
+	}
+	return clientsets, nil
+}
@@ -177,3 +177,22 @@ func TestContextSwitch(t *testing.T) {
 	}
 	// Add more validation as needed, such as checking server URLs, etc.
 }
+
+func TestConnectToContexts(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+	x := tempKubeconfig.Name()
+
+	clientsets, err := ConnectToContexts([]string{"source-context", "target-context"}, &x)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(clientsets) != 2 {
+		t.Errorf("Expected 2 clientsets, got %d", len(clientsets))
+	}
+
+	if _, err := ConnectToContexts([]string{"source-context", "unknown-context"}, &x); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+}
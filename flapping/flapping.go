@@ -0,0 +1,226 @@
+// Package flapping classifies the findings of repeated comparisons, to tell lasting drift from
+// in-flight changes and from fields a controller keeps rewriting.
+package flapping
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"kompare/DAO"
+	"kompare/baseline"
+	"kompare/tools"
+)
+
+// Class is how a finding behaved over the runs.
+type Class string
+
+const (
+	// Persistent findings have the same value in every run.
+	Persistent Class = "persistent"
+	// Transient findings are missing from some runs: they appeared, or cleared, once.
+	Transient Class = "transient"
+	// Flapping findings change value between runs, or clear and come back.
+	Flapping Class = "flapping"
+)
+
+// Result is a finding, identified like a baseline entry, and how it behaved over the runs.
+type Result struct {
+	baseline.Entry
+	Class Class
+	// Values is the difference line of every run, "" for the runs the finding was missing from.
+	Values []string
+}
+
+// Present is the number of runs the finding was reported in.
+func (r Result) Present() int {
+	present := 0
+	for _, value := range r.Values {
+		if value != "" {
+			present++
+		}
+	}
+	return present
+}
+
+// Recorder records the findings of every run. Its Record method is meant to be chained into compare.Findings.
+type Recorder struct {
+	mu      sync.Mutex
+	entries map[string]baseline.Entry
+	runs    []map[string]string
+}
+
+// NewRecorder returns a recorder with no runs.
+func NewRecorder() *Recorder {
+	return &Recorder{entries: map[string]baseline.Entry{}}
+}
+
+// StartRun starts recording a new run; findings recorded before the first call are ignored.
+func (r *Recorder) StartRun() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, map[string]string{})
+}
+
+// Record records the difference lines of the findings in the current run and returns the findings unchanged.
+func (r *Recorder) Record(diffs []DAO.DiffWithName) []DAO.DiffWithName {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.runs) == 0 {
+		return diffs
+	}
+	run := r.runs[len(r.runs)-1]
+	for _, diff := range diffs {
+		for i, entry := range baseline.Entries(diff) {
+			r.entries[entry.ID] = entry
+			run[entry.ID] = diff.Diff[i]
+		}
+	}
+	return diffs
+}
+
+// Results classifies every finding recorded, sorted by class then ID.
+func (r *Recorder) Results() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var results []Result
+	for id, entry := range r.entries {
+		result := Result{Entry: entry, Values: make([]string, len(r.runs))}
+		for i, run := range r.runs {
+			result.Values[i] = run[id]
+		}
+		result.Class = classify(result.Values)
+		results = append(results, result)
+	}
+	order := map[Class]int{Flapping: 0, Transient: 1, Persistent: 2}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Class != results[j].Class {
+			return order[results[i].Class] < order[results[j].Class]
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// classify tells how the values of a finding behaved: a finding that changes value, or clears and
+// comes back, is flapping; one missing from some runs is transient; others are persistent.
+func classify(values []string) Class {
+	distinct := map[string]bool{}
+	present, first, last := 0, -1, -1
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		distinct[value] = true
+		present++
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	switch {
+	case len(distinct) > 1 || (present > 0 && present < last-first+1):
+		return Flapping
+	case present < len(values):
+		return Transient
+	default:
+		return Persistent
+	}
+}
+
+// Suggestion is an ignore rule for a field a controller keeps rewriting: the kompare.io/ignore-fields
+// annotation to put on the object, with the field as a JSON path.
+type Suggestion struct {
+	Kind, Namespace, Name string
+	Fields                []string
+}
+
+// Suggestions proposes an ignore rule for every object with flapping findings, merging the fields of each object.
+// Fields that cannot be written as a JSON path, like list items, are left out.
+func Suggestions(results []Result) []Suggestion {
+	var suggestions []Suggestion
+	index := map[string]int{}
+	for _, result := range results {
+		if result.Class != Flapping {
+			continue
+		}
+		field, ok := jsonPath(result.Path)
+		if !ok {
+			continue
+		}
+		key := result.Kind + "/" + result.Namespace + "/" + result.Name
+		i, found := index[key]
+		if !found {
+			i = len(suggestions)
+			index[key] = i
+			suggestions = append(suggestions, Suggestion{Kind: result.Kind, Namespace: result.Namespace, Name: result.Name})
+		}
+		if !tools.IsInList(field, suggestions[i].Fields) {
+			suggestions[i].Fields = append(suggestions[i].Fields, field)
+		}
+	}
+	return suggestions
+}
+
+// jsonPath turns the path of a finding, like "Spec.Template.ObjectMeta.Annotations.map[app.kubernetes.io/name]", into the
+// JSON path kompare.io/ignore-fields expects, like "spec.template.metadata.annotations[app.kubernetes.io/name]".
+// Go field names are turned into their JSON names, which Kubernetes types derive the same way, ObjectMeta aside.
+// It reports false for paths through list items, which the annotation cannot address.
+func jsonPath(path string) (string, bool) {
+	var fields []string
+	var current strings.Builder
+	depth := 0
+	for _, r := range path {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case r == '.' && depth == 0:
+			fields = append(fields, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	fields = append(fields, current.String())
+	var converted strings.Builder
+	for _, field := range fields {
+		switch {
+		case field == "":
+			return "", false
+		case strings.HasPrefix(field, "map[") && strings.HasSuffix(field, "]"):
+			converted.WriteString(strings.TrimPrefix(field, "map"))
+		case strings.Contains(field, "["):
+			return "", false
+		case field == "TypeMeta":
+			continue
+		default:
+			if converted.Len() > 0 {
+				converted.WriteString(".")
+			}
+			if field == "ObjectMeta" {
+				field = "metadata"
+			}
+			converted.WriteString(lowerCamel(field))
+		}
+	}
+	return converted.String(), converted.Len() > 0
+}
+
+// lowerCamel turns a Go field name into its JSON name: "Replicas" into "replicas", "APIVersion" into "apiVersion".
+func lowerCamel(field string) string {
+	runes := []rune(field)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,79 @@
+package flapping
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"kompare/DAO"
+)
+
+func finding(name, property, line string) DAO.DiffWithName {
+	return DAO.DiffWithName{Kind: "deployment", Namespace: "shop", Name: name, PropertyName: property, Diff: []string{line}}
+}
+
+func TestRecorderClassifiesFindings(t *testing.T) {
+	recorder := NewRecorder()
+	runs := [][]DAO.DiffWithName{
+		{finding("web", "Spec.Template.Spec", "Containers.slice[0].Image: nginx:1.25 != nginx:1.24"), finding("api", "Spec", "Replicas: 3 != 2"), finding("worker", "Spec", "Paused: true != false")},
+		{finding("web", "Spec.Template.Spec", "Containers.slice[0].Image: nginx:1.25 != nginx:1.24"), finding("api", "Spec", "Replicas: 4 != 2")},
+		{finding("web", "Spec.Template.Spec", "Containers.slice[0].Image: nginx:1.25 != nginx:1.24"), finding("api", "Spec", "Replicas: 3 != 2")},
+	}
+	recorder.Record(runs[0])
+	for _, run := range runs {
+		recorder.StartRun()
+		recorder.Record(run)
+	}
+
+	var classes []string
+	for _, result := range recorder.Results() {
+		classes = append(classes, string(result.Class)+" "+result.ID)
+	}
+	expected := []string{
+		"flapping deployment/shop/api/Spec.Replicas",
+		"transient deployment/shop/worker/Spec.Paused",
+		"persistent deployment/shop/web/Spec.Template.Spec.Containers.slice[0].Image",
+	}
+	if !reflect.DeepEqual(classes, expected) {
+		t.Errorf("Expected %v, got %v", expected, classes)
+	}
+	if present := recorder.Results()[1].Present(); present != 1 {
+		t.Errorf("Expected the transient finding in 1 run, got %d", present)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := map[string]Class{
+		"a,a,a": Persistent,
+		"a,a,":  Transient,
+		",,a":   Transient,
+		"a,,a":  Flapping,
+		"a,b,a": Flapping,
+	}
+	for values, expected := range tests {
+		if class := classify(strings.Split(values, ",")); class != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, values, class)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	results := []Result{
+		{Class: Flapping, Values: []string{"1", "2"}},
+		{Class: Flapping},
+		{Class: Flapping},
+		{Class: Persistent},
+	}
+	results[0].Kind, results[0].Namespace, results[0].Name, results[0].Path = "deployment", "shop", "api", "Spec.Replicas"
+	results[1].Kind, results[1].Namespace, results[1].Name, results[1].Path = "deployment", "shop", "api", "Spec.Template.ObjectMeta.Annotations.map[sidecar.example.com/version]"
+	results[2].Kind, results[2].Namespace, results[2].Name, results[2].Path = "deployment", "shop", "web", "Spec.Template.Spec.Containers.slice[0].Image"
+	results[3].Kind, results[3].Namespace, results[3].Name, results[3].Path = "deployment", "shop", "db", "Spec.Replicas"
+
+	expected := []Suggestion{{Kind: "deployment", Namespace: "shop", Name: "api", Fields: []string{"spec.replicas", "spec.template.metadata.annotations[sidecar.example.com/version]"}}}
+	if suggestions := Suggestions(results); !reflect.DeepEqual(suggestions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, suggestions)
+	}
+	if field := lowerCamel("APIVersion"); field != "apiVersion" {
+		t.Errorf("Expected apiVersion, got %s", field)
+	}
+}
@@ -0,0 +1,199 @@
+// Package lint checks conventions, like required labels and naming, on the objects listed for the comparison,
+// and tells the violations every cluster has from the ones only some clusters have.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"kompare/cli"
+	"kompare/tools"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Rule is a convention the objects of a kind follow in every cluster.
+type Rule struct {
+	// Kind is a kind name as given to -i, e.g. "deployment" or "ns".
+	Kind                string   `json:"kind"`
+	RequiredLabels      []string `json:"requiredLabels,omitempty"`
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty"`
+	// NamePattern is a regular expression the whole name matches.
+	NamePattern string `json:"namePattern,omitempty"`
+
+	namePattern *regexp.Regexp
+}
+
+type file struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Violation is an object breaking a rule, and the clusters it breaks it in.
+type Violation struct {
+	Kind, Namespace, Name, Message string
+	Clusters                       []string
+	// Present are the clusters the object exists in, so that a violation in a single cluster can be
+	// told from an object that only exists in that cluster.
+	Present []string
+}
+
+// ID identifies the violation like a finding, "kind/namespace/name".
+func (v Violation) ID() string {
+	return strings.Join([]string{v.Kind, v.Namespace, v.Name}, "/")
+}
+
+// Linter checks the objects of every cluster against the rules and records the violations.
+// Its Check method is meant to be set as compare.Lint.
+type Linter struct {
+	mu         sync.Mutex
+	rules      map[string][]Rule
+	clusters   []string
+	violations map[string]*Violation
+	// present are the clusters each object of a kind with rules exists in, by "kind/namespace/name".
+	present map[string][]string
+	// checked are the kinds Check was called for.
+	checked map[string]bool
+}
+
+// New returns a linter for rules, their kind aliases resolved to canonical names, e.g. "deploy" to "deployment".
+func New(rules []Rule) (*Linter, error) {
+	l := &Linter{rules: map[string][]Rule{}, violations: map[string]*Violation{}, present: map[string][]string{}, checked: map[string]bool{}}
+	for i, rule := range rules {
+		_, kinds := cli.ValidateKubernetesObjects([]string{strings.ToLower(rule.Kind)})
+		if len(kinds) == 0 {
+			return nil, fmt.Errorf("rule %d: unknown kind %q", i+1, rule.Kind)
+		}
+		if rule.NamePattern != "" {
+			pattern, err := regexp.Compile("^(?:" + rule.NamePattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid namePattern: %v", i+1, err)
+			}
+			rule.namePattern = pattern
+		}
+		l.rules[kinds[0]] = append(l.rules[kinds[0]], rule)
+	}
+	return l, nil
+}
+
+// Load reads a rules file, in YAML or JSON, e.g.
+//
+//	rules:
+//	- kind: deployment
+//	  requiredLabels: [app.kubernetes.io/name, team]
+//	  namePattern: "[a-z][a-z0-9-]*"
+//	- kind: namespace
+//	  requiredAnnotations: [cost-center]
+func Load(path string) (*Linter, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lint rules %s: %v", path, err)
+	}
+	var f file
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, fmt.Errorf("error parsing lint rules %s: %v", path, err)
+	}
+	l, err := New(f.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("error in lint rules %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Check records the violations of the objects of a kind listed in a cluster.
+func (l *Linter) Check(kind, cluster string, objects []metav1.Object) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !tools.IsInList(cluster, l.clusters) {
+		l.clusters = append(l.clusters, cluster)
+	}
+	l.checked[kind] = true
+	if len(l.rules[kind]) == 0 {
+		return
+	}
+	for _, obj := range objects {
+		id := strings.Join([]string{kind, obj.GetNamespace(), obj.GetName()}, "/")
+		if !tools.IsInList(cluster, l.present[id]) {
+			l.present[id] = append(l.present[id], cluster)
+		}
+	}
+	for _, rule := range l.rules[kind] {
+		for _, obj := range objects {
+			for _, message := range rule.violations(obj) {
+				key := strings.Join([]string{kind, obj.GetNamespace(), obj.GetName(), message}, "/")
+				violation, found := l.violations[key]
+				if !found {
+					violation = &Violation{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), Message: message}
+					l.violations[key] = violation
+				}
+				if !tools.IsInList(cluster, violation.Clusters) {
+					violation.Clusters = append(violation.Clusters, cluster)
+				}
+			}
+		}
+	}
+}
+
+// violations describes how an object breaks the rule.
+func (r Rule) violations(obj metav1.Object) []string {
+	var messages []string
+	for _, label := range r.RequiredLabels {
+		if _, found := obj.GetLabels()[label]; !found {
+			messages = append(messages, fmt.Sprintf("missing label %s", label))
+		}
+	}
+	for _, annotation := range r.RequiredAnnotations {
+		if _, found := obj.GetAnnotations()[annotation]; !found {
+			messages = append(messages, fmt.Sprintf("missing annotation %s", annotation))
+		}
+	}
+	if r.namePattern != nil && !r.namePattern.MatchString(obj.GetName()) {
+		messages = append(messages, fmt.Sprintf("name does not match %s", r.NamePattern))
+	}
+	return messages
+}
+
+// Clusters returns the clusters checked, in the order they were first checked.
+func (l *Linter) Clusters() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.clusters...)
+}
+
+// Unchecked returns the sorted kinds that have rules but were never listed, e.g. namespaces with -n.
+func (l *Linter) Unchecked() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var kinds []string
+	for kind := range l.rules {
+		if !l.checked[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Violations returns the violations recorded, sorted by kind, namespace, name and message.
+func (l *Linter) Violations() []Violation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	violations := make([]Violation, 0, len(l.violations))
+	for _, violation := range l.violations {
+		copied := *violation
+		copied.Clusters = append([]string(nil), violation.Clusters...)
+		copied.Present = append([]string(nil), l.present[violation.ID()]...)
+		violations = append(violations, copied)
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].ID() != violations[j].ID() {
+			return violations[i].ID() < violations[j].ID()
+		}
+		return violations[i].Message < violations[j].Message
+	})
+	return violations
+}
@@ -0,0 +1,104 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func object(namespace, name string, labels, annotations map[string]string) metav1.Object {
+	return &metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, Annotations: annotations}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	rules := "rules:\n- kind: deploy\n  requiredLabels: [team]\n  namePattern: \"[a-z-]+\"\n- kind: ns\n  requiredAnnotations: [cost-center]\n"
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	linter, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(linter.rules["deployment"]) != 1 || len(linter.rules["namespace"]) != 1 {
+		t.Errorf("Expected the kind aliases to be resolved, got %v", linter.rules)
+	}
+
+	for name, invalid := range map[string]string{
+		"unknown kind":  "rules:\n- kind: gadget\n  requiredLabels: [team]\n",
+		"bad pattern":   "rules:\n- kind: deployment\n  namePattern: \"[a-z\"\n",
+		"unknown field": "rules:\n- kind: deployment\n  requiredLabel: [team]\n",
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	linter, err := New([]Rule{
+		{Kind: "deployment", RequiredLabels: []string{"app.kubernetes.io/name", "team"}, NamePattern: "[a-z][a-z0-9-]*"},
+		{Kind: "namespace", RequiredAnnotations: []string{"cost-center"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	labelled := map[string]string{"app.kubernetes.io/name": "web", "team": "shop"}
+	linter.Check("deployment", "prod", []metav1.Object{
+		object("shop", "web", map[string]string{"app.kubernetes.io/name": "web"}, nil),
+		object("shop", "Api", labelled, nil),
+	})
+	linter.Check("deployment", "staging", []metav1.Object{
+		object("shop", "web", map[string]string{"app.kubernetes.io/name": "web"}, nil),
+		object("shop", "api", labelled, nil),
+	})
+	linter.Check("namespace", "prod", []metav1.Object{object("", "shop", nil, map[string]string{"cost-center": "42"})})
+	linter.Check("namespace", "staging", []metav1.Object{object("", "shop", nil, nil)})
+	// Kinds without rules are not checked.
+	linter.Check("configmap", "prod", []metav1.Object{object("shop", "Settings", nil, nil)})
+
+	violations := linter.Violations()
+	expected := []struct{ id, message, clusters string }{
+		{"deployment/shop/Api", "name does not match [a-z][a-z0-9-]*", "prod"},
+		{"deployment/shop/web", "missing label team", "prod,staging"},
+		{"namespace//shop", "missing annotation cost-center", "staging"},
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %+v", len(expected), violations)
+	}
+	for i, want := range expected {
+		got := violations[i]
+		clusters := strings.Join(got.Clusters, ",")
+		if got.ID() != want.id || got.Message != want.message || clusters != want.clusters {
+			t.Errorf("Expected %s: %s in %s, got %s: %s in %s", want.id, want.message, want.clusters, got.ID(), got.Message, clusters)
+		}
+	}
+	if clusters := linter.Clusters(); len(clusters) != 2 || clusters[0] != "prod" || clusters[1] != "staging" {
+		t.Errorf("Expected prod and staging to be checked, got %v", clusters)
+	}
+	if present := strings.Join(violations[0].Present, ","); present != "prod" {
+		t.Errorf("Expected deployment/shop/Api to only exist in prod, got %s", present)
+	}
+	if present := strings.Join(violations[2].Present, ","); present != "prod,staging" {
+		t.Errorf("Expected namespace//shop to exist in both clusters, got %s", present)
+	}
+}
+
+func TestUnchecked(t *testing.T) {
+	linter, err := New([]Rule{{Kind: "deployment", RequiredLabels: []string{"team"}}, {Kind: "ns", RequiredAnnotations: []string{"cost-center"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	linter.Check("deployment", "prod", nil)
+
+	if unchecked := linter.Unchecked(); len(unchecked) != 1 || unchecked[0] != "namespace" {
+		t.Errorf("Expected the namespace rules not to be checked, got %v", unchecked)
+	}
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"fmt"
+	"kompare/DAO"
+	"kompare/analysis"
+	"kompare/baseline"
 	"kompare/cli"
 	"kompare/compare"
 	"kompare/connect"
+	"kompare/flapping"
+	"kompare/lint"
 	"kompare/query"
+	"kompare/state"
 	"kompare/tools"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
 func main() {
@@ -27,6 +34,18 @@ func main() {
 		defer tools.LogOutput(args.FileOutput)()
 	}
 
+	linter, err := setupLint(args)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(args.Contexts) != 0 {
+		compareContexts(args)
+		reportLint(linter)
+		fmt.Println("Finished all comparison works!")
+		return
+	}
+
 	// Connect to source cluster
 	clientsetToSource, err := connect.ConnectToSource(args.SourceClusterContext, &args.KubeconfigFile)
 	if err != nil {
@@ -41,6 +60,264 @@ func main() {
 		panic(err)
 	}
 
+	accepted, err := setupBaseline(args)
+	if err != nil {
+		panic(err)
+	}
+
+	source := compare.Cluster{Clientset: clientsetToSource, Context: args.SourceClusterContext, Kubeconfig: args.KubeconfigFile}
+	target := compare.Cluster{Clientset: clientsetToTarget, Context: args.TargetClusterContext, Kubeconfig: args.KubeconfigFile}
+
+	incremental, err := setupState(source, target, args)
+	if err != nil {
+		panic(err)
+	}
+
+	recorder := setupRepeat(args)
+	for run := 1; run <= args.Repeat; run++ {
+		if recorder != nil {
+			if run > 1 {
+				fmt.Printf("Waiting %s before the next run\n", args.Interval)
+				time.Sleep(args.Interval)
+			}
+			fmt.Printf("Run %d of %d\n", run, args.Repeat)
+			recorder.StartRun()
+		}
+		if len(args.Analyze) != 0 {
+			runAnalyses(source, target, args)
+		} else {
+			compareClusters(source, target, args)
+			compare.RecheckInFlight(args)
+		}
+	}
+	reportRepeat(recorder, args)
+	reportLint(linter)
+	if err := finishState(incremental, args); err != nil {
+		panic(err)
+	}
+
+	if err := finishBaseline(accepted, args); err != nil {
+		panic(err)
+	}
+	fmt.Println("Finished all comparison works!")
+}
+
+// setupBaseline hides the findings of the --baseline file from every comparison.
+// With "baseline accept" nothing is hidden and every finding is recorded instead.
+func setupBaseline(args cli.ArgumentsReceivedValidated) (*baseline.Baseline, error) {
+	var accepted *baseline.Baseline
+	switch {
+	case args.BaselineAccept:
+		accepted = baseline.New()
+	case args.Baseline != "":
+		var err error
+		accepted, err = baseline.Load(args.Baseline)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Only reporting findings not in the baseline %s\n", args.Baseline)
+	default:
+		return nil, nil
+	}
+	compare.Findings = accepted.Filter
+	compare.Compared = accepted.Compared
+	return accepted, nil
+}
+
+// finishBaseline writes the baseline file when accepting, or reports the accepted findings that disappeared.
+func finishBaseline(accepted *baseline.Baseline, args cli.ArgumentsReceivedValidated) error {
+	if accepted == nil {
+		return nil
+	}
+	if args.BaselineAccept {
+		file := args.Baseline
+		if file == "" {
+			file = baseline.DefaultFile
+		}
+		entries := accepted.Seen()
+		if err := baseline.Save(file, entries); err != nil {
+			return fmt.Errorf("error writing baseline: %v", err)
+		}
+		fmt.Printf("Accepted %d findings into the baseline %s\n", len(entries), file)
+		return nil
+	}
+	disappeared := accepted.Disappeared()
+	if len(disappeared) == 0 {
+		fmt.Println("All baseline findings are still present.")
+		return nil
+	}
+	fmt.Printf("%d baseline findings are no longer found:\n", len(disappeared))
+	for _, entry := range disappeared {
+		fmt.Printf("- %s\n", entry.ID)
+	}
+	return nil
+}
+
+// setupState loads the --state file, for the comparison to only diff the objects that changed since the run that
+// saved it. The clusters are identified by the UID of their kube-system namespace.
+func setupState(source, target compare.Cluster, args cli.ArgumentsReceivedValidated) (*state.State, error) {
+	if args.State == "" {
+		return nil, nil
+	}
+	var clusters []state.Cluster
+	for _, cluster := range []compare.Cluster{source, target} {
+		kubeSystem, err := query.GetNamespace(cluster.Clientset, "kube-system")
+		if err != nil {
+			return nil, fmt.Errorf("error identifying the cluster of %s: %v", cluster.Context, err)
+		}
+		clusters = append(clusters, state.Cluster{Context: cluster.Context, UID: string(kubeSystem.UID)})
+	}
+	incremental, reason, err := state.Load(args.State, clusters[0], clusters[1], time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		fmt.Printf("Comparing every object, %s\n", reason)
+	} else {
+		fmt.Printf("Only comparing the objects changed since the state %s\n", args.State)
+	}
+	compare.Incremental = incremental
+	return incremental, nil
+}
+
+// finishState reports what the incremental comparison carried forward and found new, then saves the --state file.
+func finishState(incremental *state.State, args cli.ArgumentsReceivedValidated) error {
+	if incremental == nil {
+		return nil
+	}
+	unchanged, compared := incremental.Counts()
+	fmt.Printf("Incremental comparison: %d pairs unchanged, %d compared\n", unchanged, compared)
+	if !incremental.Full() {
+		newFindings := incremental.NewFindings()
+		if len(newFindings) == 0 {
+			fmt.Println("No new findings since the previous run.")
+		} else {
+			fmt.Printf("%d new findings since the previous run:\n", len(newFindings))
+			for _, entry := range newFindings {
+				fmt.Printf("- %s\n", entry.ID)
+			}
+		}
+	}
+	if err := incremental.Save(args.State); err != nil {
+		return fmt.Errorf("error writing state: %v", err)
+	}
+	return nil
+}
+
+// setupLint checks the objects of every kind compared against the --lint rules.
+func setupLint(args cli.ArgumentsReceivedValidated) (*lint.Linter, error) {
+	if args.Lint == "" {
+		return nil, nil
+	}
+	linter, err := lint.Load(args.Lint)
+	if err != nil {
+		return nil, err
+	}
+	compare.Lint = linter.Check
+	return linter, nil
+}
+
+// reportLint prints the number of violations of every cluster, then every violation with the clusters it is found in.
+func reportLint(linter *lint.Linter) {
+	if linter == nil {
+		return
+	}
+	clusters := linter.Clusters()
+	violations := linter.Violations()
+	counts := map[string]int{}
+	for _, violation := range violations {
+		for _, cluster := range violation.Clusters {
+			counts[cluster]++
+		}
+	}
+	perCluster := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		perCluster[i] = fmt.Sprintf("%d in %s", counts[cluster], cluster)
+	}
+	fmt.Printf("Lint violations: %s\n", strings.Join(perCluster, ", "))
+	if unchecked := linter.Unchecked(); len(unchecked) != 0 {
+		fmt.Printf("Warning: the lint rules of %s were not checked, as these kinds were not compared\n", strings.Join(unchecked, ", "))
+	}
+	for _, violation := range violations {
+		where := strings.Join(violation.Clusters, ", ")
+		switch {
+		case len(violation.Clusters) == len(clusters):
+			where = "every cluster"
+		case len(violation.Clusters) == len(violation.Present):
+			// The object breaks the rule wherever it exists, it is just missing from the other clusters.
+			where = "object only in " + where
+		case len(violation.Clusters) == 1:
+			where = "only in " + where
+		}
+		fmt.Printf("- [%s] %s: %s\n", where, violation.ID(), violation.Message)
+	}
+}
+
+// setupRepeat records the reported findings of every run of --repeat, after the baseline filtered them.
+func setupRepeat(args cli.ArgumentsReceivedValidated) *flapping.Recorder {
+	if args.Repeat < 2 {
+		return nil
+	}
+	recorder := flapping.NewRecorder()
+	filter := compare.Findings
+	compare.Findings = func(diffs []DAO.DiffWithName) []DAO.DiffWithName {
+		if filter != nil {
+			diffs = filter(diffs)
+		}
+		return recorder.Record(diffs)
+	}
+	return recorder
+}
+
+// reportRepeat prints how every finding behaved over the runs, and suggests ignore rules for the flapping ones.
+func reportRepeat(recorder *flapping.Recorder, args cli.ArgumentsReceivedValidated) {
+	if recorder == nil {
+		return
+	}
+	results := recorder.Results()
+	fmt.Printf("Findings over %d runs:\n", args.Repeat)
+	if len(results) == 0 {
+		fmt.Println("No differences found.")
+		return
+	}
+	for _, result := range results {
+		switch result.Class {
+		case flapping.Flapping:
+			values := make([]string, len(result.Values))
+			for i, value := range result.Values {
+				values[i] = value
+				if value == "" {
+					values[i] = "<none>"
+				}
+			}
+			fmt.Printf("- [%s] %s: %s\n", result.Class, result.ID, strings.Join(values, " | "))
+		default:
+			fmt.Printf("- [%s] %s: in %d of %d runs\n", result.Class, result.ID, result.Present(), args.Repeat)
+		}
+	}
+	var commands []string
+	for _, suggestion := range flapping.Suggestions(results) {
+		if _, found := compare.Lookup(suggestion.Kind); !found {
+			continue
+		}
+		namespace := ""
+		if suggestion.Namespace != "" {
+			namespace = " -n " + suggestion.Namespace
+		}
+		commands = append(commands, fmt.Sprintf("kubectl annotate %s %s%s --overwrite %s=%s",
+			suggestion.Kind, suggestion.Name, namespace, compare.IgnoreFieldsAnnotation, strings.Join(suggestion.Fields, ",")))
+	}
+	if len(commands) != 0 {
+		fmt.Println("Fields that keep changing, e.g. rewritten by a controller, can be ignored with:")
+		for _, command := range commands {
+			fmt.Printf("- %s\n", command)
+		}
+	}
+}
+
+// compareClusters compares the global objects and the objects of every namespace in scope, kind by kind.
+func compareClusters(source, target compare.Cluster, args cli.ArgumentsReceivedValidated) {
+	var err error
 	// Determine namespace argument type
 	var sourceNameSpacesList *v1.NamespaceList
 	var sourceNameSpace *v1.Namespace
@@ -48,69 +325,177 @@ func main() {
 	switch namespaceArgType {
 	case "specific":
 		fmt.Println("Using", args.NamespaceName, "namespace")
-		sourceNameSpace, err = query.GetNamespace(clientsetToSource, args.NamespaceName)
+		sourceNameSpace, err = query.GetNamespace(source.Clientset, args.NamespaceName)
 		if err != nil {
 			err = fmt.Errorf("error listing namespaces: %v", err)
 			panic(err)
 		}
 		sourceNameSpacesList = &v1.NamespaceList{Items: []v1.Namespace{*sourceNameSpace}}
 	case "wildcard":
-		sourceNameSpacesList, err = query.ListNameSpaces(clientsetToSource)
+		sourceNameSpacesList, err = query.ListNameSpaces(source.Clientset)
 		if err != nil {
 			err = fmt.Errorf("error listing namespaces: %v", err)
 			panic(err)
 		}
 		sourceNameSpacesList = filterNamespaces(sourceNameSpacesList, args.NamespaceName)
 	case "empty":
-		iterateGoglabObjects(clientsetToSource, clientsetToTarget, args)
-		sourceNameSpacesList, err = query.ListNameSpaces(clientsetToSource)
+		iterateGoglabObjects(source, target, args)
+		sourceNameSpacesList, err = query.ListNameSpaces(source.Clientset)
 		if err != nil {
 			err = fmt.Errorf("error listing namespaces: %v", err)
 			panic(err)
 		}
 	}
-
+	if args.Settle.Deleting {
+		targetNameSpacesList, err := query.ListNameSpaces(target.Clientset)
+		if err != nil {
+			err = fmt.Errorf("error listing namespaces: %v", err)
+			panic(err)
+		}
+		sourceNameSpacesList = settledNamespaces(sourceNameSpacesList, targetNameSpacesList, args.Settle)
+	}
 	// Iterate over namespaces
-	iterateNamespaces(sourceNameSpacesList, clientsetToSource, clientsetToTarget, args)
+	iterateNamespaces(sourceNameSpacesList, source, target, args)
+}
 
-	fmt.Println("Finished all comparison works!")
+// compareContexts compares every kind across all the --contexts clusters at once, printing a matrix per kind and namespace.
+// With --fleet, it prints the deviating clusters instead, then ranks the clusters.
+func compareContexts(args cli.ArgumentsReceivedValidated) {
+	clientsets, err := connect.ConnectToContexts(args.Contexts, &args.KubeconfigFile)
+	if err != nil {
+		err = fmt.Errorf("error connecting to clusters: %v", err)
+		panic(err)
+	}
+	clusters := make([]compare.Cluster, len(clientsets))
+	for i, clientset := range clientsets {
+		clusters[i] = compare.Cluster{Clientset: clientset, Context: args.Contexts[i], Kubeconfig: args.KubeconfigFile}
+	}
+
+	var deviations []compare.Deviation
+	if args.NamespaceName == "" {
+		for _, resource := range selectedKinds(compare.ClusterScopedKinds(), args) {
+			deviations = append(deviations, compareResourceMatrix(clusters, "", resource, args)...)
+		}
+	}
+	namespaces := map[string]bool{}
+	for _, cluster := range clusters {
+		list, err := query.ListNameSpaces(cluster.Clientset)
+		if err != nil {
+			err = fmt.Errorf("error listing namespaces in %s: %v", compare.ClusterName(cluster), err)
+			panic(err)
+		}
+		for _, ns := range list.Items {
+			if args.NamespaceName == "" || matchWildcard(ns.Name, args.NamespaceName) {
+				namespaces[ns.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, namespace := range names {
+		fmt.Printf("Looping namespace: %s\n", namespace)
+		for _, resource := range selectedKinds(compare.NamespacedKinds(), args) {
+			deviations = append(deviations, compareResourceMatrix(clusters, namespace, resource, args)...)
+		}
+	}
+	if args.Fleet {
+		fmt.Println("Clusters ranked by deviations:")
+		for i, rank := range compare.RankClusters(args.Contexts, deviations) {
+			fmt.Printf("%d. %s: %d deviations (score %d)\n", i+1, rank.Cluster, rank.Deviations, rank.Score)
+		}
+	}
+}
+
+// compareResourceMatrix prints the fields of a kind that are not the same in every cluster.
+// With --fleet, it prints and returns the clusters that deviate from the golden cluster or the majority instead.
+func compareResourceMatrix(clusters []compare.Cluster, namespace, resource string, args cli.ArgumentsReceivedValidated) []compare.Deviation {
+	comparator, found := compare.Lookup(resource)
+	if !found {
+		return nil
+	}
+	if !comparator.Namespaced() {
+		namespace = ""
+	}
+	rows, err := compare.CompareKindMatrix(comparator, clusters, namespace, args)
+	if err != nil {
+		err = fmt.Errorf("error comparing %s: %v", comparator.GroupKind().Kind, err)
+		panic(err)
+	}
+	titleCase := cases.Title(language.English)
+	fmt.Printf("%s\n", titleCase.String(resource))
+	if len(rows) == 0 {
+		fmt.Println("No differences found.")
+		return nil
+	}
+	if !args.Fleet {
+		fmt.Print(compare.FormatMatrix(rows))
+		return nil
+	}
+	deviations := compare.FleetDeviations(rows, args.Golden)
+	for _, deviation := range deviations {
+		fmt.Printf("- [%s] %s\n", deviation.Severity, deviation)
+	}
+	return deviations
 }
 
-func iterateGoglabObjects(clientsetToSource, clientsetToTarget *kubernetes.Clientset, args cli.ArgumentsReceivedValidated) bool {
+// selectedKinds filters kinds with the include and exclude lists, like the two-cluster comparison.
+func selectedKinds(kinds []string, args cli.ArgumentsReceivedValidated) []string {
+	var selected []string
+	for _, kind := range kinds {
+		if args.Include != nil && !tools.IsInList(kind, args.Include) {
+			continue
+		}
+		if args.Exclude != nil && tools.IsInList(kind, args.Exclude) {
+			continue
+		}
+		selected = append(selected, kind)
+	}
+	return selected
+}
+
+// runAnalyses runs the analyses given with --analyze instead of the per-kind comparison.
+func runAnalyses(source, target compare.Cluster, args cli.ArgumentsReceivedValidated) {
+	scope := analysis.Scope{Source: source, Target: target, Namespace: args.NamespaceName, Args: args}
+	for _, name := range args.Analyze {
+		a, found := analysis.Lookup(name)
+		if !found {
+			fmt.Printf("Unknown analysis %s, the available analyses are: %s\n", name, strings.Join(analysis.Names(), ", "))
+			continue
+		}
+		findings, err := a.Run(scope)
+		if err != nil {
+			err = fmt.Errorf("error running the %s analysis: %v", name, err)
+			panic(err)
+		}
+		if compare.Compared != nil {
+			// Analysis findings are in scope in the namespaces given with -n, and cluster-wide.
+			namespaces := args.NamespaceName
+			if namespaces == "" {
+				namespaces = "*"
+			}
+			compare.Compared(name, namespaces)
+			compare.Compared(name, "")
+		}
+		if compare.Findings != nil {
+			findings = compare.Findings(findings)
+		}
+		analysis.Print(a, findings)
+	}
+}
+
+func iterateGoglabObjects(source, target compare.Cluster, args cli.ArgumentsReceivedValidated) bool {
 	// Flag to track if any comparison was performed
 	comparisonPerformed := false
+	globalObjects := compare.ClusterScopedKinds()
 
 	// Compare objects based on include list
 	if args.Include != nil {
-		includeObjects := []string{"namespace", "crd", "clusterrole", "clusterrolebinding"}
-		for _, objectType := range includeObjects {
+		for _, objectType := range globalObjects {
 			if tools.IsInList(objectType, args.Include) {
-				switch objectType {
-				case "namespace":
-					_, err := compare.CompareNameSpaces(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("error comparing Namespaces: %v", err)
-						panic(err)
-					}
-				case "crd":
-					_, err := compare.CompareCRDs(args.TargetClusterContext, args.KubeconfigFile, args)
-					if err != nil {
-						err = fmt.Errorf("error comparing CRDs: %v", err)
-						panic(err)
-					}
-				case "clusterrole":
-					_, err := compare.CompareClusterRoles(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("error comparing Cluster Role: %v", err)
-						panic(err)
-					}
-				case "clusterrolebinding":
-					_, err := compare.CompareClusterRoleBindings(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("error comparing Cluster Role: %v", err)
-						panic(err)
-					}
-				}
+				compareResource(source, target, "", objectType, args)
 				comparisonPerformed = true
 			}
 		}
@@ -118,35 +503,9 @@ func iterateGoglabObjects(clientsetToSource, clientsetToTarget *kubernetes.Clien
 
 	// Compare objects based on exclude list
 	if args.Exclude != nil {
-		excludeObjects := []string{"namespace", "crd", "clusterrole", "clusterrolebinding"}
-		for _, objectType := range excludeObjects {
+		for _, objectType := range globalObjects {
 			if !tools.IsInList(objectType, args.Exclude) {
-				switch objectType {
-				case "namespace":
-					_, err := compare.CompareNameSpaces(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("Error comparing Namspace: %v", err)
-						panic(err)
-					}
-				case "crd":
-					_, err := compare.CompareCRDs(args.TargetClusterContext, args.KubeconfigFile, args)
-					if err != nil {
-						err = fmt.Errorf("Error comparing CRDs: %v", err)
-						panic(err)
-					}
-				case "clusterrole":
-					_, err := compare.CompareClusterRoles(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("Error comparing Cluster Role: %v", err)
-						panic(err)
-					}
-				case "clusterrolebinding":
-					_, err := compare.CompareClusterRoleBindings(clientsetToSource, clientsetToTarget, args)
-					if err != nil {
-						err = fmt.Errorf("Error comparing Cluster Role Binding: %v", err)
-						panic(err)
-					}
-				}
+				compareResource(source, target, "", objectType, args)
 				comparisonPerformed = true
 			}
 		}
@@ -154,25 +513,8 @@ func iterateGoglabObjects(clientsetToSource, clientsetToTarget *kubernetes.Clien
 
 	// If no include or exclude lists are provided, perform default comparisons
 	if args.Include == nil && args.Exclude == nil {
-		_, err := compare.CompareNameSpaces(clientsetToSource, clientsetToTarget, args)
-		if err != nil {
-			err = fmt.Errorf("error comparing Namespaces: %v", err)
-			panic(err)
-		}
-		_, err = compare.CompareCRDs(args.TargetClusterContext, args.KubeconfigFile, args)
-		if err != nil {
-			err = fmt.Errorf("error comparing CRDs: %v", err)
-			panic(err)
-		}
-		_, err = compare.CompareClusterRoles(clientsetToSource, clientsetToTarget, args)
-		if err != nil {
-			err = fmt.Errorf("error comparing Cluster Roles: %v", err)
-			panic(err)
-		}
-		_, err = compare.CompareClusterRoleBindings(clientsetToSource, clientsetToTarget, args)
-		if err != nil {
-			err = fmt.Errorf("error comparing Cluster Role Bindings: %v", err)
-			panic(err)
+		for _, objectType := range globalObjects {
+			compareResource(source, target, "", objectType, args)
 		}
 		comparisonPerformed = true
 	}
@@ -184,10 +526,10 @@ func iterateGoglabObjects(clientsetToSource, clientsetToTarget *kubernetes.Clien
 	return comparisonPerformed
 }
 
-func compareAllResourcesInNamespace(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespace string, TheArgs cli.ArgumentsReceivedValidated) {
+func compareAllResourcesInNamespace(source, target compare.Cluster, namespace string, TheArgs cli.ArgumentsReceivedValidated) {
 	fmt.Printf("Looping on Namespace: %s\n", namespace)
 	// Compare all resources for the namespace
-	resources := []string{"deployment", "ingress", "service", "serviceaccount", "configmap", "secret", "role", "rolebinding", "hpa", "cronjob", "networkpolicy"}
+	resources := compare.NamespacedKinds()
 
 	// Create a title case converter for English
 	titleCase := cases.Title(language.English)
@@ -195,14 +537,14 @@ func compareAllResourcesInNamespace(clientsetToSource, clientsetToTarget *kubern
 	for _, resource := range resources {
 		titleResource := titleCase.String(resource)
 		fmt.Printf("%s\n", titleResource)
-		compareResource(clientsetToSource, clientsetToTarget, namespace, resource, TheArgs)
+		compareResource(source, target, namespace, resource, TheArgs)
 		fmt.Printf("Finished %s for namespace: %s\n", titleResource, namespace)
 	}
 
 	fmt.Printf("... Done with all resources in ns: %s.\n", namespace)
 }
 
-func compareResourcesByLists(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespace string, TheArgs cli.ArgumentsReceivedValidated) {
+func compareResourcesByLists(source, target compare.Cluster, namespace string, TheArgs cli.ArgumentsReceivedValidated) {
 	fmt.Printf("Looping namespace: %s\n", namespace)
 
 	includeResources := TheArgs.Include
@@ -212,13 +554,16 @@ func compareResourcesByLists(clientsetToSource, clientsetToTarget *kubernetes.Cl
 	titleCase := cases.Title(language.English)
 
 	// Define all resources
-	resources := []string{"deployment", "ingress", "service", "serviceaccout", "configmap", "secret", "role", "rolebinding", "networkpolicy", "hpa", "cronjob"}
+	resources := compare.NamespacedKinds()
 
 	// Compare resources based on include list
 	for _, resource := range includeResources {
+		if !tools.IsInList(resource, resources) {
+			continue
+		}
 		titleResource := titleCase.String(resource)
 		fmt.Printf("%s\n", titleResource)
-		compareResource(clientsetToSource, clientsetToTarget, namespace, resource, TheArgs)
+		compareResource(source, target, namespace, resource, TheArgs)
 		fmt.Printf("Finished %s for namespace: %s\n", titleResource, namespace)
 
 	}
@@ -230,97 +575,42 @@ func compareResourcesByLists(clientsetToSource, clientsetToTarget *kubernetes.Cl
 			if !tools.IsInList(resource, excludeResources) {
 				titleResource := titleCase.String(resource)
 				fmt.Printf("%s\n", titleResource)
-				compareResource(clientsetToSource, clientsetToTarget, namespace, resource, TheArgs)
+				compareResource(source, target, namespace, resource, TheArgs)
 				fmt.Printf("Finished %s for namespace: %s\n", titleResource, namespace)
 			}
 		}
 	}
 }
 
-func compareResource(clientsetToSource, clientsetToTarget *kubernetes.Clientset, namespace, resource string, TheArgs cli.ArgumentsReceivedValidated) {
-	switch resource {
-	case "deployment":
-		_, err := compare.CompareDeployments(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Deployments: %v", err)
-			panic(err)
-		}
-	case "ingress":
-		_, err := compare.CompareIngresses(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Ingresses: %v", err)
-			panic(err)
-		}
-	case "service":
-		_, err := compare.CompareServices(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Services: %v", err)
-			panic(err)
-		}
-	case "serviceaccount":
-		_, err := compare.CompareServiceAccounts(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Service Accounts: %v", err)
-			panic(err)
-		}
-	case "configmap":
-		_, err := compare.CompareConfigMaps(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Config Maps: %v", err)
-			panic(err)
-		}
-	case "secret":
-		_, err := compare.CompareSecrets(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Secrets: %v", err)
-			panic(err)
-		}
-	case "role":
-		_, err := compare.CompareRoles(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Roles: %v", err)
-			panic(err)
-		}
-	case "rolebinding":
-		_, err := compare.CompareRoleBindings(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Role Bindings: %v", err)
-			panic(err)
-		}
-	case "hpa":
-		_, err := compare.CompareHPAs(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Horizontal Pod Autoscalers: %v", err)
-			panic(err)
-		}
-	case "cronjob":
-		_, err := compare.CompareCronJobs(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Cron Jobs: %v", err)
-			panic(err)
-		}
-	case "networkpolicy":
-		_, err := compare.CompareNetworkPolicies(clientsetToSource, clientsetToTarget, namespace, TheArgs)
-		if err != nil {
-			err = fmt.Errorf("error comparing Network Policies: %v", err)
-			panic(err)
-		}
+// compareResource runs the registered comparator of a kind; namespace is ignored for cluster-scoped kinds.
+func compareResource(source, target compare.Cluster, namespace, resource string, TheArgs cli.ArgumentsReceivedValidated) {
+	comparator, found := compare.Lookup(resource)
+	if !found {
+		return
+	}
+	if !comparator.Namespaced() {
+		namespace = ""
+	}
+	_, err := compare.CompareKind(comparator, source, target, namespace, TheArgs)
+	if err != nil {
+		err = fmt.Errorf("error comparing %s: %v", comparator.GroupKind().Kind, err)
+		panic(err)
 	}
 }
 
-func iterateNamespaces(sourceNameSpacesList *v1.NamespaceList, clientsetToSource, clientsetToTarget *kubernetes.Clientset, TheArgs cli.ArgumentsReceivedValidated) {
+func iterateNamespaces(sourceNameSpacesList *v1.NamespaceList, source, target compare.Cluster, TheArgs cli.ArgumentsReceivedValidated) {
 	// Check if include or exclude lists are provided, or if no specific lists are provided
 	if TheArgs.Include == nil && TheArgs.Exclude == nil {
 		// If no include or exclude lists are provided, compare all resources for each namespace
 		for _, ns := range sourceNameSpacesList.Items {
-			compareAllResourcesInNamespace(clientsetToSource, clientsetToTarget, ns.Name, TheArgs)
+			compareAllResourcesInNamespace(source, target, ns.Name, TheArgs)
 		}
 	} else {
 		// Compare resources based on include or exclude lists
-		resources := []string{"deployment", "ingress", "service", "serviceaccount", "configmap", "secret", "role", "rolebinding", "hpa", "cronjob", "networkpolicy"}
+		resources := compare.NamespacedKinds()
 		if tools.AreAnyInLists(TheArgs.Include, resources) || tools.AreAnyInLists(TheArgs.Exclude, resources) {
 			for _, ns := range sourceNameSpacesList.Items {
-				compareResourcesByLists(clientsetToSource, clientsetToTarget, ns.Name, TheArgs)
+				compareResourcesByLists(source, target, ns.Name, TheArgs)
 			}
 		} else {
 			fmt.Println("No namespaced resources to compare")
@@ -328,6 +618,41 @@ func iterateNamespaces(sourceNameSpacesList *v1.NamespaceList, clientsetToSource
 	}
 }
 
+// settledNamespaces leaves out the namespaces being deleted in either cluster, whose objects are all on their way out.
+// With --settle mark, they are kept and the differences of their objects are reported as expected instead.
+func settledNamespaces(namespaces, targetNamespaces *v1.NamespaceList, settle cli.SettleOptions) *v1.NamespaceList {
+	deletedInTarget := map[string]bool{}
+	for _, ns := range targetNamespaces.Items {
+		if ns.DeletionTimestamp != nil {
+			deletedInTarget[ns.Name] = true
+		}
+	}
+	settled := v1.NamespaceList{
+		Items: []v1.Namespace{},
+	}
+	inFlight := map[string]string{}
+	for _, ns := range namespaces.Items {
+		reason := ""
+		if ns.DeletionTimestamp != nil {
+			reason = "namespace being deleted in the source cluster"
+		} else if deletedInTarget[ns.Name] {
+			reason = "namespace being deleted in the target cluster"
+		}
+		switch {
+		case reason == "":
+		case settle.Mark:
+			fmt.Printf("Marking namespace %s: %s\n", ns.Name, reason)
+			inFlight[ns.Name] = reason
+		default:
+			fmt.Printf("Skipping namespace %s: %s\n", ns.Name, reason)
+			continue
+		}
+		settled.Items = append(settled.Items, ns)
+	}
+	compare.SetNamespacesInFlight(inFlight)
+	return &settled
+}
+
 // filterNamespaces filters namespaces based on the wildcard pattern
 func filterNamespaces(namespaces *v1.NamespaceList, pattern string) *v1.NamespaceList {
 	matchingNamespaces := v1.NamespaceList{
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"kompare/cli"
+	"kompare/compare"
 	"kompare/mock"
 
 	"github.com/stretchr/testify/assert"
@@ -60,6 +63,45 @@ func TestMain(t *testing.T) {
 		main()
 	}()
 
+	// Run the analyses instead of the per-kind comparison
+	os.Args = []string{"main.go", "-t", "target-context", "-s", "source-context", "-c", kubeconfigFile.Name(), "--analyze", "rbac,netpol,references,impact,owners,env,posture,exposure,images,capacity,crds,discovery,deprecations,unknown"}
+	assert.NotPanics(t, func() { main() }, "Expected the analyses to run against the mock cluster")
+
+	// Compare any number of clusters at once
+	os.Args = []string{"main.go", "--contexts", "source-context,target-context", "-c", kubeconfigFile.Name()}
+	assert.NotPanics(t, func() { main() }, "Expected the contexts to be compared against the mock cluster")
+
+	os.Args = []string{"main.go", "--contexts", "source-context,target-context", "--golden", "source-context", "-c", kubeconfigFile.Name()}
+	assert.NotPanics(t, func() { main() }, "Expected the fleet to be compared against the golden context")
+
+	// Leave in-flight objects out, looking at them again after a short delay
+	os.Args = []string{"main.go", "-t", "target-context", "-s", "source-context", "-c", kubeconfigFile.Name(), "-n", "namespace2", "--settle", "age=1m,deleting,reconcile,recheck=10ms", "-v"}
+	assert.NotPanics(t, func() { main() }, "Expected the comparison to settle in-flight objects")
+
+	// Run the comparison twice to classify the findings
+	os.Args = []string{"main.go", "-t", "target-context", "-s", "source-context", "-c", kubeconfigFile.Name(), "-n", "namespace2", "--repeat", "2", "--interval", "10ms"}
+	assert.NotPanics(t, func() { main() }, "Expected the comparison to run repeatedly")
+
+	// Run the comparison twice with a state file, the second run only diffs what changed
+	stateFile := filepath.Join(t.TempDir(), "kompare-state.json")
+	for i := 0; i < 2; i++ {
+		os.Args = []string{"main.go", "-t", "target-context", "-s", "source-context", "-c", kubeconfigFile.Name(), "-n", "namespace2", "--state", stateFile}
+		assert.NotPanics(t, func() { main() }, "Expected the comparison to run incrementally")
+	}
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Errorf("Expected the state file to be written, got: %v", err)
+	}
+
+	// Check conventions on the objects of both clusters, and of every context
+	lintRules := filepath.Join(t.TempDir(), "lint.yaml")
+	if err := os.WriteFile(lintRules, []byte("rules:\n- kind: deployment\n  requiredLabels: [team]\n  namePattern: \"[a-z0-9-]+\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"main.go", "-t", "target-context", "-s", "source-context", "-c", kubeconfigFile.Name(), "-n", "namespace2", "--lint", lintRules}
+	assert.NotPanics(t, func() { main() }, "Expected the objects to be linted")
+	os.Args = []string{"main.go", "--contexts", "source-context,target-context", "-c", kubeconfigFile.Name(), "--lint", lintRules}
+	assert.NotPanics(t, func() { main() }, "Expected the objects of every context to be linted")
+
 	// Assert that clientsetToSource and clientsetToTarget are not nil after connecting to clusters
 	fmt.Println("Test completed")
 }
@@ -151,3 +193,24 @@ func TestFilterNamespaces(t *testing.T) {
 		})
 	}
 }
+
+func TestSettledNamespaces(t *testing.T) {
+	deleting := metav1.Now()
+	source := &v1.NamespaceList{Items: []v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "old", DeletionTimestamp: &deleting}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "moving"}},
+	}}
+	target := &v1.NamespaceList{Items: []v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "moving", DeletionTimestamp: &deleting}},
+	}}
+	defer compare.SetNamespacesInFlight(map[string]string{})
+
+	settled := settledNamespaces(source, target, cli.SettleOptions{Deleting: true})
+	assert.Equal(t, 1, len(settled.Items))
+	assert.Equal(t, "payments", settled.Items[0].Name)
+
+	marked := settledNamespaces(source, target, cli.SettleOptions{Deleting: true, Mark: true})
+	assert.Equal(t, 3, len(marked.Items))
+}
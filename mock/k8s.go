@@ -12,13 +12,16 @@ import (
 	"github.com/gorilla/mux"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	Corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	RbacV1 "k8s.io/api/rbac/v1"
 	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
 )
 
 func StartMockCluster() (string, *mux.Router, error) {
@@ -31,9 +34,11 @@ func StartMockCluster() (string, *mux.Router, error) {
 	r.HandleFunc("/api/v1/namespaces/{namespace}/configmaps", GetConfigMaps).Methods("GET")
 	r.HandleFunc("/api/v1/namespaces/{namespace}/services", GetServices).Methods("GET")
 	r.HandleFunc("/apis/autoscaling/v1/namespaces/{namespace}/horizontalpodautoscalers", GetHPAs).Methods("GET")
+	r.HandleFunc("/apis/autoscaling/v2/namespaces/{namespace}/horizontalpodautoscalers", GetHPAsV2).Methods("GET")
 	r.HandleFunc("/apis/batch/v1/namespaces/{namespace}/cronjobs", GetCronJobs).Methods("GET")
 	r.HandleFunc("/api/v1/namespaces", GetNamespaces).Methods("GET")
 	r.HandleFunc("/api/v1/namespaces/namespace2", GetNamespace).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/kube-system", GetKubeSystemNamespace).Methods("GET")
 	r.HandleFunc("/apis/rbac.authorization.k8s.io/v1/namespaces/{namespace}/roles", GetRoles).Methods("GET")
 	r.HandleFunc("/apis/rbac.authorization.k8s.io/v1/namespaces/{namespace}/rolebindings", GetRoleBindings).Methods("GET")
 	r.HandleFunc("/apis/apiextensions.k8s.io/v1/customresourcedefinitions", GetCustomResourceDefinitions).Methods("GET")
@@ -41,6 +46,20 @@ func StartMockCluster() (string, *mux.Router, error) {
 	r.HandleFunc("/apis/rbac.authorization.k8s.io/v1/clusterrolebindings", GetClusterRoleBindings).Methods("GET")
 	r.HandleFunc("/api/v1/namespaces/{namespace}/serviceaccounts", GetServiceAccounts).Methods("GET")
 	r.HandleFunc("/apis/networking.k8s.io/v1/namespaces/{namespace}/networkpolicies", GetNetworkPolicies).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/persistentvolumeclaims", GetPersistentVolumeClaims).Methods("GET")
+	r.HandleFunc("/apis/apps/v1/namespaces/{namespace}/statefulsets", GetStatefulSets).Methods("GET")
+	r.HandleFunc("/apis/apps/v1/namespaces/{namespace}/daemonsets", GetDaemonSets).Methods("GET")
+	r.HandleFunc("/apis/apps/v1/namespaces/{namespace}/replicasets", GetReplicaSets).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/pods", GetPods).Methods("GET")
+	r.HandleFunc("/apis/networking.k8s.io/v1/ingressclasses", GetIngressClasses).Methods("GET")
+	r.HandleFunc("/api/v1/nodes", GetNodes).Methods("GET")
+	r.HandleFunc("/version", GetVersion).Methods("GET")
+	r.HandleFunc("/api", GetCoreAPIVersions).Methods("GET")
+	r.HandleFunc("/apis", GetAPIGroups).Methods("GET")
+	r.HandleFunc("/api/{version}", GetAPIResources).Methods("GET")
+	r.HandleFunc("/apis/{group}/{version}", GetAPIResources).Methods("GET")
+	r.HandleFunc("/apis/example.com/v1/widgets", GetWidgets).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/resourcequotas", GetResourceQuotas).Methods("GET")
 
 	// Create a HTTP server instance
 	server := &http.Server{
@@ -101,6 +120,27 @@ func GetNamespace(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetKubeSystemNamespace serves the kube-system namespace, whose UID identifies the cluster.
+func GetKubeSystemNamespace(w http.ResponseWriter, r *http.Request) {
+	namespace := v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-system",
+			UID:  "4f9a5c2e-7d1b-4e8a-9c3f-2b6d8e0a1f47",
+		},
+	}
+	namespaceJSON, err := json.Marshal(namespace)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error marshaling namespace to JSON: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(namespaceJSON)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
 // NamespacesHandler handles requests to /api/v1/namespaces
 func GetNamespaces(w http.ResponseWriter, r *http.Request) {
 	// Define a sample list of namespaces
@@ -1195,3 +1235,534 @@ func GetClusterRoleBindings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// GetPersistentVolumeClaims handles HTTP requests to retrieve PersistentVolumeClaims.
+func GetPersistentVolumeClaims(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	pvcs := &Corev1.PersistentVolumeClaimList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []Corev1.PersistentVolumeClaim{},
+	}
+	// Only "namespace2" has claims
+	if namespace == "namespace2" {
+		pvcs.ResourceVersion = "320850103"
+		for _, name := range []string{"data-1", "data-2"} {
+			pvcs.Items = append(pvcs.Items, Corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+		}
+	}
+
+	// Convert the PersistentVolumeClaimList object to JSON
+	jsonResponse, err := json.Marshal(pvcs)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetStatefulSets handles HTTP requests to retrieve StatefulSets.
+func GetStatefulSets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	statefulSets := &appsv1.StatefulSetList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []appsv1.StatefulSet{},
+	}
+	// Only "namespace2" has stateful sets
+	if namespace == "namespace2" {
+		statefulSets.ResourceVersion = "320850103"
+		for _, name := range []string{"statefulset1", "statefulset2"} {
+			statefulSets.Items = append(statefulSets.Items, appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+		}
+	}
+
+	// Convert the StatefulSetList object to JSON
+	jsonResponse, err := json.Marshal(statefulSets)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetDaemonSets handles HTTP requests to retrieve DaemonSets.
+func GetDaemonSets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	daemonSets := &appsv1.DaemonSetList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []appsv1.DaemonSet{},
+	}
+	// Only "namespace2" has daemon sets
+	if namespace == "namespace2" {
+		daemonSets.ResourceVersion = "320850103"
+		for _, name := range []string{"daemonset1"} {
+			daemonSets.Items = append(daemonSets.Items, appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+		}
+	}
+
+	// Convert the DaemonSetList object to JSON
+	jsonResponse, err := json.Marshal(daemonSets)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetReplicaSets handles HTTP requests to retrieve ReplicaSets.
+func GetReplicaSets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	replicaSets := &appsv1.ReplicaSetList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []appsv1.ReplicaSet{},
+	}
+	// Only "namespace2" has replica sets
+	if namespace == "namespace2" {
+		replicaSets.ResourceVersion = "320850103"
+		for _, name := range []string{"deployment1-5d4f8c7b9"} {
+			replicaSets.Items = append(replicaSets.Items, appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+		}
+	}
+
+	// Convert the ReplicaSetList object to JSON
+	jsonResponse, err := json.Marshal(replicaSets)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetPods handles HTTP requests to retrieve Pods.
+func GetPods(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	pods := &Corev1.PodList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []Corev1.Pod{},
+	}
+	// Only "namespace2" has pods
+	if namespace == "namespace2" {
+		pods.ResourceVersion = "320850103"
+		for _, name := range []string{"deployment1-5d4f8c7b9-abcde", "deployment1-5d4f8c7b9-fghij"} {
+			pods.Items = append(pods.Items, Corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			})
+		}
+	}
+
+	// Convert the PodList object to JSON
+	jsonResponse, err := json.Marshal(pods)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetIngressClasses handles HTTP requests to retrieve IngressClass resources.
+func GetIngressClasses(w http.ResponseWriter, r *http.Request) {
+	ingressClasses := &networkingv1.IngressClassList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "320850103",
+		},
+		Items: []networkingv1.IngressClass{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "nginx",
+					Annotations: map[string]string{"ingressclass.kubernetes.io/is-default-class": "true"},
+				},
+				Spec: networkingv1.IngressClassSpec{Controller: "k8s.io/ingress-nginx"},
+			},
+		},
+	}
+
+	// Convert the IngressClassList object to JSON
+	jsonResponse, err := json.Marshal(ingressClasses)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetNodes handles HTTP requests to retrieve Node resources.
+func GetNodes(w http.ResponseWriter, r *http.Request) {
+	nodes := &Corev1.NodeList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "320850103",
+		},
+	}
+	for _, name := range []string{"node1", "node2"} {
+		nodes.Items = append(nodes.Items, Corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: Corev1.NodeStatus{Allocatable: Corev1.ResourceList{
+				Corev1.ResourceCPU:    resource.MustParse("4"),
+				Corev1.ResourceMemory: resource.MustParse("16Gi"),
+			}},
+		})
+	}
+
+	// Convert the NodeList object to JSON
+	jsonResponse, err := json.Marshal(nodes)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetResourceQuotas handles HTTP requests to retrieve ResourceQuota resources.
+func GetResourceQuotas(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	resourceQuotas := &Corev1.ResourceQuotaList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []Corev1.ResourceQuota{},
+	}
+	// Only "namespace2" has a quota
+	if namespace == "namespace2" {
+		resourceQuotas.ResourceVersion = "320850103"
+		resourceQuotas.Items = append(resourceQuotas.Items, Corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: namespace},
+			Spec: Corev1.ResourceQuotaSpec{Hard: Corev1.ResourceList{
+				Corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				Corev1.ResourceRequestsMemory: resource.MustParse("4Gi"),
+			}},
+		})
+	}
+
+	// Convert the ResourceQuotaList object to JSON
+	jsonResponse, err := json.Marshal(resourceQuotas)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetWidgets handles HTTP requests to retrieve custom resources of a sample "widgets.example.com" CRD.
+func GetWidgets(w http.ResponseWriter, r *http.Request) {
+	widgets := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "WidgetList",
+		"metadata":   map[string]interface{}{"resourceVersion": "320850103"},
+		"items": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Widget",
+				"metadata":   map[string]interface{}{"name": "widget1", "namespace": "namespace2"},
+				"spec":       map[string]interface{}{"size": "small"},
+			},
+		},
+	}
+
+	// Convert the WidgetList object to JSON
+	jsonResponse, err := json.Marshal(widgets)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetHPAsV2 handles HTTP requests to retrieve HorizontalPodAutoscaler resources as autoscaling/v2,
+// which keeps the metrics autoscaling/v1 cannot represent, like memory utilization.
+func GetHPAsV2(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+
+	hpas := &autoscalingv2.HorizontalPodAutoscalerList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0",
+		},
+		Items: []autoscalingv2.HorizontalPodAutoscaler{},
+	}
+	// Create three sample HPAs for namespace2, scaling on CPU and memory
+	if namespace == "namespace2" {
+		hpas.ResourceVersion = "320850103"
+		cpu, memory := int32(80), int32(70)
+		for _, name := range []string{"hpa1", "hpa2", "hpa3"} {
+			hpas.Items = append(hpas.Items, autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "namespace2",
+				},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "deployment1", APIVersion: "apps/v1"},
+					MaxReplicas:    5,
+					Metrics: []autoscalingv2.MetricSpec{
+						{Type: autoscalingv2.ResourceMetricSourceType, Resource: &autoscalingv2.ResourceMetricSource{
+							Name: "cpu", Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &cpu}}},
+						{Type: autoscalingv2.ResourceMetricSourceType, Resource: &autoscalingv2.ResourceMetricSource{
+							Name: "memory", Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &memory}}},
+					},
+				},
+			})
+		}
+	}
+
+	// Convert the HPAList object to JSON
+	jsonResponse, err := json.Marshal(hpas)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// discoveryResources are the resources the mock API server serves, by group-version.
+var discoveryResources = map[string][]metav1.APIResource{
+	"v1": {
+		{Name: "namespaces", Kind: "Namespace", Verbs: metav1.Verbs{"get", "list"}},
+		{Name: "services", Namespaced: true, Kind: "Service", Verbs: metav1.Verbs{"get", "list"}},
+	},
+	"apps/v1": {
+		{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: metav1.Verbs{"get", "list"}},
+	},
+	"batch/v1": {
+		{Name: "cronjobs", Namespaced: true, Kind: "CronJob", Verbs: metav1.Verbs{"get", "list"}},
+	},
+	"autoscaling/v2": {
+		{Name: "horizontalpodautoscalers", Namespaced: true, Kind: "HorizontalPodAutoscaler", Verbs: metav1.Verbs{"get", "list"}},
+	},
+	"autoscaling/v1": {
+		{Name: "horizontalpodautoscalers", Namespaced: true, Kind: "HorizontalPodAutoscaler", Verbs: metav1.Verbs{"get", "list"}},
+	},
+}
+
+// discoveryGroups are the versions of the API groups the mock API server serves, the preferred one first.
+var discoveryGroups = []struct {
+	Name     string
+	Versions []string
+}{
+	{"apps", []string{"v1"}},
+	{"batch", []string{"v1"}},
+	{"autoscaling", []string{"v2", "v1"}},
+}
+
+// GetVersion handles HTTP requests to retrieve the server version.
+func GetVersion(w http.ResponseWriter, r *http.Request) {
+	info := version.Info{Major: "1", Minor: "29", GitVersion: "v1.29.1"}
+
+	// Convert the version.Info object to JSON
+	jsonResponse, err := json.Marshal(info)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetCoreAPIVersions handles HTTP requests to discover the versions of the core API group.
+func GetCoreAPIVersions(w http.ResponseWriter, r *http.Request) {
+	versions := metav1.APIVersions{Versions: []string{"v1"}}
+
+	// Convert the APIVersions object to JSON
+	jsonResponse, err := json.Marshal(versions)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetAPIGroups handles HTTP requests to discover the API groups.
+func GetAPIGroups(w http.ResponseWriter, r *http.Request) {
+	groups := &metav1.APIGroupList{}
+	for _, discoveryGroup := range discoveryGroups {
+		group := metav1.APIGroup{Name: discoveryGroup.Name}
+		for _, version := range discoveryGroup.Versions {
+			group.Versions = append(group.Versions, metav1.GroupVersionForDiscovery{GroupVersion: discoveryGroup.Name + "/" + version, Version: version})
+		}
+		group.PreferredVersion = group.Versions[0]
+		groups.Groups = append(groups.Groups, group)
+	}
+
+	// Convert the APIGroupList object to JSON
+	jsonResponse, err := json.Marshal(groups)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetAPIResources handles HTTP requests to discover the resources of a group-version.
+func GetAPIResources(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupVersion := vars["version"]
+	if vars["group"] != "" {
+		groupVersion = vars["group"] + "/" + vars["version"]
+	}
+	resources, found := discoveryResources[groupVersion]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	resourceList := &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: resources}
+
+	// Convert the APIResourceList object to JSON
+	jsonResponse, err := json.Marshal(resourceList)
+	if err != nil {
+		http.Error(w, "Error marshalling JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	// Set the response headers and write the JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(jsonResponse)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error writing the JSON response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
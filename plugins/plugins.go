@@ -0,0 +1,134 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Prefix is the name every comparator plugin executable starts with, followed by the kind, e.g. kompare-compare-deployment.
+const Prefix = "kompare-compare-"
+
+// Timeout bounds how long a plugin may take for one pair of objects.
+var Timeout = 30 * time.Second
+
+// Severities a plugin may report. Anything else is reported as SeverityWarning.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Request is the JSON document a plugin receives on stdin, once per pair of objects.
+type Request struct {
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Source    interface{} `json:"source"`
+	Target    interface{} `json:"target"`
+}
+
+// Finding is one element of the JSON list a plugin writes on stdout.
+type Finding struct {
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]string{}
+)
+
+// Find returns the path of the plugin for the first of names that has one.
+// Plugins in dir take precedence over the ones on PATH; dir may be empty.
+// Lookups are cached for the lifetime of the process.
+func Find(dir string, names ...string) (string, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for _, name := range names {
+		key := dir + "\x00" + name
+		if path, found := cache[key]; found {
+			if path != "" {
+				return path, true
+			}
+			continue
+		}
+		path := lookup(dir, Prefix+name)
+		cache[key] = path
+		if path != "" {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// lookup finds an executable in dir, then on PATH. It returns "" when there is none.
+func lookup(dir, executable string) string {
+	if dir != "" {
+		candidate := filepath.Join(dir, executable)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate
+		}
+	}
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// Run executes a plugin with the request on stdin and decodes the findings it prints on stdout.
+// A plugin that exits with a non-zero status, or prints something else than a JSON list, is an error.
+func Run(path string, request Request) ([]Finding, error) {
+	request.Source = addressable(request.Source)
+	request.Target = addressable(request.Target)
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the request for plugin %s: %w", path, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var findings []Finding
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid findings: %w", path, err)
+	}
+	for i := range findings {
+		switch findings[i].Severity {
+		case SeverityInfo, SeverityWarning, SeverityError:
+		default:
+			findings[i].Severity = SeverityWarning
+		}
+	}
+	return findings, nil
+}
+
+// addressable returns a pointer to a copy of obj, so types that implement json.Marshaler
+// on their pointer, like unstructured.Unstructured, are encoded as Kubernetes objects.
+func addressable(obj interface{}) interface{} {
+	if obj == nil {
+		return nil
+	}
+	value := reflect.ValueOf(obj)
+	if value.Kind() == reflect.Ptr {
+		return obj
+	}
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	return ptr.Interface()
+}
@@ -0,0 +1,71 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	path := filepath.Join(dir, Prefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Error writing plugin: %v", err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	expected := writePlugin(t, dir, "widget", "echo '[]'\n")
+
+	path, found := Find(dir, "wdg", "widget")
+	if !found || path != expected {
+		t.Errorf("Expected to find %s, got %s (found: %t)", expected, path, found)
+	}
+	if _, found := Find(dir, "gadget"); found {
+		t.Errorf("Expected no plugin for 'gadget'")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	// The plugin checks that it received both objects and reports one finding per severity.
+	path := writePlugin(t, dir, "widget", `input=$(cat)
+case "$input" in
+  *'"source":{"apiVersion":"example.com/v1"'*'"target":{"metadata":{"name":"w1"'*) ;;
+  *) echo "unexpected input: $input" >&2; exit 1 ;;
+esac
+echo '[{"path":"spec.size","message":"size shrinks","severity":"error"},{"path":"spec.color","message":"color differs","severity":"loud"}]'
+`)
+	source := unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Widget"}}
+	target := metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "w1"}}
+
+	findings, err := Run(path, Request{Kind: "Widget", Name: "w1", Source: source, Target: target})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d", len(findings))
+	}
+	if findings[0].Severity != SeverityError || findings[0].Path != "spec.size" {
+		t.Errorf("Unexpected first finding: %+v", findings[0])
+	}
+	if findings[1].Severity != SeverityWarning {
+		t.Errorf("Expected unknown severities to become %s, got %s", SeverityWarning, findings[1].Severity)
+	}
+}
+
+func TestRunWithFailingPlugin(t *testing.T) {
+	dir := t.TempDir()
+	failing := writePlugin(t, dir, "failing", "echo boom >&2\nexit 3\n")
+	if _, err := Run(failing, Request{}); err == nil {
+		t.Errorf("Expected an error for a plugin exiting with a non-zero status")
+	}
+	garbage := writePlugin(t, dir, "garbage", "echo not json\n")
+	if _, err := Run(garbage, Request{}); err == nil {
+		t.Errorf("Expected an error for a plugin printing invalid findings")
+	}
+}
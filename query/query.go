@@ -7,6 +7,7 @@ import (
 
 	v1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 
 	networkingv1 "k8s.io/api/networking/v1"
@@ -16,7 +17,13 @@ import (
 
 	Corev1 "k8s.io/api/core/v1"
 	RbacV1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -77,6 +84,24 @@ func ListHPAs(clientset *kubernetes.Clientset, nameSpace string) (*autoscalingv1
 	return listHPA, nil
 }
 
+// ListHPAsV2 retrieves a list of HorizontalPodAutoscalers in the specified namespace as autoscaling/v2,
+// which keeps the memory, pods, object and external metrics autoscaling/v1 drops.
+// Parameters:
+// - clientset: The Kubernetes clientset used to make the API call.
+// - nameSpace: The namespace in which to list the HPAs. If empty, uses the "default" namespace.
+// Returns:
+// - (*autoscalingv2.HorizontalPodAutoscalerList): A list of HorizontalPodAutoscalers.
+func ListHPAsV2(clientset *kubernetes.Clientset, nameSpace string) (*autoscalingv2.HorizontalPodAutoscalerList, error) {
+	if nameSpace == "" {
+		nameSpace = "default"
+	}
+	listHPA, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the HPA List: %w", err)
+	}
+	return listHPA, nil
+}
+
 // ListCronJobs retrieves a list of CronJobs in the specified namespace.
 // Parameters:
 // - clientset: The Kubernetes clientset used to make the API call.
@@ -217,3 +242,134 @@ func ListNetworkPolicies(clientset *kubernetes.Clientset, nameSpace string) (*ne
 	}
 	return listNetworkPolicy, nil
 }
+
+// ListPersistentVolumeClaims retrieves a list of PersistentVolumeClaims in the specified namespace.
+func ListPersistentVolumeClaims(clientset *kubernetes.Clientset, nameSpace string) (*Corev1.PersistentVolumeClaimList, error) {
+	listPVCs, err := clientset.CoreV1().PersistentVolumeClaims(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Persistent Volume Claim List: %w", err)
+	}
+	return listPVCs, nil
+}
+
+// ListStatefulSets retrieves a list of StatefulSets in the specified namespace.
+func ListStatefulSets(clientset *kubernetes.Clientset, nameSpace string) (*v1.StatefulSetList, error) {
+	listStatefulSets, err := clientset.AppsV1().StatefulSets(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the StatefulSet List: %w", err)
+	}
+	return listStatefulSets, nil
+}
+
+// ListDaemonSets retrieves a list of DaemonSets in the specified namespace.
+func ListDaemonSets(clientset *kubernetes.Clientset, nameSpace string) (*v1.DaemonSetList, error) {
+	listDaemonSets, err := clientset.AppsV1().DaemonSets(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the DaemonSet List: %w", err)
+	}
+	return listDaemonSets, nil
+}
+
+// ListReplicaSets retrieves a list of ReplicaSets in the specified namespace.
+func ListReplicaSets(clientset *kubernetes.Clientset, nameSpace string) (*v1.ReplicaSetList, error) {
+	listReplicaSets, err := clientset.AppsV1().ReplicaSets(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the ReplicaSet List: %w", err)
+	}
+	return listReplicaSets, nil
+}
+
+// ListPods retrieves a list of Pods in the specified namespace.
+func ListPods(clientset *kubernetes.Clientset, nameSpace string) (*Corev1.PodList, error) {
+	listPods, err := clientset.CoreV1().Pods(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Pod List: %w", err)
+	}
+	return listPods, nil
+}
+
+// ListIngressClasses retrieves the list of IngressClasses, a cluster-scoped kind.
+func ListIngressClasses(clientset *kubernetes.Clientset) (*networkingv1.IngressClassList, error) {
+	listIngressClasses, err := clientset.NetworkingV1().IngressClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Ingress Class List: %w", err)
+	}
+	return listIngressClasses, nil
+}
+
+// ListNodes retrieves the list of Nodes, a cluster-scoped kind.
+func ListNodes(clientset *kubernetes.Clientset) (*Corev1.NodeList, error) {
+	listNodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Node List: %w", err)
+	}
+	return listNodes, nil
+}
+
+// ListResourceQuotas retrieves a list of ResourceQuotas in the specified namespace.
+func ListResourceQuotas(clientset *kubernetes.Clientset, nameSpace string) (*Corev1.ResourceQuotaList, error) {
+	listResourceQuotas, err := clientset.CoreV1().ResourceQuotas(nameSpace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Resource Quota List: %w", err)
+	}
+	return listResourceQuotas, nil
+}
+
+// ListCustomResources retrieves the custom resources of a group, version and resource in every namespace,
+// with a dynamic client built like the one of ListCRDs.
+func ListCustomResources(ctx, kubeconfig string, resource schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	config, err := connect.BuildConfigWithContextFromFlags(ctx, kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config for quering custom resources: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dynamic client for quering custom resources: %w", err)
+	}
+	listCustomResources, err := dynamicClient.Resource(resource).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the %s List: %w", resource.String(), err)
+	}
+	return listCustomResources, nil
+}
+
+// GetServerVersion retrieves the Kubernetes version of the API server.
+func GetServerVersion(clientset *kubernetes.Clientset) (*version.Info, error) {
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the server version: %w", err)
+	}
+	return serverVersion, nil
+}
+
+// ListServerGroupsAndResources retrieves the API groups and the resources of every group-version the API server serves.
+// When only some group-versions could not be discovered, e.g. an aggregated API whose backend is down, the others are
+// returned along with the *discovery.ErrGroupDiscoveryFailed naming the failed ones.
+func ListServerGroupsAndResources(clientset *kubernetes.Clientset) ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	groups, resources, err := clientset.Discovery().ServerGroupsAndResources()
+	if discovery.IsGroupDiscoveryFailedError(err) {
+		return groups, resources, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query the server groups and resources: %w", err)
+	}
+	return groups, resources, nil
+}
+
+// ServesKind reports whether the API server serves a kind at a group-version, e.g. "autoscaling/v2".
+// A group-version the server does not know is not an error.
+func ServesKind(clientset *kubernetes.Clientset, groupVersion, kind string) (bool, error) {
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query the resources of %s: %w", groupVersion, err)
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == kind {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -7,6 +7,7 @@ import (
 	"kompare/connect"
 	"kompare/mock"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -169,6 +170,51 @@ func TestGetHPA(t *testing.T) {
 	}
 }
 
+func TestGetHPAV2(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	hpas, err := ListHPAsV2(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 3 // Update this value with the expected number of HPAs
+	if len(hpas.Items) != expectedLength {
+		t.Fatalf("Expected %d HPAs, got: %d", expectedLength, len(hpas.Items))
+	}
+	if len(hpas.Items[0].Spec.Metrics) != 2 {
+		t.Errorf("Expected the CPU and memory metrics, got: %+v", hpas.Items[0].Spec.Metrics)
+	}
+}
+
 func TestListCronJobs(t *testing.T) {
 	// Set up test environment and get the temporary kubeconfig file
 	_, _, tempKubeconfig := mock.SetupTestEnvironment()
@@ -664,3 +710,513 @@ func TestListNetworkPolicies(t *testing.T) {
 		t.Errorf("Expected %d network policies, got: %d", expectedLength, len(networkPolicies.Items))
 	}
 }
+
+func TestListPersistentVolumeClaims(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	pvcs, err := ListPersistentVolumeClaims(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 2
+	if len(pvcs.Items) != expectedLength {
+		t.Errorf("Expected %d persistent volume claims, got: %d", expectedLength, len(pvcs.Items))
+	}
+}
+
+func TestListStatefulSets(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	statefulSets, err := ListStatefulSets(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 2
+	if len(statefulSets.Items) != expectedLength {
+		t.Errorf("Expected %d stateful sets, got: %d", expectedLength, len(statefulSets.Items))
+	}
+}
+
+func TestListDaemonSets(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	daemonSets, err := ListDaemonSets(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 1
+	if len(daemonSets.Items) != expectedLength {
+		t.Errorf("Expected %d daemon sets, got: %d", expectedLength, len(daemonSets.Items))
+	}
+}
+
+func TestListReplicaSets(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	replicaSets, err := ListReplicaSets(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 1
+	if len(replicaSets.Items) != expectedLength {
+		t.Errorf("Expected %d replica sets, got: %d", expectedLength, len(replicaSets.Items))
+	}
+}
+
+func TestListPods(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	pods, err := ListPods(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 2
+	if len(pods.Items) != expectedLength {
+		t.Errorf("Expected %d pods, got: %d", expectedLength, len(pods.Items))
+	}
+}
+
+func TestListIngressClasses(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	ingressClasses, err := ListIngressClasses(config)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 1
+	if len(ingressClasses.Items) != expectedLength {
+		t.Errorf("Expected %d ingress classes, got: %d", expectedLength, len(ingressClasses.Items))
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	nodes, err := ListNodes(config)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 2
+	if len(nodes.Items) != expectedLength {
+		t.Errorf("Expected %d nodes, got: %d", expectedLength, len(nodes.Items))
+	}
+}
+
+func TestListResourceQuotas(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	resourceQuotas, err := ListResourceQuotas(config, "namespace2")
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 1
+	if len(resourceQuotas.Items) != expectedLength {
+		t.Errorf("Expected %d resource quotas, got: %d", expectedLength, len(resourceQuotas.Items))
+	}
+}
+
+func TestListCustomResources(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Load the kubeconfig data
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Get the current context from the kubeconfig
+	currentContext := kubeconfig.CurrentContext
+
+	// Query the custom resources using the context and the kubeconfig file path
+	widgets, err := ListCustomResources(currentContext, tempKubeconfig.Name(), schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"})
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedLength := 1
+	if len(widgets.Items) != expectedLength {
+		t.Errorf("Expected %d widgets, got: %d", expectedLength, len(widgets.Items))
+	}
+}
+
+func TestGetServerVersion(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	serverVersion, err := GetServerVersion(config)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if serverVersion.GitVersion != "v1.29.1" {
+		t.Errorf("Expected the server version v1.29.1, got: %s", serverVersion.GitVersion)
+	}
+}
+
+func TestListServerGroupsAndResources(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	// Connect to the Kubernetes cluster using the test context and kubeconfig file path
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	groups, resources, err := ListServerGroupsAndResources(config)
+	if err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	expectedGroups, expectedGroupVersions := 4, 5
+	if len(groups) != expectedGroups || len(resources) != expectedGroupVersions {
+		t.Errorf("Expected %d groups and %d group-versions, got: %d and %d", expectedGroups, expectedGroupVersions, len(groups), len(resources))
+	}
+}
+
+func TestServesKind(t *testing.T) {
+	// Set up test environment and get the temporary kubeconfig file
+	_, _, tempKubeconfig := mock.SetupTestEnvironment()
+	defer tempKubeconfig.Close() // Close the file after the test completes
+
+	// Read the content of the kubeconfig file
+	tempKubeconfigByte, err := os.ReadFile(tempKubeconfig.Name())
+	if err != nil {
+		t.Fatalf("Error reading kubeconfig file: %v", err)
+	}
+
+	// Load the kubeconfig data
+	kubeconfig, err := clientcmd.Load(tempKubeconfigByte)
+	if err != nil {
+		t.Fatalf("Error loading kubeconfig: %v", err)
+	}
+
+	// Choose one of the contexts from the kubeconfig
+	var testContext string
+	for context := range kubeconfig.Contexts {
+		testContext = context
+		break // Choose the first context, you can modify this logic as needed
+	}
+
+	x := tempKubeconfig.Name()
+	config, err := connect.ConnectToSource(testContext, &x)
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+
+	// Perform the test
+	tests := []struct {
+		groupVersion, kind string
+		expected           bool
+	}{
+		{"autoscaling/v2", "HorizontalPodAutoscaler", true},
+		{"autoscaling/v1", "HorizontalPodAutoscaler", true},
+		{"autoscaling/v2beta2", "HorizontalPodAutoscaler", false},
+		{"batch/v1", "Job", false},
+	}
+	for _, test := range tests {
+		served, err := ServesKind(config, test.groupVersion, test.kind)
+		if err != nil {
+			t.Errorf("Expected no error for %s, got: %v", test.groupVersion, err)
+		}
+		if served != test.expected {
+			t.Errorf("Expected %s %s served to be %v, got: %v", test.groupVersion, test.kind, test.expected, served)
+		}
+	}
+}
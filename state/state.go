@@ -0,0 +1,193 @@
+// Package state remembers the objects compared and their findings between runs, so that a run only
+// diffs the pairs whose objects changed since the previous one.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"kompare/DAO"
+	"kompare/baseline"
+)
+
+// MaxAge is how long the findings of a full comparison are carried forward. Past it, the next run
+// compares every pair again, so that a finding carried forward for too long is checked again.
+const MaxAge = 24 * time.Hour
+
+// Snapshot is what a pair looked like when it was compared: the resourceVersion of both objects,
+// and the fingerprint of their compared fields, which changes with the criteria too.
+type Snapshot struct {
+	SourceResourceVersion string `json:"sourceResourceVersion"`
+	TargetResourceVersion string `json:"targetResourceVersion"`
+	SourceFingerprint     string `json:"sourceFingerprint"`
+	TargetFingerprint     string `json:"targetFingerprint"`
+}
+
+// Cluster identifies a cluster by context and UID, the UID of its kube-system namespace, which only
+// changes when the cluster is rebuilt or the context points to another cluster.
+type Cluster struct {
+	Context string `json:"context"`
+	UID     string `json:"uid"`
+}
+
+type pair struct {
+	Snapshot
+	Findings []DAO.DiffWithName `json:"findings,omitempty"`
+}
+
+type file struct {
+	Source Cluster `json:"source"`
+	Target Cluster `json:"target"`
+	// FullAt is when every pair was last compared; incremental runs keep it.
+	FullAt time.Time       `json:"fullAt"`
+	Pairs  map[string]pair `json:"pairs"`
+}
+
+// State is the pairs of the previous run plus the pairs of the current one.
+type State struct {
+	mu       sync.Mutex
+	previous file
+	current  map[string]pair
+	// full is set when the previous pairs cannot be trusted, every pair is then compared.
+	full        bool
+	unchanged   int
+	compared    int
+	newFindings map[string]baseline.Entry
+}
+
+// New returns a state without previous pairs: every pair is compared.
+func New(source, target Cluster, now time.Time) *State {
+	return &State{
+		previous:    file{Source: source, Target: target, FullAt: now, Pairs: map[string]pair{}},
+		current:     map[string]pair{},
+		full:        true,
+		newFindings: map[string]baseline.Entry{},
+	}
+}
+
+// Load reads a state file written by Save for the source and target clusters. It also returns why the
+// previous pairs cannot be used, or "" when only the changed pairs need comparing: the file does not
+// exist yet, a cluster UID or context changed, or the last full comparison is older than MaxAge.
+func Load(path string, source, target Cluster, now time.Time) (*State, string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(source, target, now), "no state file yet", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading state %s: %v", path, err)
+	}
+	var f file
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, "", fmt.Errorf("error parsing state %s: %v", path, err)
+	}
+	reason := ""
+	switch {
+	case f.Source != source:
+		reason = fmt.Sprintf("the source cluster changed from %s (%s) to %s (%s)", f.Source.Context, f.Source.UID, source.Context, source.UID)
+	case f.Target != target:
+		reason = fmt.Sprintf("the target cluster changed from %s (%s) to %s (%s)", f.Target.Context, f.Target.UID, target.Context, target.UID)
+	case now.Sub(f.FullAt) > MaxAge:
+		reason = fmt.Sprintf("the last full comparison is older than %s", MaxAge)
+	}
+	if reason != "" {
+		return New(source, target, now), reason, nil
+	}
+	s := New(source, target, now)
+	s.previous.FullAt = f.FullAt
+	if f.Pairs != nil {
+		s.previous.Pairs = f.Pairs
+	}
+	s.full = false
+	return s, "", nil
+}
+
+// Unchanged returns the findings of the previous run for a pair whose objects have not changed since,
+// and records them for the current run. It reports false when the pair needs comparing.
+func (s *State) Unchanged(key string, snapshot Snapshot) ([]DAO.DiffWithName, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, found := s.previous.Pairs[key]
+	if s.full || !found || previous.Snapshot != snapshot || snapshot.SourceResourceVersion == "" || snapshot.TargetResourceVersion == "" {
+		return nil, false
+	}
+	s.current[key] = previous
+	s.unchanged++
+	return append([]DAO.DiffWithName(nil), previous.Findings...), true
+}
+
+// Record records the findings of a pair compared during the current run. The difference lines that
+// the pair did not have in the previous run are new, unless the previous pairs could not be used.
+func (s *State) Record(key string, snapshot Snapshot, findings []DAO.DiffWithName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current[key] = pair{Snapshot: snapshot, Findings: findings}
+	s.compared++
+	if s.full {
+		return
+	}
+	known := map[string]bool{}
+	for _, diff := range s.previous.Pairs[key].Findings {
+		for _, entry := range baseline.Entries(diff) {
+			known[entry.ID] = true
+		}
+	}
+	for _, diff := range findings {
+		for _, entry := range baseline.Entries(diff) {
+			if !known[entry.ID] {
+				s.newFindings[entry.ID] = entry
+			}
+		}
+	}
+}
+
+// Full reports whether every pair is compared, the previous pairs not being usable.
+func (s *State) Full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.full
+}
+
+// Counts returns the number of pairs carried forward unchanged and the number of pairs compared.
+func (s *State) Counts() (unchanged, compared int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unchanged, s.compared
+}
+
+// NewFindings returns the findings of the pairs compared during the run that the previous run did not have, sorted by ID.
+func (s *State) NewFindings() []baseline.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]baseline.Entry, 0, len(s.newFindings))
+	for _, entry := range s.newFindings {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+// Save writes the pairs of the current run to a state file. After an incremental run, the previous
+// pairs not seen during the run, e.g. of namespaces out of scope, are kept for the next runs.
+func (s *State) Save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := file{Source: s.previous.Source, Target: s.previous.Target, FullAt: s.previous.FullAt, Pairs: map[string]pair{}}
+	if !s.full {
+		for key, previous := range s.previous.Pairs {
+			f.Pairs[key] = previous
+		}
+	}
+	for key, current := range s.current {
+		f.Pairs[key] = current
+	}
+	content, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
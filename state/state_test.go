@@ -0,0 +1,105 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kompare/DAO"
+)
+
+var (
+	source = Cluster{Context: "source-context", UID: "source-uid"}
+	target = Cluster{Context: "target-context", UID: "target-uid"}
+)
+
+func snapshot(sourceVersion, targetVersion string) Snapshot {
+	return Snapshot{SourceResourceVersion: sourceVersion, TargetResourceVersion: targetVersion, SourceFingerprint: "a", TargetFingerprint: "b"}
+}
+
+func finding(line string) []DAO.DiffWithName {
+	return []DAO.DiffWithName{{Kind: "deployment", Namespace: "shop", Name: "web", PropertyName: "Spec.Replicas", Diff: []string{line}}}
+}
+
+func TestIncrementalRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first, reason, err := Load(path, source, target, now)
+	if err != nil || reason == "" || !first.Full() {
+		t.Fatalf("Expected a full comparison without a state file, got %q, %v", reason, err)
+	}
+	if _, unchanged := first.Unchanged("deployment/shop/web", snapshot("1", "7")); unchanged {
+		t.Errorf("Expected every pair to be compared on a full comparison")
+	}
+	first.Record("deployment/shop/web", snapshot("1", "7"), finding("2 != 3"))
+	first.Record("deployment/shop/api", snapshot("4", "9"), nil)
+	if err := first.Save(path); err != nil {
+		t.Fatalf("Expected no error saving, got: %v", err)
+	}
+
+	second, reason, err := Load(path, source, target, now.Add(time.Hour))
+	if err != nil || reason != "" || second.Full() {
+		t.Fatalf("Expected an incremental comparison, got %q, %v", reason, err)
+	}
+	findings, unchanged := second.Unchanged("deployment/shop/web", snapshot("1", "7"))
+	if !unchanged || len(findings) != 1 || findings[0].Diff[0] != "2 != 3" {
+		t.Errorf("Expected the findings of the unchanged pair to be carried forward, got %v, %v", findings, unchanged)
+	}
+	if _, unchanged := second.Unchanged("deployment/shop/api", snapshot("5", "9")); unchanged {
+		t.Errorf("Expected a pair with a new resourceVersion to be compared")
+	}
+	second.Record("deployment/shop/api", snapshot("5", "9"), []DAO.DiffWithName{{Kind: "deployment", Namespace: "shop", Name: "api", PropertyName: "Spec.Replicas", Diff: []string{"1 != 2"}}})
+	if unchanged, compared := second.Counts(); unchanged != 1 || compared != 1 {
+		t.Errorf("Expected 1 pair unchanged and 1 compared, got %d and %d", unchanged, compared)
+	}
+	newFindings := second.NewFindings()
+	if len(newFindings) != 1 || newFindings[0].ID != "deployment/shop/api/Spec.Replicas" {
+		t.Errorf("Expected the finding of api to be new, got %v", newFindings)
+	}
+	if err := second.Save(path); err != nil {
+		t.Fatalf("Expected no error saving, got: %v", err)
+	}
+
+	// A scoped run leaves the pairs it did not see in the state.
+	third, _, _ := Load(path, source, target, now.Add(2*time.Hour))
+	if _, unchanged := third.Unchanged("deployment/shop/api", snapshot("5", "9")); !unchanged {
+		t.Errorf("Expected the pair compared by the previous run to be unchanged")
+	}
+	if err := third.Save(path); err != nil {
+		t.Fatalf("Expected no error saving, got: %v", err)
+	}
+	fourth, _, _ := Load(path, source, target, now.Add(3*time.Hour))
+	if _, unchanged := fourth.Unchanged("deployment/shop/web", snapshot("1", "7")); !unchanged {
+		t.Errorf("Expected the pairs out of the scope of the previous run to be kept")
+	}
+}
+
+func TestFullComparisonFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := New(source, target, now)
+	s.Record("deployment/shop/web", snapshot("1", "7"), finding("2 != 3"))
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Expected no error saving, got: %v", err)
+	}
+
+	rebuilt := Cluster{Context: "target-context", UID: "new-uid"}
+	for name, load := range map[string]func() (*State, string, error){
+		"cluster UID changed": func() (*State, string, error) { return Load(path, source, rebuilt, now) },
+		"stale state":         func() (*State, string, error) { return Load(path, source, target, now.Add(MaxAge+time.Minute)) },
+	} {
+		loaded, reason, err := load()
+		if err != nil || reason == "" || !loaded.Full() {
+			t.Errorf("%s: expected a full comparison, got %q, %v", name, reason, err)
+			continue
+		}
+		if _, unchanged := loaded.Unchanged("deployment/shop/web", snapshot("1", "7")); unchanged {
+			t.Errorf("%s: expected the pair to be compared again", name)
+		}
+	}
+
+	if _, unchanged := New(source, target, now).Unchanged("deployment/shop/web", snapshot("", "")); unchanged {
+		t.Errorf("Expected objects without a resourceVersion to always be compared")
+	}
+}
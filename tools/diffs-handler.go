@@ -26,6 +26,21 @@ func FormatDiffHumanReadable(differences []DAO.DiffWithName) string {
 			if diff.Namespace != "" {
 				formattedDiff.WriteString(fmt.Sprintf("Namespace: %s\n", diff.Namespace))
 			}
+			if diff.Severity != "" {
+				formattedDiff.WriteString(fmt.Sprintf("Severity: %s\n", diff.Severity))
+			}
+			if diff.APIVersion != "" {
+				formattedDiff.WriteString(fmt.Sprintf("API version: %s\n", diff.APIVersion))
+			}
+			if diff.Reason != "" {
+				formattedDiff.WriteString(fmt.Sprintf("Expected difference: %s\n", diff.Reason))
+			}
+			if diff.SourceFingerprint != "" {
+				formattedDiff.WriteString(fmt.Sprintf("Fingerprints: source %s, target %s\n", diff.SourceFingerprint, diff.TargetFingerprint))
+			}
+			if diff.MessageHeading != "" {
+				formattedDiff.WriteString(fmt.Sprintf("Reported by: %s\n", diff.MessageHeading))
+			}
 			formattedDiff.WriteString("Differences:\n")
 			if len(diff.Diff) > 0 {
 				for _, d := range diff.Diff {
@@ -69,10 +84,14 @@ func FormatDiffHumanReadable(differences []DAO.DiffWithName) string {
 				formattedDiff.WriteString("\n")
 			}
 		} else {
+			fingerprint := ""
+			if diff.SourceFingerprint != "" {
+				fingerprint = ", Fingerprint " + diff.SourceFingerprint
+			}
 			if diff.Namespace != "" {
-				fmt.Printf("No differences found; Object Name %s, Kubernetes resource definition type %s, Namespace %s\n", diff.Name, diff.PropertyName, diff.Namespace)
+				fmt.Printf("No differences found; Object Name %s, Kubernetes resource definition type %s, Namespace %s%s\n", diff.Name, diff.PropertyName, diff.Namespace, fingerprint)
 			} else {
-				fmt.Printf("No differences found; Object Name %s, Kubernetes resource definition type %s\n", diff.Name, diff.PropertyName)
+				fmt.Printf("No differences found; Object Name %s, Kubernetes resource definition type %s%s\n", diff.Name, diff.PropertyName, fingerprint)
 			}
 		}
 	}